@@ -0,0 +1,233 @@
+// Package basictest runs tests which are written in BASIC.
+//
+// A test is a program whose name ends in "_test.bas".  It may use the
+// ASSERT statement to check its own results, and anything it prints is
+// compared against a "golden" file which holds the output expected.  For
+// the test "sort_test.bas":
+//
+//   - "sort_test.golden" holds the output which is expected.
+//   - "sort_test.stdin", if present, is given to the program as its
+//     input, for INPUT to read.
+//
+// A test fails if an assertion fails, if the program reports an error,
+// or if its output doesn't match the golden file.  When golden files are
+// updated only those of tests which ran cleanly are written.
+package basictest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/format"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// Suffix is the suffix of the names of tests.
+const Suffix = "_test.bas"
+
+// Options control how tests are run.
+type Options struct {
+
+	// Update is true if the golden files should be updated to match
+	// the output of the tests, rather than compared with it.
+	Update bool
+
+	// Timeout is the time each test may run for, or zero for no
+	// limit.
+	Timeout time.Duration
+}
+
+// Failure describes a single reason why a test failed.
+type Failure struct {
+
+	// Line is the line of the source which failed, counting from
+	// one, or zero if the failure isn't related to a line.
+	Line int
+
+	// Message describes the failure.
+	Message string
+}
+
+// Result holds the outcome of a single test.
+type Result struct {
+
+	// Path is the path of the test.
+	Path string
+
+	// Failures holds the reasons why the test failed, if it did.
+	Failures []Failure
+
+	// Updated is true if the golden file was updated.
+	Updated bool
+
+	// Duration is the time the test took to run.
+	Duration time.Duration
+}
+
+// Passed returns true if the test passed.
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// fail records a failure.
+func (r *Result) fail(line int, format string, args ...interface{}) {
+	r.Failures = append(r.Failures, Failure{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// Find returns the tests found at the given paths, in order.
+//
+// Directories are searched recursively, skipping those whose names
+// begin with ".", while files are returned as they are.
+func Find(paths []string) ([]string, error) {
+	var tests []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			tests = append(tests, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if p != path && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(info.Name(), Suffix) {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		tests = append(tests, found...)
+	}
+
+	return tests, nil
+}
+
+// Golden returns the path of the golden file of the given test.
+func Golden(path string) string {
+	return strings.TrimSuffix(path, ".bas") + ".golden"
+}
+
+// Stdin returns the path of the file which holds the input of the given
+// test.
+func Stdin(path string) string {
+	return strings.TrimSuffix(path, ".bas") + ".stdin"
+}
+
+// Run runs the test at the given path.
+func Run(path string, opts Options) Result {
+	result := Result{Path: path}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		result.fail(0, "%s", err.Error())
+		return result
+	}
+
+	//
+	// The input is optional.
+	//
+	input, err := os.ReadFile(Stdin(path))
+	if err != nil && !os.IsNotExist(err) {
+		result.fail(0, "%s", err.Error())
+		return result
+	}
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	e, err := eval.NewWithContext(ctx, tokenizer.New(string(src)))
+	if err != nil {
+		result.fail(0, "%s", err.Error())
+		return result
+	}
+
+	var stdout, stderr bytes.Buffer
+	e.STDIN = bufio.NewReader(bytes.NewReader(input))
+	e.STDOUT = bufio.NewWriter(&stdout)
+	e.STDERR = bufio.NewWriter(&stderr)
+
+	start := time.Now()
+	err = e.Run()
+	result.Duration = time.Since(start)
+
+	e.STDOUT.Flush()
+	e.STDERR.Flush()
+
+	if err != nil {
+		var a *eval.AssertionError
+		var l *eval.LineError
+		switch {
+		case errors.As(err, &a):
+			result.fail(a.Line, "%s", a.Error())
+		case errors.As(err, &l):
+			result.fail(l.Line, "%s", l.Error())
+		default:
+			result.fail(0, "%s", err.Error())
+		}
+	}
+
+	//
+	// Now compare the output with what we expected, or record it.
+	//
+	golden := Golden(path)
+	expected, err := os.ReadFile(golden)
+	if err != nil && !os.IsNotExist(err) {
+		result.fail(0, "%s", err.Error())
+		return result
+	}
+
+	if opts.Update {
+
+		// The output of a test which failed isn't what's expected.
+		if !result.Passed() {
+			return result
+		}
+
+		if err != nil || !bytes.Equal(expected, stdout.Bytes()) {
+			if err := os.WriteFile(golden, stdout.Bytes(), 0644); err != nil {
+				result.fail(0, "%s", err.Error())
+				return result
+			}
+			result.Updated = true
+		}
+		return result
+	}
+
+	if err != nil {
+		result.fail(0, "%s does not exist, run with -update to create it", golden)
+		return result
+	}
+	if diff := format.Diff(golden, string(expected), stdout.String()); diff != "" {
+		result.fail(0, "output differs from %s:\n%s", golden, diff)
+	}
+
+	return result
+}
@@ -0,0 +1,169 @@
+// basictest_test.go - Test-cases for our test-runner.
+
+package basictest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// write creates a file beneath the given directory.
+func write(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %s", err.Error())
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", path, err.Error())
+	}
+	return path
+}
+
+// TestFind tests that tests are found, in order.
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "b_test.bas", "")
+	write(t, dir, "a_test.bas", "")
+	write(t, dir, "sub/c_test.bas", "")
+	write(t, dir, "other.bas", "")
+	write(t, dir, ".hidden/d_test.bas", "")
+	extra := write(t, dir, "named.bas", "")
+
+	found, err := Find([]string{dir, extra})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := []string{
+		filepath.Join(dir, "a_test.bas"),
+		filepath.Join(dir, "b_test.bas"),
+		filepath.Join(dir, "sub", "c_test.bas"),
+		extra,
+	}
+	if strings.Join(found, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected tests %v", found)
+	}
+
+	_, err = Find([]string{filepath.Join(dir, "missing")})
+	if err == nil {
+		t.Errorf("expected an error finding a missing path")
+	}
+}
+
+// TestRun tests that passing, and failing, tests are reported.
+func TestRun(t *testing.T) {
+
+	type Test struct {
+		Source string
+		Stdin  string
+		Golden string
+		Line   int
+		Error  string
+	}
+
+	tests := []Test{
+
+		// Passes, reading its input.
+		{Source: "10 INPUT \"\", a\n20 ASSERT a = 3\n30 PRINT a * 2, \"\\n\"\n",
+			Stdin:  "3\n",
+			Golden: "6 \n"},
+
+		// An assertion fails.
+		{Source: "10 PRINT \"ok\\n\"\n20 ASSERT 1 = 2, \"bad sum\"\n",
+			Golden: "ok\n",
+			Line:   2,
+			Error:  "assertion failed: bad sum"},
+
+		// The output differs.
+		{Source: "10 PRINT \"b\\n\"\n",
+			Golden: "a\n",
+			Error:  "output differs from"},
+
+		// The program fails.
+		{Source: "10 PRINT \"a\"\n20 GOTO 100\n",
+			Line:  2,
+			Error: "Line 100 does not exist"},
+
+		// The program runs for too long.
+		{Source: "10 GOTO 10\n",
+			Error: "timeout during execution"},
+	}
+
+	for i, test := range tests {
+		dir := t.TempDir()
+		path := write(t, dir, "x_test.bas", test.Source)
+		write(t, dir, "x_test.golden", test.Golden)
+		if test.Stdin != "" {
+			write(t, dir, "x_test.stdin", test.Stdin)
+		}
+
+		r := Run(path, Options{Timeout: 100 * time.Millisecond})
+		if test.Error == "" {
+			if !r.Passed() {
+				t.Errorf("test %d: unexpected failure %v", i, r.Failures)
+			}
+			continue
+		}
+
+		if r.Passed() {
+			t.Errorf("test %d: expected a failure", i)
+			continue
+		}
+		f := r.Failures[0]
+		if !strings.Contains(f.Message, test.Error) {
+			t.Errorf("test %d: failure %q doesn't contain %q", i, f.Message, test.Error)
+		}
+		if f.Line != test.Line {
+			t.Errorf("test %d: failure reported on line %d, not %d", i, f.Line, test.Line)
+		}
+	}
+}
+
+// TestUpdate tests that golden files are created, and updated.
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	path := write(t, dir, "x_test.bas", "10 PRINT \"hello\\n\"\n")
+
+	r := Run(path, Options{})
+	if r.Passed() || !strings.Contains(r.Failures[0].Message, "run with -update") {
+		t.Fatalf("expected the missing golden file to be reported, got %v", r.Failures)
+	}
+
+	r = Run(path, Options{Update: true})
+	if !r.Passed() || !r.Updated {
+		t.Fatalf("expected the golden file to be created, got %v", r)
+	}
+	out, err := os.ReadFile(Golden(path))
+	if err != nil || string(out) != "hello\n" {
+		t.Fatalf("unexpected golden file %q %v", out, err)
+	}
+
+	// Once it matches there is nothing to update.
+	r = Run(path, Options{Update: true})
+	if !r.Passed() || r.Updated {
+		t.Errorf("didn't expect the golden file to be updated, got %v", r)
+	}
+	r = Run(path, Options{})
+	if !r.Passed() {
+		t.Errorf("unexpected failure %v", r.Failures)
+	}
+
+	// The output of tests which fail isn't recorded.
+	for _, src := range []string{
+		"10 PRINT \"bye\\n\"\n20 ASSERT 1 = 2\n",
+		"10 PRINT \"bye\\n\"\n20 GOTO 100\n",
+	} {
+		write(t, dir, "x_test.bas", src)
+		r = Run(path, Options{Update: true})
+		if r.Passed() || r.Updated {
+			t.Errorf("expected %q to fail without updating, got %v", src, r)
+		}
+		out, err = os.ReadFile(Golden(path))
+		if err != nil || string(out) != "hello\n" {
+			t.Errorf("unexpected golden file %q %v", out, err)
+		}
+	}
+}
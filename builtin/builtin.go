@@ -71,6 +71,19 @@ func (b *Builtins) Get(name string) (int, Signature) {
 	return b.argRegistry[name], b.fnRegistry[name]
 }
 
+// Names returns the names of all the registered built-in functions,
+// along with the number of arguments each requires.
+func (b *Builtins) Names() map[string]int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	names := make(map[string]int, len(b.argRegistry))
+	for name, n := range b.argRegistry {
+		names[name] = n
+	}
+	return names
+}
+
 // Environment is an interface which is passed to all built-in functions.
 type Environment interface {
 	// StdInput is a handle to a reader-object, allowing input to
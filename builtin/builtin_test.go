@@ -32,3 +32,25 @@ func TestNoddy(t *testing.T) {
 		t.Errorf("We found something unexpected on a missing entry!")
 	}
 }
+
+// Test that we can list the registered names.
+func TestNames(t *testing.T) {
+
+	b := New()
+	b.Register("one", 1, nil)
+	b.Register("two", 2, nil)
+
+	names := b.Names()
+	if len(names) != 2 {
+		t.Fatalf("Wrong number of names: %d", len(names))
+	}
+	if names["one"] != 1 || names["two"] != 2 {
+		t.Errorf("Wrong argument counts: %v", names)
+	}
+
+	// The result is a copy.
+	names["three"] = 3
+	if len(b.Names()) != 2 {
+		t.Errorf("Modifying the result changed the registry")
+	}
+}
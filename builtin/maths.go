@@ -9,6 +9,7 @@ import (
 	"math"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/skx/gobasic/object"
@@ -24,7 +25,7 @@ func ABS(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -42,7 +43,7 @@ func ACS(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -54,7 +55,7 @@ func ASN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -66,7 +67,7 @@ func ATN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -78,7 +79,7 @@ func BIN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -93,12 +94,52 @@ func BIN(env Environment, args []object.Object) object.Object {
 
 }
 
+// BINS implements BIN$, which converts a number to a string of binary
+// digits; the reverse of BIN.
+func BINS(env Environment, args []object.Object) object.Object {
+	return toBase("BIN$", args, 2)
+}
+
+// HEX implements HEX$, which converts a number to a string of
+// hexadecimal digits.
+func HEX(env Environment, args []object.Object) object.Object {
+	return toBase("HEX$", args, 16)
+}
+
+// OCT implements OCT$, which converts a number to a string of octal
+// digits.
+func OCT(env Environment, args []object.Object) object.Object {
+	return toBase("OCT$", args, 8)
+}
+
+// toBase is the helper for BIN$, HEX$, and OCT$, which converts the
+// integer part of a number to a string in the given base.
+func toBase(name string, args []object.Object, base int) object.Object {
+
+	// Get the (float) argument.
+	if args[0].Type() != object.NUMBER {
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
+	}
+	i := args[0].(*object.NumberObject).Value
+
+	if i < 0 {
+		return object.CodedError(object.ErrIllegalFunction, "%s: negative argument %v", name, i)
+	}
+
+	// Larger numbers don't fit in 64 bits, so can't be converted.
+	if i >= 1<<64 || math.IsNaN(i) {
+		return object.CodedError(object.ErrIllegalFunction, "%s: argument %v is too large", name, i)
+	}
+
+	return &object.StringObject{Value: strings.ToUpper(strconv.FormatUint(uint64(i), base))}
+}
+
 // COS implements the COS function..
 func COS(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -109,7 +150,7 @@ func COS(env Environment, args []object.Object) object.Object {
 func EXP(env Environment, args []object.Object) object.Object {
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -121,7 +162,7 @@ func INT(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -134,7 +175,7 @@ func LN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -151,7 +192,7 @@ func RND(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -167,12 +208,18 @@ func RND(env Environment, args []object.Object) object.Object {
 	return &object.NumberObject{Value: float64(rand.Intn(n))}
 }
 
+// RNDF implements RND for dialects in which it returns a number from
+// zero to one.  Any argument is ignored.
+func RNDF(env Environment, args []object.Object) object.Object {
+	return &object.NumberObject{Value: rand.Float64()}
+}
+
 // SGN is the sign function (sometimes called signum).
 func SGN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -191,7 +238,7 @@ func SIN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -203,7 +250,7 @@ func SQR(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -219,7 +266,7 @@ func TAN(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
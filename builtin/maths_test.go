@@ -156,6 +156,66 @@ func TestBIN(t *testing.T) {
 
 }
 
+func TestBases(t *testing.T) {
+
+	tests := []struct {
+		fn     func(Environment, []object.Object) object.Object
+		input  float64
+		output string
+	}{
+		{BINS, 0, "0"},
+		{BINS, 10, "1010"},
+		{HEX, 255, "FF"},
+		{HEX, 3.7, "3"},
+		{OCT, 8, "10"},
+		{OCT, 511, "777"},
+	}
+
+	for _, test := range tests {
+		out := test.fn(nil, []object.Object{object.Number(test.input)})
+		if out.Type() != object.STRING {
+			t.Fatalf("We expected a string, but got %s", out.String())
+		}
+		if out.(*object.StringObject).Value != test.output {
+			t.Errorf("Wrong result for %v, got %s, expected %s", test.input, out.String(), test.output)
+		}
+	}
+
+	//
+	// Requires a number argument
+	//
+	out := HEX(nil, []object.Object{object.String("steve")})
+	if out.Type() != object.ERROR {
+		t.Errorf("We expected a type-error, but didn't receive one")
+	}
+
+	//
+	// Negative numbers are an error
+	//
+	out = OCT(nil, []object.Object{object.Number(-3)})
+	if out.Type() != object.ERROR || out.(*object.ErrorObject).Code != object.ErrIllegalFunction {
+		t.Errorf("We expected an illegal function call, but got %s", out.String())
+	}
+
+	//
+	// As are numbers too large to convert
+	//
+	for _, n := range []float64{math.Exp2(64), 1e300, math.Inf(1), math.NaN()} {
+		out = HEX(nil, []object.Object{object.Number(n)})
+		if out.Type() != object.ERROR || out.(*object.ErrorObject).Code != object.ErrIllegalFunction {
+			t.Errorf("We expected an illegal function call for %v, but got %s", n, out.String())
+		}
+	}
+
+	//
+	// But the largest which fit are fine
+	//
+	out = HEX(nil, []object.Object{object.Number(math.Exp2(64) - 2048)})
+	if out.Type() != object.STRING || out.(*object.StringObject).Value != "FFFFFFFFFFFFF800" {
+		t.Errorf("Unexpected result %s", out.String())
+	}
+}
+
 func TestCOS(t *testing.T) {
 	//
 	// Requires a number argument
@@ -309,6 +369,21 @@ func TestRND(t *testing.T) {
 
 }
 
+// Test RNDF
+func TestRNDF(t *testing.T) {
+
+	for i := 0; i < 100; i++ {
+		out := RNDF(nil, nil)
+		if out.Type() != object.NUMBER {
+			t.Fatalf("We expected a number, but didn't receive one")
+		}
+		n := out.(*object.NumberObject).Value
+		if n < 0 || n >= 1 {
+			t.Fatalf("RNDF returned %f, which is out of range", n)
+		}
+	}
+}
+
 func TestSGN(t *testing.T) {
 	//
 	// Requires a number argument
@@ -17,7 +17,7 @@ func CHR(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	i := args[0].(*object.NumberObject).Value
 
@@ -37,7 +37,7 @@ func CODE(env Environment, args []object.Object) object.Object {
 
 	// Get the (string) argument.
 	if args[0].Type() != object.STRING {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 
 	// We convert this to an array of runes because we
@@ -57,7 +57,7 @@ func LEFT(env Environment, args []object.Object) object.Object {
 
 	// Get the (string) argument.
 	if args[0].Type() != object.STRING {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 
 	// We convert this to an array of runes because we
@@ -66,7 +66,7 @@ func LEFT(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[1].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	n := int(args[1].(*object.NumberObject).Value)
 
@@ -89,7 +89,7 @@ func LEN(env Environment, args []object.Object) object.Object {
 
 	// Get the (string) argument.
 	if args[0].Type() != object.STRING {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	in := args[0].(*object.StringObject).Value
 
@@ -104,7 +104,7 @@ func MID(env Environment, args []object.Object) object.Object {
 
 	// Get the (string) argument.
 	if args[0].Type() != object.STRING {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 
 	// We convert this to an array of runes because we
@@ -113,7 +113,7 @@ func MID(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[1].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	offset := int(args[1].(*object.NumberObject).Value)
 	if offset < 0 {
@@ -122,7 +122,7 @@ func MID(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[2].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	count := int(args[2].(*object.NumberObject).Value)
 	if count < 0 {
@@ -151,7 +151,7 @@ func RIGHT(env Environment, args []object.Object) object.Object {
 
 	// Get the (string) argument.
 	if args[0].Type() != object.STRING {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 
 	// We convert this to an array of runes because we
@@ -160,7 +160,7 @@ func RIGHT(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[1].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	n := int(args[1].(*object.NumberObject).Value)
 
@@ -182,7 +182,7 @@ func SPC(env Environment, args []object.Object) object.Object {
 
 	// Get the (float) argument.
 	if args[0].Type() != object.NUMBER {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 	n := int(args[0].(*object.NumberObject).Value)
 
@@ -232,7 +232,7 @@ func TL(env Environment, args []object.Object) object.Object {
 
 	// Get the (string) argument.
 	if args[0].Type() != object.STRING {
-		return object.Error("Wrong type")
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
 	}
 
 	// We convert this to an array of runes because we
@@ -264,7 +264,7 @@ func VAL(env Environment, args []object.Object) object.Object {
 	s := args[0].(*object.StringObject).Value
 	b, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return object.Error("VAL: %s", err.Error())
+		return object.CodedError(object.ErrTypeMismatch, "VAL: %s", err.Error())
 	}
 
 	return &object.NumberObject{Value: float64(b)}
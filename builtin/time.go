@@ -0,0 +1,148 @@
+// The builtin package provides the ability to register our built-in functions.
+//
+// time.go implements our time-related primitives, along with the clock
+// they are built upon.
+
+package builtin
+
+import (
+	"context"
+	"time"
+
+	"github.com/skx/gobasic/object"
+)
+
+// Clock is the interface which the time-related primitives use to read
+// the current time, and to pause execution.
+//
+// The default implementation is SystemClock, which uses the real time,
+// but embedders and test-cases can replace it with something of their
+// own - for example a clock which never really sleeps.
+type Clock interface {
+
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sleep pauses for the given duration, returning early with
+	// an error if the context is cancelled first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// ClockEnvironment is an optional interface which an Environment may
+// implement to supply the clock, and the context, that the time-related
+// primitives should use.
+//
+// Environments which don't implement it will get the SystemClock, and
+// a background context which will never be cancelled.
+type ClockEnvironment interface {
+
+	// Clock returns the clock to use.
+	Clock() Clock
+
+	// Context returns the context which will interrupt sleeping.
+	Context() context.Context
+}
+
+// SystemClock is a Clock which uses the real system time.
+type SystemClock struct{}
+
+// Now returns the current time.
+func (s SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep pauses for the given duration, or until the context is cancelled.
+func (s SystemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// clockFor returns the clock, and context, associated with the given
+// environment - falling back to the defaults if there are none.
+func clockFor(env Environment) (Clock, context.Context) {
+	if c, ok := env.(ClockEnvironment); ok {
+		clock := c.Clock()
+		if clock == nil {
+			clock = SystemClock{}
+		}
+		ctx := c.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return clock, ctx
+	}
+	return SystemClock{}, context.Background()
+}
+
+// pause is the shared implementation of SLEEP and PAUSE.
+func pause(env Environment, name string, d time.Duration) object.Object {
+
+	// Negative pauses are meaningless
+	if d < 0 {
+		return object.Error("Argument to %s must be >=0", name)
+	}
+
+	clock, ctx := clockFor(env)
+	if err := clock.Sleep(ctx, d); err != nil {
+		return object.Error("%s interrupted: %s", name, err.Error())
+	}
+	return &object.NumberObject{Value: 0}
+}
+
+// DATE returns the current date, as a string of the form "MM-DD-YYYY".
+func DATE(env Environment, args []object.Object) object.Object {
+	clock, _ := clockFor(env)
+	return &object.StringObject{Value: clock.Now().Format("01-02-2006")}
+}
+
+// PAUSE pauses execution for the given number of frames, where a frame
+// is a fiftieth of a second - as it was upon the ZX Spectrum.
+func PAUSE(env Environment, args []object.Object) object.Object {
+
+	// Get the (float) argument.
+	if args[0].Type() != object.NUMBER {
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
+	}
+	i := args[0].(*object.NumberObject).Value
+
+	return pause(env, "PAUSE", time.Duration(i*float64(time.Second)/50))
+}
+
+// SLEEP pauses execution for the given number of seconds.
+func SLEEP(env Environment, args []object.Object) object.Object {
+
+	// Get the (float) argument.
+	if args[0].Type() != object.NUMBER {
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type")
+	}
+	i := args[0].(*object.NumberObject).Value
+
+	return pause(env, "SLEEP", time.Duration(i*float64(time.Second)))
+}
+
+// TIME returns the current time, as a string of the form "HH:MM:SS".
+func TIME(env Environment, args []object.Object) object.Object {
+	clock, _ := clockFor(env)
+	return &object.StringObject{Value: clock.Now().Format("15:04:05")}
+}
+
+// TIMER returns the number of seconds which have elapsed since midnight.
+func TIMER(env Environment, args []object.Object) object.Object {
+	clock, _ := clockFor(env)
+
+	now := clock.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// Truncate to hundredths of a second, to avoid surprises.
+	secs := now.Sub(midnight).Seconds()
+	secs = float64(int(secs*100)) / 100
+
+	return &object.NumberObject{Value: secs}
+}
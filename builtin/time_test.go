@@ -0,0 +1,145 @@
+// time_test.go - Simple test-cases for time-related primitives.
+
+package builtin
+
+import (
+	"bufio"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skx/gobasic/object"
+)
+
+// fakeClock is a clock which returns a fixed time, and which records
+// how long it was asked to sleep rather than actually sleeping.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.slept += d
+	return nil
+}
+
+// clockEnv is an environment which supplies a clock and a context.
+type clockEnv struct {
+	clock *fakeClock
+	ctx   context.Context
+}
+
+func (c *clockEnv) StdInput() *bufio.Reader  { return nil }
+func (c *clockEnv) StdOutput() *bufio.Writer { return nil }
+func (c *clockEnv) StdError() *bufio.Writer  { return nil }
+func (c *clockEnv) LineEnding() string       { return "" }
+func (c *clockEnv) Data() interface{}        { return nil }
+func (c *clockEnv) Clock() Clock             { return c.clock }
+func (c *clockEnv) Context() context.Context { return c.ctx }
+
+func newClockEnv() *clockEnv {
+	return &clockEnv{
+		clock: &fakeClock{now: time.Date(2021, time.March, 4, 13, 14, 15, 500000000, time.UTC)},
+		ctx:   context.Background(),
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	env := newClockEnv()
+
+	out := DATE(env, nil)
+	if out.Type() != object.STRING {
+		t.Fatalf("We expected a string-result, but got something else")
+	}
+	if out.(*object.StringObject).Value != "03-04-2021" {
+		t.Errorf("DATE$ gave the wrong result: %s", out.String())
+	}
+
+	out = TIME(env, nil)
+	if out.Type() != object.STRING {
+		t.Fatalf("We expected a string-result, but got something else")
+	}
+	if out.(*object.StringObject).Value != "13:14:15" {
+		t.Errorf("TIME$ gave the wrong result: %s", out.String())
+	}
+
+	out = TIMER(env, nil)
+	if out.Type() != object.NUMBER {
+		t.Fatalf("We expected a number-result, but got something else")
+	}
+	if out.(*object.NumberObject).Value != 13*3600+14*60+15.5 {
+		t.Errorf("TIMER gave the wrong result: %s", out.String())
+	}
+
+	//
+	// Without a clock-environment we use the system clock, so all
+	// we can do is test the type.
+	//
+	out = TIMER(nil, nil)
+	if out.Type() != object.NUMBER {
+		t.Errorf("We expected a number-result, but got something else")
+	}
+}
+
+func TestSleep(t *testing.T) {
+
+	//
+	// Requires a number argument
+	//
+	var failArgs []object.Object
+	failArgs = append(failArgs, object.Error("Bogus type"))
+	if SLEEP(nil, failArgs).Type() != object.ERROR {
+		t.Errorf("We expected a type-error, but didn't receive one")
+	}
+	if PAUSE(nil, failArgs).Type() != object.ERROR {
+		t.Errorf("We expected a type-error, but didn't receive one")
+	}
+
+	//
+	// Negative values are errors.
+	//
+	env := newClockEnv()
+	out := SLEEP(env, []object.Object{object.Number(-1)})
+	if out.Type() != object.ERROR {
+		t.Errorf("We expected an error, but didn't receive one")
+	}
+
+	//
+	// SLEEP is in seconds, PAUSE in fiftieths of a second.
+	//
+	SLEEP(env, []object.Object{object.Number(2)})
+	PAUSE(env, []object.Object{object.Number(25)})
+	if env.clock.slept != 2500*time.Millisecond {
+		t.Errorf("We slept for the wrong duration: %s", env.clock.slept)
+	}
+
+	//
+	// A cancelled context interrupts the sleep.
+	//
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	env.ctx = ctx
+	out = SLEEP(env, []object.Object{object.Number(1)})
+	if out.Type() != object.ERROR {
+		t.Errorf("We expected an error, but didn't receive one")
+	}
+
+	//
+	// And the same is true of the real clock.
+	//
+	err := SystemClock{}.Sleep(ctx, time.Hour)
+	if err == nil {
+		t.Errorf("We expected an error, but didn't receive one")
+	}
+	err = SystemClock{}.Sleep(context.Background(), time.Millisecond)
+	if err != nil {
+		t.Errorf("Unexpected error sleeping: %s", err.Error())
+	}
+}
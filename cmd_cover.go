@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/cover"
+)
+
+// coverCommand implements "gobasic cover", which merges the lcov
+// tracefiles written by "gobasic -coverage", and summarizes them.
+//
+// The merged results may be written as a tracefile with -o, or as an
+// HTML report with -html.
+func coverCommand(args []string) int {
+
+	flags := flag.NewFlagSet("cover", flag.ExitOnError)
+	html := flags.String("html", "", "Write an HTML report to the given file.")
+	out := flags.String("o", "", "Write the merged tracefile to the given file.")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Printf("Usage: gobasic cover [-html report.html] [-o merged.info] coverage.info ..\n")
+		return 2
+	}
+
+	report := &cover.Report{}
+	for _, path := range flags.Args() {
+		r, err := readCoverage(path)
+		if err != nil {
+			fmt.Printf("Error reading %s - %s\n", path, err.Error())
+			return 3
+		}
+		report.Merge(r)
+	}
+
+	for _, f := range report.Files {
+		hit, found := f.LinesHit()
+		fmt.Printf("%s: %d of %d lines", f.Path, hit, found)
+		if found > 0 {
+			fmt.Printf(" (%.1f%%)", 100*float64(hit)/float64(found))
+		}
+		hit, found = f.BranchesHit()
+		fmt.Printf(", %d of %d branches", hit, found)
+		if found > 0 {
+			fmt.Printf(" (%.1f%%)", 100*float64(hit)/float64(found))
+		}
+		fmt.Printf("\n")
+	}
+
+	if *out != "" {
+		var buf bytes.Buffer
+		report.WriteLcov(&buf)
+		if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+			fmt.Printf("Error writing %s - %s\n", *out, err.Error())
+			return 1
+		}
+	}
+
+	if *html != "" {
+		var buf bytes.Buffer
+		if err := report.WriteHTML(&buf, readSource); err != nil {
+			fmt.Printf("Error writing %s - %s\n", *html, err.Error())
+			return 1
+		}
+		if err := os.WriteFile(*html, buf.Bytes(), 0644); err != nil {
+			fmt.Printf("Error writing %s - %s\n", *html, err.Error())
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// readCoverage reads an lcov tracefile.
+func readCoverage(path string) (*cover.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return cover.ReadLcov(f)
+}
+
+// writeCoverage merges the coverage of a single run of the program at
+// the given path into an lcov tracefile, creating it if it doesn't exist.
+func writeCoverage(f *cover.File, out string, path string) error {
+	report := &cover.Report{}
+	if _, err := os.Stat(out); err == nil {
+		report, err = readCoverage(out)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.Path = path
+	report.Add(f)
+
+	var buf bytes.Buffer
+	report.WriteLcov(&buf)
+	return os.WriteFile(out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/format"
+)
+
+// fmtCommand implements "gobasic fmt", which formats programs.
+//
+// By default the formatted programs are written to STDOUT, but with -w
+// the files are rewritten in-place, and with -d the changes are shown
+// as a diff instead.
+func fmtCommand(args []string) int {
+
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "Rewrite the files in-place, rather than showing the result.")
+	diff := flags.Bool("d", false, "Show the changes as a diff, rather than showing the result.")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Printf("Usage: gobasic fmt [-d] [-w] /path/to/input/script.bas ..\n")
+		return 2
+	}
+
+	ret := 0
+	for _, path := range flags.Args() {
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading %s - %s\n", path, err.Error())
+			ret = 3
+			continue
+		}
+
+		out, err := format.Source(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+			ret = 1
+			continue
+		}
+
+		if *diff {
+			fmt.Print(format.Diff(path, string(data), out))
+		}
+
+		if *write {
+			if out == string(data) {
+				continue
+			}
+			err = os.WriteFile(path, []byte(out), 0644)
+			if err != nil {
+				fmt.Printf("Error writing %s - %s\n", path, err.Error())
+				ret = 3
+			}
+			continue
+		}
+
+		if !*diff {
+			fmt.Print(out)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/lsp"
+)
+
+// lspCommand implements "gobasic lsp", which runs a Language Server
+// Protocol server upon STDIN/STDOUT, for use by editors.
+func lspCommand(args []string) int {
+
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: gobasic lsp\n")
+		return 2
+	}
+
+	server, err := lsp.New(os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting server - %s\n", err.Error())
+		return 3
+	}
+
+	if err := server.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error - %s\n", err.Error())
+		return 3
+	}
+	return 0
+}
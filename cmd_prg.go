@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/prg"
+)
+
+// bas2prgCommand implements "gobasic bas2prg", which converts a program
+// into the tokenised format used by Commodore BASIC v2.
+func bas2prgCommand(args []string) int {
+
+	if len(args) != 1 {
+		fmt.Printf("Usage: gobasic bas2prg /path/to/input/script.bas\n")
+		return 2
+	}
+	path := args[0]
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading %s - %s\n", path, err.Error())
+		return 3
+	}
+
+	out, err := prg.Tokenize(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, err.Error())
+		return 1
+	}
+
+	os.Stdout.Write(out)
+	return 0
+}
+
+// prg2basCommand implements "gobasic prg2bas", which converts a program
+// saved by Commodore BASIC v2 into source.
+func prg2basCommand(args []string) int {
+
+	if len(args) != 1 {
+		fmt.Printf("Usage: gobasic prg2bas /path/to/input/program.prg\n")
+		return 2
+	}
+	path := args[0]
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading %s - %s\n", path, err.Error())
+		return 3
+	}
+
+	out, err := prg.Detokenize(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		return 1
+	}
+
+	fmt.Print(out)
+	return 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/renum"
+)
+
+// renumCommand implements "gobasic renum", which renumbers a program.
+//
+// By default the renumbered program is written to STDOUT, but with -w
+// the file is rewritten in-place.
+func renumCommand(args []string) int {
+
+	def := renum.DefaultOptions()
+
+	flags := flag.NewFlagSet("renum", flag.ExitOnError)
+	start := flags.Int("start", def.Start, "The first line-number to use.")
+	step := flags.Int("step", def.Step, "The increment between line-numbers.")
+	from := flags.Int("from", 0, "The first existing line to renumber.")
+	to := flags.Int("to", 0, "The last existing line to renumber, 0 for the end of the program.")
+	write := flags.Bool("w", false, "Rewrite the file in-place, rather than showing the result.")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Printf("Usage: gobasic renum [-start N] [-step N] [-from N] [-to N] [-w] /path/to/input/script.bas\n")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading %s - %s\n", path, err.Error())
+		return 3
+	}
+
+	out, err := renum.Renumber(string(data), renum.Options{
+		Start: *start,
+		Step:  *step,
+		From:  *from,
+		To:    *to,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, err.Error())
+		return 1
+	}
+
+	if *write {
+		err = os.WriteFile(path, []byte(out), 0644)
+		if err != nil {
+			fmt.Printf("Error writing %s - %s\n", path, err.Error())
+			return 3
+		}
+		return 0
+	}
+
+	fmt.Print(out)
+	return 0
+}
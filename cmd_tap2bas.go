@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/tap"
+)
+
+// tap2basCommand implements "gobasic tap2bas", which converts a program
+// stored in a ZX Spectrum tape image into BASIC source.
+//
+// By default the first program upon the tape is converted, but -n may be
+// used to choose another by name, and -l lists the contents of the tape.
+func tap2basCommand(args []string) int {
+
+	flags := flag.NewFlagSet("tap2bas", flag.ExitOnError)
+	list := flags.Bool("l", false, "List the files stored upon the tape, rather than converting a program.")
+	name := flags.String("n", "", "The name of the program to convert.")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Printf("Usage: gobasic tap2bas [-l] [-n name] /path/to/input/tape.tap\n")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading %s - %s\n", path, err.Error())
+		return 3
+	}
+
+	files, err := tap.Files(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		return 1
+	}
+
+	if *list {
+		for i, f := range files {
+			fmt.Printf("%3d %-15s %-10q %6d bytes", i, f.Kind, f.Name, len(f.Data))
+			if f.Autostart >= 0 {
+				fmt.Printf(", LINE %d", f.Autostart)
+			}
+			fmt.Printf("\n")
+		}
+		return 0
+	}
+
+	for _, f := range files {
+		if f.Kind != tap.Program || (*name != "" && f.Name != *name) {
+			continue
+		}
+
+		src, err := f.Source()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %q: %s\n", path, f.Name, err.Error())
+			return 1
+		}
+		fmt.Print(src)
+		return 0
+	}
+
+	if *name != "" {
+		fmt.Fprintf(os.Stderr, "%s: there is no program named %q\n", path, *name)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: there are no programs upon the tape\n", path)
+	}
+	return 1
+}
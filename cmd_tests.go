@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skx/gobasic/basictest"
+)
+
+// testCommand implements "gobasic test", which runs the tests written in
+// BASIC found in the given files and directories, or beneath the current
+// directory if none are given.
+//
+// Failures are reported as "file:line: message", and the exit-code is
+// non-zero if any test failed.
+func testCommand(args []string) int {
+
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	update := flags.Bool("update", false, "Update the golden files to match the output of the tests.")
+	timeout := flags.Duration("timeout", 10*time.Second, "The time each test may run for.")
+	verbose := flags.Bool("v", false, "Report the tests which pass, as well as those which fail.")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	tests, err := basictest.Find(paths)
+	if err != nil {
+		fmt.Printf("Error finding tests - %s\n", err.Error())
+		return 3
+	}
+	if len(tests) == 0 {
+		fmt.Printf("no tests found\n")
+		return 0
+	}
+
+	failed := 0
+	for _, path := range tests {
+		r := basictest.Run(path, basictest.Options{Update: *update, Timeout: *timeout})
+
+		if r.Updated {
+			fmt.Printf("updated %s\n", basictest.Golden(path))
+		}
+
+		if r.Passed() {
+			if *verbose {
+				fmt.Printf("--- PASS: %s (%.2fs)\n", path, r.Duration.Seconds())
+			}
+			continue
+		}
+
+		failed++
+		fmt.Printf("--- FAIL: %s (%.2fs)\n", path, r.Duration.Seconds())
+		for _, f := range r.Failures {
+			location := path
+			if f.Line > 0 {
+				location = fmt.Sprintf("%s:%d", path, f.Line)
+			}
+			message := strings.ReplaceAll(strings.TrimSuffix(f.Message, "\n"), "\n", "\n        ")
+			fmt.Printf("    %s: %s\n", location, message)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("FAIL\t%d of %d tests failed\n", failed, len(tests))
+		return 1
+	}
+	fmt.Printf("ok\t%d tests passed\n", len(tests))
+	return 0
+}
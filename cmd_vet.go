@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/skx/gobasic/vet"
+)
+
+// vetCommand implements "gobasic vet", which reports common mistakes
+// in programs without running them.
+//
+// Problems are reported as "file:line:col: message", and the exit-code
+// is non-zero if any were found.
+func vetCommand(args []string) int {
+
+	flags := flag.NewFlagSet("vet", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Printf("Usage: gobasic vet /path/to/input/script.bas ..\n")
+		return 2
+	}
+
+	ret := 0
+	for _, path := range flags.Args() {
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading %s - %s\n", path, err.Error())
+			ret = 3
+			continue
+		}
+
+		problems, err := vet.Source(string(data))
+		if err != nil {
+			fmt.Printf("Error checking %s - %s\n", path, err.Error())
+			ret = 3
+			continue
+		}
+
+		for _, d := range problems {
+			fmt.Printf("%s:%s\n", path, d.String())
+			if ret == 0 {
+				ret = 1
+			}
+		}
+	}
+	return ret
+}
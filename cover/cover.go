@@ -0,0 +1,145 @@
+// Package cover holds the results of measuring the coverage of BASIC
+// programs: which lines were executed, and which way each IF statement
+// went.
+//
+// The interpreter gathers the results for a single run of a program, and
+// this package allows the results of many runs to be merged, and written
+// as either an lcov tracefile or an HTML report.
+package cover
+
+import (
+	"sort"
+)
+
+// Branch holds the outcomes of a single IF statement.
+type Branch struct {
+
+	// Line is the line of the source which holds the statement,
+	// counting from one.
+	Line int
+
+	// Block distinguishes between the IF statements upon a single
+	// line, counting from zero.
+	Block int
+
+	// True and False hold the number of times the condition was true,
+	// and false.
+	True  int
+	False int
+}
+
+// File holds the coverage of a single program.
+type File struct {
+
+	// Path is the path of the program's source.
+	Path string
+
+	// Lines holds the number of times each line was executed, indexed
+	// by the line of the source, counting from one.
+	//
+	// Lines which could have been executed, but weren't, are present
+	// with a count of zero.
+	Lines map[int]int
+
+	// Branches holds the outcomes of each IF statement, ordered by
+	// their position in the source.
+	Branches []*Branch
+}
+
+// NewFile returns a new, empty, record of the coverage of the program at
+// the given path.
+func NewFile(path string) *File {
+	return &File{Path: path, Lines: make(map[int]int)}
+}
+
+// Branch returns the record of the given IF statement, creating it if it
+// doesn't exist.
+func (f *File) Branch(line int, block int) *Branch {
+	for _, b := range f.Branches {
+		if b.Line == line && b.Block == block {
+			return b
+		}
+	}
+
+	b := &Branch{Line: line, Block: block}
+	f.Branches = append(f.Branches, b)
+	sort.Slice(f.Branches, func(i, j int) bool {
+		if f.Branches[i].Line != f.Branches[j].Line {
+			return f.Branches[i].Line < f.Branches[j].Line
+		}
+		return f.Branches[i].Block < f.Branches[j].Block
+	})
+	return b
+}
+
+// Merge adds the results of another record of the same program to ours.
+func (f *File) Merge(other *File) {
+	for line, n := range other.Lines {
+		f.Lines[line] += n
+	}
+	for _, b := range other.Branches {
+		ours := f.Branch(b.Line, b.Block)
+		ours.True += b.True
+		ours.False += b.False
+	}
+}
+
+// LinesHit returns the number of lines which were executed, and the
+// number which could have been.
+func (f *File) LinesHit() (int, int) {
+	hit := 0
+	for _, n := range f.Lines {
+		if n > 0 {
+			hit++
+		}
+	}
+	return hit, len(f.Lines)
+}
+
+// BranchesHit returns the number of branches which were taken, and the
+// number which could have been.  Each IF statement has two branches.
+func (f *File) BranchesHit() (int, int) {
+	hit := 0
+	for _, b := range f.Branches {
+		if b.True > 0 {
+			hit++
+		}
+		if b.False > 0 {
+			hit++
+		}
+	}
+	return hit, 2 * len(f.Branches)
+}
+
+// Report holds the coverage of a number of programs.
+type Report struct {
+
+	// Files holds the coverage of each program, in the order they
+	// were added.
+	Files []*File
+}
+
+// File returns the record of the program at the given path, creating it
+// if it doesn't exist.
+func (r *Report) File(path string) *File {
+	for _, f := range r.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	f := NewFile(path)
+	r.Files = append(r.Files, f)
+	return f
+}
+
+// Add merges the record of a single program into the report.
+func (r *Report) Add(f *File) {
+	r.File(f.Path).Merge(f)
+}
+
+// Merge merges another report into ours.
+func (r *Report) Merge(other *Report) {
+	for _, f := range other.Files {
+		r.Add(f)
+	}
+}
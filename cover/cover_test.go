@@ -0,0 +1,173 @@
+// cover_test.go - Test-cases for our coverage reports.
+
+package cover
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// sample returns a record of a single run of a program.
+func sample(path string, taken bool) *File {
+	f := NewFile(path)
+	f.Lines[1] = 1
+	f.Lines[2] = 1
+	f.Lines[3] = 0
+	b := f.Branch(2, 0)
+	if taken {
+		b.True++
+		f.Lines[3]++
+	} else {
+		b.False++
+	}
+	return f
+}
+
+// TestMerge tests merging the results of several runs.
+func TestMerge(t *testing.T) {
+	r := &Report{}
+	r.Add(sample("a.bas", false))
+	r.Add(sample("a.bas", true))
+	r.Add(sample("b.bas", false))
+
+	if len(r.Files) != 2 {
+		t.Fatalf("unexpected files %v", r.Files)
+	}
+
+	a := r.Files[0]
+	if a.Lines[1] != 2 || a.Lines[3] != 1 {
+		t.Errorf("unexpected lines %v", a.Lines)
+	}
+	if hit, found := a.LinesHit(); hit != 3 || found != 3 {
+		t.Errorf("unexpected lines hit %d/%d", hit, found)
+	}
+	if hit, found := a.BranchesHit(); hit != 2 || found != 2 {
+		t.Errorf("unexpected branches hit %d/%d", hit, found)
+	}
+
+	b := r.Files[1]
+	if hit, found := b.LinesHit(); hit != 2 || found != 3 {
+		t.Errorf("unexpected lines hit %d/%d", hit, found)
+	}
+	if hit, found := b.BranchesHit(); hit != 1 || found != 2 {
+		t.Errorf("unexpected branches hit %d/%d", hit, found)
+	}
+}
+
+// TestBranchOrder ensures branches are kept in the order of the source.
+func TestBranchOrder(t *testing.T) {
+	f := NewFile("x")
+	f.Branch(5, 1)
+	f.Branch(2, 0)
+	f.Branch(5, 0)
+	if f.Branch(2, 0) != f.Branches[0] {
+		t.Errorf("looking up a branch created another")
+	}
+
+	var got []int
+	for _, b := range f.Branches {
+		got = append(got, b.Line*10+b.Block)
+	}
+	if len(got) != 3 || got[0] != 20 || got[1] != 50 || got[2] != 51 {
+		t.Errorf("unexpected order %v", got)
+	}
+}
+
+// TestLcov tests writing, and reading, tracefiles.
+func TestLcov(t *testing.T) {
+	r := &Report{}
+	r.Add(sample("a.bas", true))
+	f := r.File("a.bas")
+	f.Branch(3, 0)
+
+	var buf bytes.Buffer
+	if err := r.WriteLcov(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `TN:
+SF:a.bas
+BRDA:2,0,0,1
+BRDA:2,0,1,0
+BRDA:3,0,0,-
+BRDA:3,0,1,-
+BRF:4
+BRH:1
+DA:1,1
+DA:2,1
+DA:3,1
+LF:3
+LH:3
+end_of_record
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected tracefile\n%s", buf.String())
+	}
+
+	// Reading it twice doubles the counts.
+	in, err := ReadLcov(strings.NewReader(expected + expected))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(in.Files) != 1 || in.Files[0].Lines[2] != 2 || in.Files[0].Branches[0].True != 2 {
+		t.Errorf("unexpected result %v", in.Files[0])
+	}
+	if len(in.Files[0].Branches) != 2 || in.Files[0].Branches[1].True != 0 {
+		t.Errorf("unexpected branches %v", in.Files[0].Branches)
+	}
+
+	// Records we don't understand, and checksums, are ignored.
+	in, err = ReadLcov(strings.NewReader("TN:x\nSF:c.go\nFN:1,main\nFNDA:1,main\nDA:1,3,abc\nend_of_record\n"))
+	if err != nil || in.Files[0].Lines[1] != 3 {
+		t.Errorf("unexpected result %v %v", in, err)
+	}
+}
+
+// TestLcovErrors tests that invalid tracefiles are reported.
+func TestLcovErrors(t *testing.T) {
+	bad := map[string]string{
+		"outside of a file": "DA:1,1\n",
+		"invalid DA":        "SF:a\nDA:x,1\n",
+		"invalid DA record": "SF:a\nDA:1\n",
+		"invalid BRDA":      "SF:a\nBRDA:1,0,2,1\n",
+	}
+	for expected, input := range bad {
+		_, err := ReadLcov(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("expected an error containing %q", expected)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q doesn't contain %q", err.Error(), expected)
+		}
+	}
+}
+
+// TestHTML tests the HTML report.
+func TestHTML(t *testing.T) {
+	r := &Report{}
+	r.Add(sample("a.bas", true))
+	r.Add(sample("b.bas", false))
+
+	src := "10 PRINT \"<hi>\"\n20 IF a THEN PRINT 3\n30 END\n"
+	var buf bytes.Buffer
+	err := r.WriteHTML(&buf, func(path string) (string, error) {
+		return src, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, expected := range []string{
+		"66.7% (2/3)",
+		`<tr class="partial">`,
+		`<tr class="miss">`,
+		"&lt;hi&gt;",
+		"T:1 F:0",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("report doesn't contain %q", expected)
+		}
+	}
+}
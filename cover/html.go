@@ -0,0 +1,130 @@
+package cover
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// htmlLine holds a single line of the listing of a program.
+type htmlLine struct {
+	Number int
+	Text   string
+
+	// Class is "hit", "miss", or "partial" for lines which could be
+	// executed, and empty for others.
+	Class string
+
+	// Count is the number of times the line was executed, and
+	// Branches describes its IF statements.
+	Count    string
+	Branches string
+}
+
+// htmlFile holds the listing of a single program.
+type htmlFile struct {
+	ID       int
+	Path     string
+	Lines    string
+	Branches string
+	Listing  []htmlLine
+}
+
+// page is the template of our report.
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>BASIC coverage</title>
+<style>
+body { font-family: sans-serif; }
+table.summary td, table.summary th { padding: 2px 12px; text-align: left; }
+pre { margin: 0; }
+table.listing { border-collapse: collapse; font-family: monospace; }
+table.listing td { padding: 0 8px; white-space: pre; }
+td.num, td.count, td.branches { color: #888; text-align: right; }
+tr.hit td.text { background: #dfd; }
+tr.miss td.text { background: #fdd; }
+tr.partial td.text { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>BASIC coverage</h1>
+<table class="summary">
+<tr><th>File</th><th>Lines</th><th>Branches</th></tr>
+{{range .}}<tr><td><a href="#file{{.ID}}">{{.Path}}</a></td><td>{{.Lines}}</td><td>{{.Branches}}</td></tr>
+{{end}}</table>
+{{range .}}
+<h2 id="file{{.ID}}">{{.Path}}</h2>
+<table class="listing">
+{{range .Listing}}<tr class="{{.Class}}"><td class="num">{{.Number}}</td><td class="count">{{.Count}}</td><td class="branches">{{.Branches}}</td><td class="text">{{.Text}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a single HTML page, which shows the
+// source of each program with the lines which were executed, and those
+// which weren't, highlighted.
+//
+// The source of each program is read by calling the given function with
+// its path.
+func (r *Report) WriteHTML(w io.Writer, source func(path string) (string, error)) error {
+	var files []htmlFile
+
+	for i, f := range r.Files {
+		src, err := source(f.Path)
+		if err != nil {
+			return err
+		}
+
+		hf := htmlFile{
+			ID:       i,
+			Path:     f.Path,
+			Lines:    percent(f.LinesHit()),
+			Branches: percent(f.BranchesHit()),
+		}
+
+		branches := make(map[int][]*Branch)
+		for _, b := range f.Branches {
+			branches[b.Line] = append(branches[b.Line], b)
+		}
+
+		for n, text := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+			l := htmlLine{Number: n + 1, Text: text}
+
+			if count, ok := f.Lines[n+1]; ok {
+				l.Count = fmt.Sprintf("%d", count)
+				l.Class = "miss"
+				if count > 0 {
+					l.Class = "hit"
+				}
+			}
+
+			var outcomes []string
+			for _, b := range branches[n+1] {
+				outcomes = append(outcomes, fmt.Sprintf("T:%d F:%d", b.True, b.False))
+				if l.Class == "hit" && (b.True == 0 || b.False == 0) {
+					l.Class = "partial"
+				}
+			}
+			l.Branches = strings.Join(outcomes, " ")
+
+			hf.Listing = append(hf.Listing, l)
+		}
+
+		files = append(files, hf)
+	}
+
+	return page.Execute(w, files)
+}
+
+// percent describes the proportion of things which were covered.
+func percent(hit int, found int) string {
+	if found == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%% (%d/%d)", 100*float64(hit)/float64(found), hit, found)
+}
@@ -0,0 +1,128 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadLcov reads a report from an lcov tracefile.
+//
+// We understand the records we write ourselves, and ignore any others,
+// such as the function records written for other languages.
+func ReadLcov(r io.Reader) (*Report, error) {
+	report := &Report{}
+
+	var f *File
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := strings.TrimSpace(scanner.Text())
+
+		key, value := text, ""
+		if i := strings.Index(text, ":"); i >= 0 {
+			key, value = text[:i], text[i+1:]
+		}
+
+		switch key {
+		case "SF":
+			f = report.File(value)
+			continue
+		case "end_of_record":
+			f = nil
+			continue
+		case "DA", "BRDA":
+		default:
+			continue
+		}
+
+		if f == nil {
+			return nil, fmt.Errorf("line %d: %s record outside of a file", n, key)
+		}
+
+		// A DA record may end with a checksum of the line, which
+		// we don't use.
+		fields := strings.Split(value, ",")
+		if key == "DA" && len(fields) == 3 {
+			fields = fields[:2]
+		}
+
+		var nums []int
+		for i, v := range fields {
+			// A branch which was never evaluated is recorded as
+			// "-", rather than zero.
+			if key == "BRDA" && i == 3 && v == "-" {
+				v = "0"
+			}
+			num, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s record %q", n, key, text)
+			}
+			nums = append(nums, num)
+		}
+
+		switch {
+		case key == "DA" && len(nums) == 2:
+			f.Lines[nums[0]] += nums[1]
+		case key == "BRDA" && len(nums) == 4 && (nums[2] == 0 || nums[2] == 1):
+			b := f.Branch(nums[0], nums[1])
+			if nums[2] == 0 {
+				b.True += nums[3]
+			} else {
+				b.False += nums[3]
+			}
+		default:
+			return nil, fmt.Errorf("line %d: invalid %s record %q", n, key, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// WriteLcov writes the report as an lcov tracefile.
+//
+// Each IF statement is written as a block with two branches, the first
+// taken when its condition is true, and the second when it is false.
+func (r *Report) WriteLcov(w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	for _, f := range r.Files {
+		fmt.Fprintf(out, "TN:\n")
+		fmt.Fprintf(out, "SF:%s\n", f.Path)
+
+		for _, b := range f.Branches {
+			for i, n := range []int{b.True, b.False} {
+				taken := strconv.Itoa(n)
+				if b.True+b.False == 0 {
+					taken = "-"
+				}
+				fmt.Fprintf(out, "BRDA:%d,%d,%d,%s\n", b.Line, b.Block, i, taken)
+			}
+		}
+		hit, found := f.BranchesHit()
+		fmt.Fprintf(out, "BRF:%d\n", found)
+		fmt.Fprintf(out, "BRH:%d\n", hit)
+
+		var lines []int
+		for l := range f.Lines {
+			lines = append(lines, l)
+		}
+		sort.Ints(lines)
+		for _, l := range lines {
+			fmt.Fprintf(out, "DA:%d,%d\n", l, f.Lines[l])
+		}
+		hit, found = f.LinesHit()
+		fmt.Fprintf(out, "LF:%d\n", found)
+		fmt.Fprintf(out, "LH:%d\n", hit)
+		fmt.Fprintf(out, "end_of_record\n")
+	}
+
+	return out.Flush()
+}
@@ -6,9 +6,12 @@
 //
 // 1. Setting a variable from golang which will be visible to BASIC.
 //
-// 2. Defining custom functions (CIRCLE, DOT, PEEK, POKE, SAVE).
+// 2. Defining custom functions (PEEK, POKE).
 //
-// 3. Retrieving the contents of BASIC values back to golang.
+// 3. Adding the drawing functions of the graphics package (CIRCLE, PLOT,
+// SAVE, and friends).
+//
+// 4. Retrieving the contents of BASIC values back to golang.
 //
 // Being standalone should make it simple to understand.
 //
@@ -16,25 +19,14 @@ package main
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
-	"os"
 
 	"github.com/skx/gobasic/builtin"
 	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/graphics"
 	"github.com/skx/gobasic/object"
 	"github.com/skx/gobasic/tokenizer"
 )
 
-// img holds a canvas.
-//
-// The BASIC program embedded in this program will draw upon an image
-// this is the actual image they draw upon.
-//
-var img *image.RGBA
-
 // peekFunction is the golang implementation of the PEEK primitive,
 // which is made available to BASIC.
 //
@@ -63,136 +55,6 @@ func pokeFunction(env builtin.Environment, args []object.Object) object.Object {
 	return &object.NumberObject{Value: 0.0}
 }
 
-// circleFunction allows drawing a circle upon our image.
-func circleFunction(env builtin.Environment, args []object.Object) object.Object {
-
-	// Ensure we have three arguments
-	if len(args) != 3 {
-		return object.Error("CIRCLE requires three arguments: X, Y, R")
-	}
-
-	var xx, yy, rr float64
-
-	if args[0].Type() == object.NUMBER {
-		xx = args[0].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for X")
-	}
-
-	if args[1].Type() == object.NUMBER {
-		yy = args[1].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for Y")
-	}
-
-	if args[2].Type() == object.NUMBER {
-		rr = args[2].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for R")
-	}
-
-	// We want the parameters as integers, rather than float64
-	x0 := int(xx)
-	y0 := int(yy)
-	r := int(rr)
-
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		black := color.RGBA{0, 0, 0, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{black}, image.Point{}, draw.Src)
-	}
-
-	// Create the colour
-	c := color.RGBA{0, 255, 0, 255}
-
-	// Now circle-magic happens.
-	x, y, dx, dy := r-1, 0, 1, 1
-	err := dx - (int(r) * 2)
-
-	for x > y {
-		img.Set(x0+x, y0+y, c)
-		img.Set(x0+y, y0+x, c)
-		img.Set(x0-y, y0+x, c)
-		img.Set(x0-x, y0+y, c)
-		img.Set(x0-x, y0-y, c)
-		img.Set(x0-y, y0-x, c)
-		img.Set(x0+y, y0-x, c)
-		img.Set(x0+x, y0-y, c)
-
-		if err <= 0 {
-			y++
-			err += dy
-			dy += 2
-		}
-		if err > 0 {
-			x--
-			dx += 2
-			err += dx - (r * 2)
-		}
-	}
-
-	// All done.
-	return &object.NumberObject{Value: 0.0}
-}
-
-// plotFunction is the golang implementation of the PLOT primitive.
-func plotFunction(env builtin.Environment, args []object.Object) object.Object {
-
-	var x, y float64
-
-	// Ensure we have two arguments
-	if len(args) != 2 {
-		return object.Error("PLOT requires two arguments: X, Y")
-	}
-
-	// Get X
-	if args[0].Type() == object.NUMBER {
-		x = args[0].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for X")
-	}
-
-	// Get Y
-	if args[1].Type() == object.NUMBER {
-		y = args[1].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for Y")
-	}
-
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		black := color.RGBA{0, 0, 0, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{black}, image.Point{}, draw.Src)
-	}
-
-	// Plot the pixel
-	img.Set(int(x), int(y), color.RGBA{255, 0, 0, 255})
-
-	return &object.NumberObject{Value: 0.0}
-}
-
-// saveFunction is the golang implementation of the SAVE primitive,
-// which is made available to BASIC.
-// We save the image-canvas to the file `out.png`.
-func saveFunction(env builtin.Environment, args []object.Object) object.Object {
-
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		black := color.RGBA{0, 0, 0, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{black}, image.Point{}, draw.Src)
-	}
-
-	// Now write out the image.
-	f, _ := os.OpenFile("out.png", os.O_WRONLY|os.O_CREATE, 0600)
-	defer f.Close()
-	png.Encode(f, img)
-
-	return &object.NumberObject{Value: 0.0}
-}
-
 func main() {
 
 	//
@@ -205,17 +67,20 @@ func main() {
  40 PEEK 30
  50 PRINT "\n" "I'M NOW CREATING AN IMAGE!!!!\n"
  60 REM
- 70 REM Draw 100 random pixels
- 80 REM
+ 70 REM Draw 100 random red pixels, upon black
+ 75 REM
+ 80 INK 0 : BOX 0, 0, 599, 399
+ 85 INK 2
  90 FOR I = 1 TO 100
 120  PLOT RND 600, RND 400
 130 NEXT I
 140 REM
-150 REM Draw a random number of circles
+150 REM Draw a random number of green circles
 160 REM
 170 LET R = RND 30
 180 IF R < 2 THEN LET R=2
 190 PRINT "\tWe will draw", R, "random circles upon the image\n"
+195 INK 4
 200 FOR I = 1 TO R
 240  CIRCLE RND 600, RND 400, RND 100
 250 NEXT I
@@ -240,12 +105,13 @@ func main() {
 	//
 	// Register some  functions.
 	//
-	e.RegisterBuiltin("CIRCLE", 3, circleFunction)
-	e.RegisterBuiltin("DOT", 2, plotFunction)
 	e.RegisterBuiltin("PEEK", 1, peekFunction)
-	e.RegisterBuiltin("PLOT", 2, plotFunction)
 	e.RegisterBuiltin("POKE", 2, pokeFunction)
-	e.RegisterBuiltin("SAVE", 0, saveFunction)
+
+	//
+	// Add the drawing functions, upon a canvas of 600x400 pixels.
+	//
+	graphics.Register(e, graphics.Options{Width: 600, Height: 400})
 
 	//
 	// Set an initial value to the variable "S".
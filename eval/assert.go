@@ -0,0 +1,87 @@
+// assert.go contains the support for the ASSERT statement, which allows
+// BASIC programs to test themselves.
+
+package eval
+
+import (
+	"fmt"
+
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/token"
+)
+
+// AssertionError is the error returned when the condition of an ASSERT
+// statement is false.
+//
+// It is never trapped by ON ERROR GOTO, so a failing assertion always
+// ends the program.
+type AssertionError struct {
+
+	// Line is the line of the source which holds the statement,
+	// counting from one.
+	Line int
+
+	// Label is the BASIC line-number of the line, if it has one.
+	Label string
+
+	// Message is the message given to the statement, or the text of
+	// its condition if there was none.
+	Message string
+}
+
+// Error returns a description of the failure.
+func (a *AssertionError) Error() string {
+	return "assertion failed: " + a.Message
+}
+
+// runASSERT handles the ASSERT statement, which fails if its condition
+// is false:
+//
+//	ASSERT a = 3
+//	ASSERT LEN a$ > 0, "the name is empty"
+func (e *Interpreter) runASSERT() error {
+
+	tok := e.program[e.offset]
+
+	// Skip the ASSERT token
+	e.offset++
+
+	if e.offset >= len(e.program) {
+		return fmt.Errorf("hit end of program processing ASSERT")
+	}
+
+	start := e.offset
+	result, err := e.condition()
+	if err != nil {
+		return err
+	}
+	message := token.Render(e.program[start:e.offset])
+
+	//
+	// Is there a message?
+	//
+	if e.offset < len(e.program) && e.program[e.offset].Type == token.COMMA {
+		e.offset++
+
+		val := e.expr(true)
+		if val.Type() == object.ERROR {
+			return toError(val.(*object.ErrorObject))
+		}
+		switch v := val.(type) {
+		case *object.StringObject:
+			message = v.Value
+		case *object.NumberObject:
+			message = fmt.Sprintf("%v", v.Value)
+		}
+	}
+
+	if result {
+		return nil
+	}
+
+	return &AssertionError{
+		Line:    tok.Line,
+		Label:   e.lineAt(e.offset - 1),
+		Message: message,
+	}
+}
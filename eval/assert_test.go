@@ -0,0 +1,79 @@
+// assert_test.go - Test-cases for the ASSERT statement.
+
+package eval
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// TestAssert tests assertions which pass, and fail.
+func TestAssert(t *testing.T) {
+
+	type Test struct {
+		Input   string
+		Line    int
+		Label   string
+		Message string
+	}
+
+	tests := []Test{
+		{Input: "10 LET a = 3\n20 ASSERT a = 3\n30 ASSERT a > 1 AND a < 4, \"range\"\n"},
+		{Input: "10 LET a$ = \"x\"\n20 ASSERT a$ : ASSERT 7, \"seven\"\n"},
+		{Input: "10 LET a = 3\n20 ASSERT a = 4\n", Line: 2, Label: "20", Message: "a = 4"},
+		{Input: "10 LET a = 3\n\n20 ASSERT a = 4 OR a = 5, \"a is \" + STR$ a\n", Line: 3, Label: "20", Message: "a is 3"},
+		{Input: "10 ASSERT 1 = 2, 42\n", Line: 1, Label: "10", Message: "42"},
+		{Input: "10 GOSUB 100\n20 END\n100 ASSERT 0\n", Line: 3, Label: "100", Message: "0"},
+
+		// Errors can't trap failed assertions.
+		{Input: "10 ON ERROR GOTO 100\n20 ASSERT 0\n30 END\n100 RESUME NEXT\n", Line: 2, Label: "20", Message: "0"},
+	}
+
+	for _, test := range tests {
+		e, err := New(tokenizer.New(test.Input))
+		if err != nil {
+			t.Fatalf("error parsing %q: %s", test.Input, err.Error())
+		}
+
+		err = e.Run()
+		if test.Message == "" {
+			if err != nil {
+				t.Errorf("unexpected error running %q: %s", test.Input, err.Error())
+			}
+			continue
+		}
+
+		var a *AssertionError
+		if !errors.As(err, &a) {
+			t.Errorf("expected an assertion to fail running %q, got %v", test.Input, err)
+			continue
+		}
+		if a.Line != test.Line || a.Label != test.Label || a.Message != test.Message {
+			t.Errorf("unexpected failure running %q: %d %s %q", test.Input, a.Line, a.Label, a.Message)
+		}
+		if !strings.Contains(err.Error(), "assertion failed: "+test.Message) {
+			t.Errorf("unexpected error %s", err.Error())
+		}
+	}
+}
+
+// TestAssertErrors tests malformed assertions.
+func TestAssertErrors(t *testing.T) {
+	for _, input := range []string{"10 ASSERT", "10 ASSERT 1 +\n", "10 ASSERT 1, 2 +\n"} {
+		e, err := New(tokenizer.New(input))
+		if err != nil {
+			continue
+		}
+		err = e.Run()
+		if err == nil {
+			t.Errorf("expected an error running %q", input)
+		}
+		var a *AssertionError
+		if errors.As(err, &a) {
+			t.Errorf("unexpected assertion failure running %q", input)
+		}
+	}
+}
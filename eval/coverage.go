@@ -0,0 +1,110 @@
+// coverage.go contains the support for measuring the coverage of
+// programs, recording which lines are executed, and which way each IF
+// statement goes.
+
+package eval
+
+import (
+	"github.com/skx/gobasic/cover"
+	"github.com/skx/gobasic/token"
+)
+
+// coverage records the coverage of a program as it runs.
+type coverage struct {
+
+	// file holds the results.
+	file *cover.File
+
+	// branches holds the record of each IF statement, indexed by the
+	// offset of its token within our program.
+	branches map[int]*cover.Branch
+
+	// line and offset are the source line, and the offset, of the
+	// most recent statement, which allow us to tell when a line is
+	// entered.
+	line   int
+	offset int
+}
+
+// SetCoverage allows the user to enable the measuring of which lines of
+// the program are executed, and which way each IF statement goes, which
+// may then be retrieved via Coverage.
+//
+// Enabling coverage discards any results gathered previously.
+func (e *Interpreter) SetCoverage(val bool) {
+	e.coverage = nil
+	if !val {
+		return
+	}
+
+	c := &coverage{
+		file:     cover.NewFile(""),
+		branches: make(map[int]*cover.Branch),
+		line:     -1,
+	}
+
+	//
+	// Each line which holds a statement may be executed, and each IF
+	// statement may go either way.
+	//
+	// Lines which hold only a line-number, or a label, are ignored,
+	// since jumping to them skips over it.
+	//
+	blocks := make(map[int]int)
+	for i, tok := range e.program {
+		if tok.Type != token.NEWLINE && tok.Type != token.LINENO && tok.Type != token.LABEL {
+			c.file.Lines[tok.Line] = 0
+		}
+		if tok.Type == token.IF {
+			c.branches[i] = c.file.Branch(tok.Line, blocks[tok.Line])
+			blocks[tok.Line]++
+		}
+	}
+
+	e.coverage = c
+}
+
+// Coverage returns the results gathered so far, or nil if coverage has
+// not been enabled via SetCoverage.
+//
+// The Path of the result is empty, since we don't know where the program
+// came from.
+func (e *Interpreter) Coverage() *cover.File {
+	if e.coverage == nil {
+		return nil
+	}
+
+	out := cover.NewFile("")
+	out.Merge(e.coverage.file)
+	return out
+}
+
+// executed records that the statement at the given offset is about to
+// be executed.
+//
+// A line is counted each time it is entered, either because the previous
+// statement was upon a different line, or because we jumped back to an
+// earlier statement upon the same line - as a loop upon a single line
+// does.
+func (c *coverage) executed(offset int, tok token.Token) {
+	if tok.Line != c.line || offset <= c.offset {
+		if _, ok := c.file.Lines[tok.Line]; ok {
+			c.file.Lines[tok.Line]++
+		}
+	}
+	c.line = tok.Line
+	c.offset = offset
+}
+
+// branch records the outcome of the IF statement at the given offset.
+func (c *coverage) branch(offset int, result bool) {
+	b := c.branches[offset]
+	if b == nil {
+		return
+	}
+	if result {
+		b.True++
+	} else {
+		b.False++
+	}
+}
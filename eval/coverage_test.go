@@ -0,0 +1,87 @@
+// coverage_test.go - Test-cases for measuring coverage.
+
+package eval
+
+import (
+	"testing"
+
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// TestCoverage tests the lines, and branches, which are recorded.
+func TestCoverage(t *testing.T) {
+	src := `10 LET a = 0
+20 FOR i = 1 TO 3 : LET a = a + i : NEXT i
+30 IF a > 100 THEN PRINT "big" ELSE GOSUB 100
+40 IF a = 6 THEN END
+50 PRINT "unreached"
+100 REM subroutine
+110 RETURN
+`
+	e, err := New(tokenizer.New(src))
+	if err != nil {
+		t.Fatalf("error parsing: %s", err.Error())
+	}
+
+	if e.Coverage() != nil {
+		t.Errorf("expected no coverage before it is enabled")
+	}
+
+	e.SetCoverage(true)
+	if err = e.Run(); err != nil {
+		t.Fatalf("error running: %s", err.Error())
+	}
+
+	c := e.Coverage()
+	// The comment upon line 6 can't be executed.
+	expected := map[int]int{1: 1, 2: 3, 3: 1, 4: 1, 5: 0, 7: 1}
+	if len(c.Lines) != len(expected) {
+		t.Fatalf("unexpected lines %v", c.Lines)
+	}
+	for line, n := range expected {
+		if c.Lines[line] != n {
+			t.Errorf("line %d executed %d times, expected %d", line, c.Lines[line], n)
+		}
+	}
+
+	if len(c.Branches) != 2 {
+		t.Fatalf("unexpected branches %v", c.Branches)
+	}
+	if b := c.Branches[0]; b.Line != 3 || b.True != 0 || b.False != 1 {
+		t.Errorf("unexpected branch %v", *b)
+	}
+	if b := c.Branches[1]; b.Line != 4 || b.True != 1 || b.False != 0 {
+		t.Errorf("unexpected branch %v", *b)
+	}
+
+	// The result is a copy.
+	c.Lines[5] = 10
+	if e.Coverage().Lines[5] != 0 {
+		t.Errorf("coverage was modified")
+	}
+
+	e.SetCoverage(false)
+	if e.Coverage() != nil {
+		t.Errorf("expected no coverage after it is disabled")
+	}
+}
+
+// TestCoverageBlocks tests several IF statements upon a single line.
+func TestCoverageBlocks(t *testing.T) {
+	e, err := New(tokenizer.New("10 LET a = 1 : IF a = 1 THEN IF a = 2 THEN LET a = 3\n"))
+	if err != nil {
+		t.Fatalf("error parsing: %s", err.Error())
+	}
+	e.SetCoverage(true)
+	if err = e.Run(); err != nil {
+		t.Fatalf("error running: %s", err.Error())
+	}
+
+	c := e.Coverage()
+	if len(c.Branches) != 2 || c.Branches[0].Block != 0 || c.Branches[1].Block != 1 {
+		t.Fatalf("unexpected branches %v", c.Branches)
+	}
+	if c.Branches[0].True != 1 || c.Branches[1].False != 1 {
+		t.Errorf("unexpected outcomes %v %v", *c.Branches[0], *c.Branches[1])
+	}
+}
@@ -0,0 +1,279 @@
+// dialect.go contains the profiles which allow programs written for other
+// families of BASIC to run unmodified.
+
+package eval
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/token"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// Random describes the behaviour of RND.
+type Random int
+
+// The forms of RND which we support.
+const (
+	// RandomInteger means "RND n" returns an integer from 0 to n-1.
+	RandomInteger Random = iota
+
+	// RandomFraction means "RND" returns a number from zero to one,
+	// and takes no argument, although "RND(1)" is also accepted.
+	RandomFraction
+
+	// RandomFunction means "RND(x)" returns a number from zero to
+	// one, and the argument is ignored.
+	RandomFunction
+)
+
+// Dialect describes the conventions which a program follows.
+type Dialect struct {
+
+	// Name is the name of the dialect, as used by the -dialect flag.
+	Name string
+
+	// Base is the lowest array index, either 0 or 1.
+	//
+	// With a base of 0 "DIM a(10)" creates eleven elements, 0 to 10,
+	// with a base of 1 it creates ten.
+	Base int
+
+	// Parens is true if arrays are indexed as "a(1)", rather than
+	// "a[1]", and a built-in followed by "(" takes its arguments from
+	// within the brackets, as in "LEFT$(a$, 2) + b$".
+	Parens bool
+
+	// Slicing is true if strings may be sliced as "a$(2 TO 4)".
+	Slicing bool
+
+	// RND describes the behaviour of RND.
+	RND Random
+
+	// RequireLet is true if assignments must begin with LET.
+	RequireLet bool
+
+	// PrintNewline is true if PRINT ends its output with a newline,
+	// unless it finishes with ";" or ",".  A ";" then separates
+	// values without adding a space.
+	PrintNewline bool
+
+	// Abbreviations maps abbreviations, or alternative spellings, of
+	// keywords to the keyword they stand for, such as "?" for PRINT.
+	Abbreviations map[string]string
+}
+
+// The dialects which we support.
+var (
+	// Default is our own dialect.
+	Default = Dialect{Name: "gobasic"}
+
+	// Spectrum is the dialect of Sinclair ZX Spectrum BASIC.
+	Spectrum = Dialect{
+		Name:          "spectrum",
+		Base:          1,
+		Parens:        true,
+		Slicing:       true,
+		RND:           RandomFraction,
+		PrintNewline:  true,
+		Abbreviations: map[string]string{"GO TO": "GOTO", "GO SUB": "GOSUB"},
+	}
+
+	// GWBASIC is the dialect of Microsoft GW-BASIC, and its relatives.
+	GWBASIC = Dialect{
+		Name:          "gwbasic",
+		Parens:        true,
+		RND:           RandomFraction,
+		PrintNewline:  true,
+		Abbreviations: map[string]string{"?": "PRINT"},
+	}
+
+	// Dartmouth is the dialect of the original Dartmouth BASIC.
+	Dartmouth = Dialect{
+		Name:          "dartmouth",
+		Parens:        true,
+		RND:           RandomFunction,
+		RequireLet:    true,
+		PrintNewline:  true,
+		Abbreviations: map[string]string{"GO TO": "GOTO", "GO SUB": "GOSUB"},
+	}
+)
+
+// Dialects returns the dialects we support, indexed by name.
+func Dialects() map[string]Dialect {
+	out := make(map[string]Dialect)
+	for _, d := range []Dialect{Default, Spectrum, GWBASIC, Dartmouth} {
+		out[d.Name] = d
+	}
+	return out
+}
+
+// DialectNames returns the sorted names of the dialects we support.
+func DialectNames() []string {
+	var names []string
+	for name := range Dialects() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// abbreviate replaces any abbreviations the dialect allows with the
+// keywords they stand for.
+//
+// Abbreviations may be a sequence of words, such as "GO TO", and are
+// matched regardless of case.  Strings are left alone.
+func (d Dialect) abbreviate(tokens []token.Token) []token.Token {
+	if len(d.Abbreviations) == 0 {
+		return tokens
+	}
+
+	// Match the longest abbreviations first.
+	var from []string
+	for k := range d.Abbreviations {
+		from = append(from, k)
+	}
+	sort.Slice(from, func(i, j int) bool {
+		if len(from[i]) != len(from[j]) {
+			return len(from[i]) > len(from[j])
+		}
+		return from[i] < from[j]
+	})
+
+	var out []token.Token
+	for i := 0; i < len(tokens); i++ {
+		n := 0
+		for _, k := range from {
+			if matches(tokens[i:], strings.Fields(k)) {
+				tok := tokenizer.New(d.Abbreviations[k]).NextToken()
+				tok.Offset = tokens[i].Offset
+				tok.Line = tokens[i].Line
+				tok.Column = tokens[i].Column
+				out = append(out, tok)
+				n = len(strings.Fields(k))
+				break
+			}
+		}
+		if n == 0 {
+			out = append(out, tokens[i])
+			continue
+		}
+		i += n - 1
+	}
+	return out
+}
+
+// matches returns true if the tokens begin with the given words.
+func matches(tokens []token.Token, words []string) bool {
+	if len(words) > len(tokens) {
+		return false
+	}
+	for i, w := range words {
+		if tokens[i].Type == token.STRING || !strings.EqualFold(tokens[i].Literal, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// arguments reads the arguments to a built-in which are enclosed in
+// brackets, the current token being the opening bracket.
+func (e *Interpreter) arguments(name string) ([]object.Object, object.Object) {
+	var args []object.Object
+
+	// Skip the "(".
+	e.offset++
+
+	if e.offset < len(e.program) && e.program[e.offset].Type == token.RBRACKET {
+		e.offset++
+		return args, nil
+	}
+
+	for e.offset < len(e.program) {
+		obj := e.expr(true)
+		if obj.Type() == object.ERROR {
+			return nil, obj
+		}
+		args = append(args, obj)
+
+		if e.offset >= len(e.program) {
+			break
+		}
+		switch e.program[e.offset].Type {
+		case token.COMMA:
+			e.offset++
+		case token.RBRACKET:
+			e.offset++
+			return args, nil
+		default:
+			return nil, object.Error("Expected ',' or ')' in arguments to %s, got %s", name, e.program[e.offset].Literal)
+		}
+	}
+	return nil, object.Error("Hit end of program processing arguments to %s", name)
+}
+
+// slice handles "a$(2 TO 4)", and its variations, the current token
+// being the opening bracket.
+//
+// Characters are counted from one, and either end of the range may be
+// omitted.  A single position selects a single character.
+func (e *Interpreter) slice(s string) object.Object {
+
+	chars := []rune(s)
+	start := 1
+	end := len(chars)
+
+	// index evaluates one end of the range.
+	index := func() (int, object.Object) {
+		obj := e.expr(true)
+		if obj.Type() == object.ERROR {
+			return 0, obj
+		}
+		if obj.Type() != object.NUMBER {
+			return 0, object.CodedError(object.ErrTypeMismatch, "String slices must be numbers")
+		}
+		return int(obj.(*object.NumberObject).Value), nil
+	}
+
+	// Skip the "(".
+	e.offset++
+	if e.offset >= len(e.program) {
+		return object.Error("Hit end of program processing string slice")
+	}
+
+	if e.program[e.offset].Type != token.TO {
+		n, err := index()
+		if err != nil {
+			return err
+		}
+		start = n
+		if e.offset < len(e.program) && e.program[e.offset].Type != token.TO {
+			end = n
+		}
+	}
+	if e.offset < len(e.program) && e.program[e.offset].Type == token.TO {
+		e.offset++
+		if e.offset < len(e.program) && e.program[e.offset].Type != token.RBRACKET {
+			n, err := index()
+			if err != nil {
+				return err
+			}
+			end = n
+		}
+	}
+
+	if e.offset >= len(e.program) || e.program[e.offset].Type != token.RBRACKET {
+		return object.Error("Unclosed bracket in string slice")
+	}
+	e.offset++
+
+	if start > end {
+		return &object.StringObject{Value: ""}
+	}
+	if start < 1 || end > len(chars) {
+		return object.CodedError(object.ErrSubscript, "String slice %d TO %d is out of range", start, end)
+	}
+	return &object.StringObject{Value: string(chars[start-1 : end])}
+}
@@ -0,0 +1,162 @@
+// dialect_test.go - Test-cases for our dialect profiles.
+
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// runDialect runs the given program in the given dialect, returning the
+// interpreter along with anything it printed.
+func runDialect(t *testing.T, d Dialect, src string) (*Interpreter, string, error) {
+	e, err := NewWithDialect(tokenizer.New(src), d)
+	if err != nil {
+		t.Fatalf("error parsing %q: %s", src, err.Error())
+	}
+
+	var buf bytes.Buffer
+	e.STDOUT = bufio.NewWriter(&buf)
+	err = e.Run()
+	e.STDOUT.Flush()
+	return e, buf.String(), err
+}
+
+// TestDialects ensures each dialect can be found by name.
+func TestDialects(t *testing.T) {
+	for _, name := range DialectNames() {
+		d, ok := Dialects()[name]
+		if !ok || d.Name != name {
+			t.Errorf("failed to find dialect %s", name)
+		}
+	}
+	if len(DialectNames()) != 4 {
+		t.Errorf("unexpected dialects %v", DialectNames())
+	}
+}
+
+// TestSpectrum tests arrays, slicing, and GO TO.
+func TestSpectrum(t *testing.T) {
+	src := `10 DIM a(3)
+20 FOR i = 1 TO 3
+30 LET a(i) = i * 10
+40 NEXT i
+50 LET s$ = "abcdef"
+60 PRINT a(1); a(3)
+70 PRINT s$(2 TO 4); s$(5); s$( TO 2); s$(6 TO )
+80 GO TO 100
+90 PRINT "skipped"
+100 LET r = RND
+110 PRINT LEN(s$) + 1;
+120 PRINT "!"
+`
+	e, out, err := runDialect(t, Spectrum, src)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if out != "1030\nbcdeabf\n7!\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	r := e.GetVariable("r")
+	if r.Type() != object.NUMBER {
+		t.Fatalf("RND didn't return a number")
+	}
+	if n := r.(*object.NumberObject).Value; n < 0 || n >= 1 {
+		t.Errorf("RND returned %f", n)
+	}
+
+	// Indexes start from one.
+	_, _, err = runDialect(t, Spectrum, "10 DIM a(3)\n20 LET a(0) = 1\n")
+	if err == nil {
+		t.Errorf("expected an error indexing from zero")
+	}
+	_, _, err = runDialect(t, Spectrum, "10 DIM a(0)\n")
+	if err == nil {
+		t.Errorf("expected an error for an empty array")
+	}
+
+	// Slices must be within the string.
+	_, _, err = runDialect(t, Spectrum, "10 LET s$ = \"abc\"\n20 PRINT s$(2 TO 4)\n")
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expected an out of range error, got %v", err)
+	}
+
+	// Although an empty slice is fine.
+	_, out, err = runDialect(t, Spectrum, "10 LET s$ = \"abc\"\n20 PRINT s$(3 TO 2); \"x\"\n")
+	if err != nil || out != "x\n" {
+		t.Errorf("unexpected result %q %v", out, err)
+	}
+}
+
+// TestGWBASIC tests abbreviations, and built-ins called with brackets.
+func TestGWBASIC(t *testing.T) {
+	src := `10 LET a$ = "hello"
+20 ? LEFT$(a$, 2) + "y", "?"
+30 DIM b(2)
+40 b(0) = 1
+50 b(2) = RND(1)
+`
+	e, out, err := runDialect(t, GWBASIC, src)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if out != "hey ?\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	b := e.GetArrayVariable("b", []int{0})
+	if b.Type() != object.NUMBER || b.(*object.NumberObject).Value != 1 {
+		t.Errorf("unexpected array contents %s", b.String())
+	}
+
+	// User-defined functions follow the dialect too.
+	_, out, err = runDialect(t, GWBASIC, "10 DEF FN f(x$) = LEFT$(x$, 1) + \"!\"\n20 ? FN f(\"abc\")\n")
+	if err != nil || out != "a!\n" {
+		t.Errorf("unexpected result %q %v", out, err)
+	}
+
+	// Built-ins must receive the right number of arguments.
+	_, _, err = runDialect(t, GWBASIC, "10 PRINT LEFT$(\"abc\")\n")
+	if err == nil || !strings.Contains(err.Error(), "expects 2") {
+		t.Errorf("expected an argument error, got %v", err)
+	}
+}
+
+// TestDartmouth tests that assignments require LET.
+func TestDartmouth(t *testing.T) {
+	_, _, err := runDialect(t, Dartmouth, "10 a = 3\n")
+	if err == nil || !strings.Contains(err.Error(), "require LET") {
+		t.Errorf("expected an error requiring LET, got %v", err)
+	}
+
+	e, _, err := runDialect(t, Dartmouth, "10 LET a = RND(7)\n20 GO SUB 40\n30 END\n40 LET b = 2\n50 RETURN\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if n := e.GetVariable("a").(*object.NumberObject).Value; n < 0 || n >= 1 {
+		t.Errorf("RND returned %f", n)
+	}
+	if n := e.GetVariable("b").(*object.NumberObject).Value; n != 2 {
+		t.Errorf("GO SUB failed")
+	}
+}
+
+// TestDefaultDialect ensures the default dialect is unchanged.
+func TestDefaultDialect(t *testing.T) {
+	e, out, err := runDialect(t, Default, "10 DIM a(2)\n20 LET a[2] = 4\n30 PRINT a[2], \"x\"\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if out != "4 x" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if e.Dialect().Name != "gobasic" {
+		t.Errorf("unexpected dialect %s", e.Dialect().Name)
+	}
+}
@@ -0,0 +1,277 @@
+// errors.go contains the support for trapping run-time errors, via
+// ON ERROR GOTO, and the primitives which describe them.
+
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/skx/gobasic/builtin"
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/token"
+)
+
+// ErrTimeout is returned by Run when the context given to NewWithContext
+// is cancelled, or its deadline passes, before the program ends.
+var ErrTimeout = errors.New("timeout during execution")
+
+// ErrStepLimit is returned by Run when the program executes more
+// statements than were allowed by SetStepLimit.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// LineError is the error returned by Run when a statement fails, and
+// describes where it was.
+type LineError struct {
+
+	// Line is the line of the source which holds the statement,
+	// counting from one.
+	Line int
+
+	// Label is the BASIC line-number of the line, if it has one.
+	Label string
+
+	// Err is the reason the statement failed.
+	Err error
+}
+
+// Error returns a description of the failure, prefixed by its line.
+func (l *LineError) Error() string {
+	if l.Label != "" {
+		return fmt.Sprintf("line %s : %s", l.Label, l.Err.Error())
+	}
+	return fmt.Sprintf("line %d : %s", l.Line, l.Err.Error())
+}
+
+// Unwrap returns the reason the statement failed.
+func (l *LineError) Unwrap() error {
+	return l.Err
+}
+
+// codedError is an error which carries one of the error-codes defined
+// in the object package, so that it may be exposed to BASIC via ERR.
+type codedError struct {
+
+	// code is the numeric error-code.
+	code int
+
+	// msg is the human-readable description of the error.
+	msg string
+}
+
+// Error returns the message associated with the error.
+func (c *codedError) Error() string {
+	return c.msg
+}
+
+// errorf creates a new error with the given code and message.
+func errorf(code int, format string, args ...interface{}) error {
+	return &codedError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// toError converts an error-object into an error, preserving its code.
+func toError(obj *object.ErrorObject) error {
+	code := obj.Code
+	if code == 0 {
+		code = object.ErrIllegalFunction
+	}
+	return &codedError{code: code, msg: obj.Value}
+}
+
+// errorCode returns the code associated with the given error.
+//
+// Errors which weren't created with a code are regarded as syntax-errors,
+// since that is what the bulk of them are.
+func errorCode(err error) int {
+	var c *codedError
+	if errors.As(err, &c) {
+		return c.code
+	}
+	return object.ErrSyntax
+}
+
+// lineAt returns the line-number of the line which contains the token
+// at the given offset, or "" if that line has no number.
+func (e *Interpreter) lineAt(offset int) string {
+	for i := offset; i >= 0 && i < len(e.program); i-- {
+		tok := e.program[i]
+		if tok.Type == token.LINENO {
+			return tok.Literal
+		}
+
+		// Walking back into the previous line means the line we
+		// started from had no number.
+		if tok.Type == token.NEWLINE && i != offset {
+			return ""
+		}
+	}
+	return ""
+}
+
+// trap is invoked when a statement fails.
+//
+// If there is an error-handler installed, and we're not already running
+// it, then we record the details of the error and jump to the handler,
+// returning true.  Otherwise we return false, and the error is fatal.
+func (e *Interpreter) trap(offset int, err error) bool {
+
+	if e.onError < 0 || e.inError {
+		return false
+	}
+
+	// Failed assertions are always fatal.
+	var a *AssertionError
+	if errors.As(err, &a) {
+		return false
+	}
+
+	e.inError = true
+	e.errCode = errorCode(err)
+	e.errMessage = err.Error()
+	e.errOffset = offset
+	e.errLine = e.lineAt(offset)
+
+	e.offset = e.onError
+	return true
+}
+
+// runOnError handles the installation of an error-handler:
+//
+//	ON ERROR GOTO 100
+//
+// A target of zero removes any handler, so errors become fatal again.
+func (e *Interpreter) runOnError() error {
+
+	// Skip the ERROR token
+	e.offset++
+
+	if e.offset >= len(e.program) {
+		return fmt.Errorf("hit end of program processing ON ERROR")
+	}
+
+	// We need a GOTO
+	tok := e.program[e.offset]
+	if tok.Type != token.GOTO {
+		return fmt.Errorf("expected GOTO after ON ERROR, got %v", tok)
+	}
+	e.offset++
+
+	if e.offset >= len(e.program) {
+		return fmt.Errorf("hit end of program processing ON ERROR")
+	}
+
+	// Now the line-number, or label
+	target := e.program[e.offset]
+	if target.Type != token.INT && target.Type != token.IDENT {
+		return fmt.Errorf("ERROR: ON ERROR GOTO should be followed by an integer")
+	}
+	e.offset++
+
+	if target.Literal == "0" {
+		e.onError = -1
+		return nil
+	}
+
+	offset, err := e.lineOffset("ON ERROR GOTO", target.Literal)
+	if err != nil {
+		return err
+	}
+	e.onError = offset
+	return nil
+}
+
+// runERROR handles the ERROR statement, which raises an error with the
+// given code.  This is mostly useful for testing error-handlers.
+func (e *Interpreter) runERROR() error {
+
+	// Skip the ERROR token
+	e.offset++
+
+	if e.offset >= len(e.program) {
+		return fmt.Errorf("hit end of program processing ERROR")
+	}
+
+	val := e.expr(true)
+	if val.Type() == object.ERROR {
+		return toError(val.(*object.ErrorObject))
+	}
+	if val.Type() != object.NUMBER {
+		return errorf(object.ErrTypeMismatch, "ERROR expects a numeric error-code, got %s", val.String())
+	}
+
+	code := int(val.(*object.NumberObject).Value)
+	return errorf(code, "Error %d", code)
+}
+
+// runRESUME handles returning from an error-handler, in one of the
+// following forms:
+//
+//	RESUME       - Retry the statement which failed.
+//	RESUME NEXT  - Continue with the statement after the one which failed.
+//	RESUME 100   - Continue at the given line, or label.
+func (e *Interpreter) runRESUME() error {
+
+	// Skip the RESUME token
+	e.offset++
+
+	if !e.inError {
+		return errorf(object.ErrResumeWithoutError, "RESUME without error")
+	}
+
+	//
+	// By default we retry the failing statement.
+	//
+	// We step back one token, because the caller will bump the
+	// offset after we return.
+	//
+	offset := e.errOffset - 1
+
+	if e.offset < len(e.program) {
+		tok := e.program[e.offset]
+
+		switch {
+		case tok.Type == token.NEXT:
+
+			// Find the end of the statement which failed, which
+			// may be followed by others upon the same line.
+			offset = e.errOffset
+			for offset < len(e.program) && e.program[offset].Type != token.NEWLINE && e.program[offset].Type != token.COLON {
+				offset++
+			}
+
+		case (tok.Type == token.INT && tok.Literal != "0") || tok.Type == token.IDENT:
+			var err error
+			offset, err = e.lineOffset("RESUME", tok.Literal)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	e.inError = false
+	e.offset = offset
+	return nil
+}
+
+// errBuiltin implements ERR, which returns the code of the most
+// recently trapped error.
+func errBuiltin(env builtin.Environment, args []object.Object) object.Object {
+	e := env.Data().(*Interpreter)
+	return &object.NumberObject{Value: float64(e.errCode)}
+}
+
+// erlBuiltin implements ERL, which returns the line-number upon which
+// the most recently trapped error occurred.
+func erlBuiltin(env builtin.Environment, args []object.Object) object.Object {
+	e := env.Data().(*Interpreter)
+	line, _ := strconv.ParseFloat(e.errLine, 64)
+	return &object.NumberObject{Value: line}
+}
+
+// errStrBuiltin implements ERR$, which returns the message associated
+// with the most recently trapped error.
+func errStrBuiltin(env builtin.Environment, args []object.Object) object.Object {
+	e := env.Data().(*Interpreter)
+	return &object.StringObject{Value: e.errMessage}
+}
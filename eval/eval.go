@@ -38,6 +38,11 @@ type userFunction struct {
 
 	// args is the array of variable-names to set for the arguments.
 	args []string
+
+	// eval evaluates the body, once the function has been called, and
+	// active is true while it is doing so.
+	eval   *Interpreter
+	active bool
 }
 
 // Interpreter holds our state.
@@ -110,10 +115,51 @@ type Interpreter struct {
 	data []object.Object
 
 	// fns contains a map of user-defined functions.
-	fns map[string]userFunction
+	fns map[string]*userFunction
 
 	// context for handling timeout
 	context context.Context
+
+	// steps counts the statements executed by Run, and stepLimit
+	// holds the most which may be executed, or zero for no limit.
+	steps     int
+	stepLimit int
+
+	// host holds the state of the application which embeds us, for
+	// the use of the builtins it registers.
+	host interface{}
+
+	// clock is used by the time-related primitives, such as TIMER
+	// and SLEEP.
+	clock builtin.Clock
+
+	// onError holds the offset of the error-handler installed by
+	// ON ERROR GOTO, or -1 if errors are not being trapped.
+	onError int
+
+	// inError is true while an error-handler is running.
+	inError bool
+
+	// errCode, errLine, and errMessage describe the most recently
+	// trapped error.  They are exposed via ERR, ERL, and ERR$.
+	errCode    int
+	errLine    string
+	errMessage string
+
+	// errOffset holds the offset of the statement which caused
+	// the most recently trapped error, for RESUME.
+	errOffset int
+
+	// dialect holds the conventions which the program follows.
+	dialect Dialect
+
+	// profile gathers statistics about the program as it runs, if
+	// profiling has been enabled.
+	profile *profiler
+
+	// coverage records the lines which are executed, if coverage has
+	// been enabled.
+	coverage *coverage
 }
 
 // StdInput allows access to the input-reading object.
@@ -153,12 +199,61 @@ func (e *Interpreter) LineEnding() string {
 	return e.LINEEND
 }
 
+// Clock returns the clock used by the time-related primitives.
+func (e *Interpreter) Clock() builtin.Clock {
+	return e.clock
+}
+
+// SetClock replaces the clock used by the time-related primitives.
+//
+// Useful for testing/embedding.
+func (e *Interpreter) SetClock(clock builtin.Clock) {
+	e.clock = clock
+}
+
+// Context returns the context which governs our execution.
+func (e *Interpreter) Context() context.Context {
+	return e.context
+}
+
+// SetStepLimit limits the number of statements which Run may execute,
+// after which it fails with ErrStepLimit.  A limit of zero, the default,
+// allows any number.
+//
+// Like a timeout this protects against programs which never end, but
+// it doesn't depend upon how fast the host is.
+func (e *Interpreter) SetStepLimit(limit int) {
+	e.stepLimit = limit
+}
+
+// Host returns the value given to SetHost, or nil.
+func (e *Interpreter) Host() interface{} {
+	return e.host
+}
+
+// SetHost stores a value for the application which embeds us, which
+// its builtins may retrieve via Host.
+//
+// This allows each interpreter to have its own state, for example the
+// image a program is drawing, rather than sharing it via globals:
+//
+//	canvas := env.Data().(*eval.Interpreter).Host().(*Canvas)
+func (e *Interpreter) SetHost(host interface{}) {
+	e.host = host
+}
+
 // New is our constructor.
 //
 // Given a lexer we store all the tokens it produced in our array, and
 // initialise some other state.
 func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
-	t := &Interpreter{offset: 0}
+	return NewWithDialect(stream, Default)
+}
+
+// NewWithDialect is a constructor which allows the dialect of the program
+// to be specified.
+func NewWithDialect(stream *tokenizer.Tokenizer, dialect Dialect) (*Interpreter, error) {
+	t := &Interpreter{offset: 0, dialect: dialect}
 
 	// setup a stack for holding line-numbers for GOSUB/RETURN
 	t.gstack = NewStack()
@@ -186,7 +281,7 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 	//
 	// Setup a map to hold user-defined functions.
 	//
-	t.fns = make(map[string]userFunction)
+	t.fns = make(map[string]*userFunction)
 
 	//
 	// No context by default
@@ -194,31 +289,71 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 	t.context = context.Background()
 
 	//
-	// The previous token we've seen, if any.
+	// Use the real time by default.
 	//
-	var prevToken token.Token
+	t.clock = builtin.SystemClock{}
 
 	//
-	// Save the tokens that our program consists of, one by one,
-	// until we hit the end.
+	// Errors are fatal, until a handler is installed.
 	//
-	// We also insert any implied GOTO statements into IF
-	// statements which lack them.
+	t.onError = -1
+
 	//
+	// Read all the tokens our program consists of, one by one,
+	// until we hit the end.
+	//
+	var tokens []token.Token
 	for {
-
-		//
-		// Fetch the next token from our tokenizer.
-		//
 		tok := stream.NextToken()
 		if tok.Type == token.EOF {
 			break
 		}
+		tokens = append(tokens, tok)
+	}
+
+	//
+	// Expand any abbreviations the dialect allows, such as "?" for
+	// PRINT.
+	//
+	tokens = dialect.abbreviate(tokens)
+
+	//
+	// Find the labels which are defined in the program, so that we
+	// know which names are valid jump-targets.
+	//
+	// A label may be written as "*loop" at the start of a line,
+	// which the tokenizer handles for us, or as "loop:", which
+	// we have to spot here.
+	//
+	labels := make(map[string]bool)
+	for i, tok := range tokens {
+		if tok.Type == token.IDENT && i+1 < len(tokens) && tokens[i+1].Type == token.COLON {
+			if i == 0 || tokens[i-1].Type == token.NEWLINE || tokens[i-1].Type == token.LINENO {
+				tokens[i].Type = token.LABEL
+			}
+		}
+		if tokens[i].Type == token.LABEL {
+			labels[tok.Literal] = true
+		}
+	}
+
+	//
+	// Save the tokens that our program consists of.
+	//
+	// Comments have no effect, so we drop them, and we also insert
+	// any implied GOTO statements into IF statements which lack
+	// them.
+	//
+	for i, tok := range tokens {
+
+		if tok.Type == token.REM {
+			continue
+		}
 
 		//
 		// If the previous token was a "THEN" or "ELSE", and the
-		// current token is an integer then we add in the implicit
-		// GOTO.
+		// current token is an integer, or the name of a label,
+		// then we add in the implicit GOTO.
 		//
 		// This allows the following two programs to be identical:
 		//
@@ -226,8 +361,8 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 		//
 		//   IF 1 < 2 THEN GOTO 300 ELSE GOTO 400
 		//
-		if prevToken.Type == token.THEN || prevToken.Type == token.ELSE {
-			if tok.Type == token.INT {
+		if i > 0 && (tokens[i-1].Type == token.THEN || tokens[i-1].Type == token.ELSE) {
+			if tok.Type == token.INT || (tok.Type == token.IDENT && labels[tok.Literal] && endOfStatement(tokens, i+1)) {
 				t.program = append(t.program,
 					token.Token{Type: token.GOTO, Literal: "GOTO"})
 			}
@@ -237,9 +372,6 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 		// Append the token to our array
 		//
 		t.program = append(t.program, tok)
-
-		// Continue - recording the previous token too.
-		prevToken = tok
 	}
 
 	//
@@ -264,11 +396,6 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 	// be a pain..
 	//
 
-	//
-	// We're not initially inside a comment.
-	//
-	inComment := false
-
 	//
 	// Process the complete program, now we've stored it.
 	//
@@ -297,22 +424,31 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 		}
 
 		//
-		// If we're in a comment then skip all action until
-		// we hit the next newline (or EOF).
+		// Did we find a label?
 		//
-		if inComment {
-			if tok.Type == token.NEWLINE {
-				inComment = false
+		// We record the offset of the final token of the label,
+		// either the name itself or the trailing ":", because
+		// jumps resume execution after the target.
+		//
+		if tok.Type == token.LABEL {
+			name := tok.Literal
+			if _, ok := t.lines[name]; ok {
+				err := fmt.Sprintf("WARN: Label %s is duplicated - GOTO/GOSUB behaviour is undefined", name)
+				t.StdError().WriteString(err + t.LineEnding())
 			}
-			continue
+			end := offset
+			if end+1 < len(t.program) && t.program[end+1].Type == token.COLON {
+				end++
+			}
+			t.lines[name] = end
 		}
 
 		//
-		// Comments start with REM, if we find a REM then
-		// we're in a comment.
+		// The tokenizer couldn't make sense of this; report it
+		// now, rather than failing mysteriously at run-time.
 		//
-		if tok.Type == token.REM {
-			inComment = true
+		if tok.Type == token.ILLEGAL {
+			return nil, fmt.Errorf("line %d, column %d: %s", tok.Line, tok.Column, tok.Problem())
 		}
 
 		//
@@ -389,13 +525,23 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 	t.RegisterBuiltin("ASN", 1, builtin.ASN)
 	t.RegisterBuiltin("ATN", 1, builtin.ATN)
 	t.RegisterBuiltin("BIN", 1, builtin.BIN)
+	t.RegisterBuiltin("BIN$", 1, builtin.BINS)
 	t.RegisterBuiltin("COS", 1, builtin.COS)
 	t.RegisterBuiltin("EXP", 1, builtin.EXP)
+	t.RegisterBuiltin("HEX$", 1, builtin.HEX)
 	t.RegisterBuiltin("INT", 1, builtin.INT)
 	t.RegisterBuiltin("LN", 1, builtin.LN)
 	t.RegisterBuiltin("LOG", 1, builtin.LN)
+	t.RegisterBuiltin("OCT$", 1, builtin.OCT)
 	t.RegisterBuiltin("PI", 0, builtin.PI)
-	t.RegisterBuiltin("RND", 1, builtin.RND)
+	switch dialect.RND {
+	case RandomFraction:
+		t.RegisterBuiltin("RND", 0, builtin.RNDF)
+	case RandomFunction:
+		t.RegisterBuiltin("RND", 1, builtin.RNDF)
+	default:
+		t.RegisterBuiltin("RND", 1, builtin.RND)
+	}
 	t.RegisterBuiltin("SGN", 1, builtin.SGN)
 	t.RegisterBuiltin("SIN", 1, builtin.SIN)
 	t.RegisterBuiltin("SQR", 1, builtin.SQR)
@@ -414,6 +560,18 @@ func New(stream *tokenizer.Tokenizer) (*Interpreter, error) {
 	t.RegisterBuiltin("STR$", 1, builtin.STR)
 	t.RegisterBuiltin("TL$", 1, builtin.TL)
 
+	// Time-related primitives
+	t.RegisterBuiltin("DATE$", 0, builtin.DATE)
+	t.RegisterBuiltin("PAUSE", 1, builtin.PAUSE)
+	t.RegisterBuiltin("SLEEP", 1, builtin.SLEEP)
+	t.RegisterBuiltin("TIME$", 0, builtin.TIME)
+	t.RegisterBuiltin("TIMER", 0, builtin.TIMER)
+
+	// Error-handling primitives
+	t.RegisterBuiltin("ERL", 0, erlBuiltin)
+	t.RegisterBuiltin("ERR", 0, errBuiltin)
+	t.RegisterBuiltin("ERR$", 0, errStrBuiltin)
+
 	// Output
 	t.RegisterBuiltin("PRINT", -1, builtin.PRINT)
 	t.RegisterBuiltin("DUMP", 1, builtin.DUMP)
@@ -499,6 +657,8 @@ func (e *Interpreter) factor() object.Object {
 		case token.STRING:
 			e.offset++
 			return &object.StringObject{Value: tok.Literal}
+		case token.ILLEGAL:
+			return object.CodedError(object.ErrSyntax, "Malformed number %s", tok.Literal)
 		case token.FN:
 
 			//
@@ -565,9 +725,19 @@ func (e *Interpreter) factor() object.Object {
 		case token.IDENT:
 
 			//
-			// Look for indexed variables
+			// Look for sliced strings, if the dialect has them.
 			//
 			e.offset++
+			if e.dialect.Slicing && e.offset < len(e.program) && e.program[e.offset].Type == token.LBRACKET {
+				val := e.GetVariable(tok.Literal)
+				if val.Type() == object.STRING {
+					return e.slice(val.(*object.StringObject).Value)
+				}
+			}
+
+			//
+			// Look for indexed variables
+			//
 			index, ee := e.findIndex()
 
 			if ee != nil {
@@ -734,7 +904,7 @@ func (e *Interpreter) term() object.Object {
 			}
 			if tok.Type == token.SLASH {
 				if v2 == 0 {
-					return object.Error("Division by zero")
+					return object.CodedError(object.ErrDivisionByZero, "Division by zero")
 				}
 				f1 = &object.NumberObject{Value: v1 / v2}
 			}
@@ -744,7 +914,7 @@ func (e *Interpreter) term() object.Object {
 				d2 := int(v2)
 
 				if d2 == 0 {
-					return object.Error("MOD 0 is an error")
+					return object.CodedError(object.ErrDivisionByZero, "MOD 0 is an error")
 				}
 				f1 = &object.NumberObject{Value: float64(d1 % d2)}
 			}
@@ -762,7 +932,7 @@ func (e *Interpreter) term() object.Object {
 		// were invalid, so report that.
 		//
 		if !handled {
-			return object.Error("term() only handles string-multiplication and integer-operations")
+			return object.CodedError(object.ErrTypeMismatch, "term() only handles string-multiplication and integer-operations")
 		}
 
 		// repeat?
@@ -846,7 +1016,7 @@ func (e *Interpreter) expr(allowBinOp bool) object.Object {
 		// do not match.  If we hit this it's a bug.
 		//
 		if t1.Type() != t2.Type() {
-			return object.Error("expr() - type mismatch between '%v' + '%v'", t1, t2)
+			return object.CodedError(object.ErrTypeMismatch, "expr() - type mismatch between '%v' + '%v'", t1, t2)
 		}
 
 		//
@@ -858,7 +1028,7 @@ func (e *Interpreter) expr(allowBinOp bool) object.Object {
 		//
 		if t1.Type() != object.STRING &&
 			t1.Type() != object.NUMBER {
-			return object.Error("expr() - we don't support operations on non-number/non-string types '%v' + '%v'", t1, t2)
+			return object.CodedError(object.ErrTypeMismatch, "expr() - we don't support operations on non-number/non-string types '%v' + '%v'", t1, t2)
 		}
 
 		//
@@ -878,7 +1048,7 @@ func (e *Interpreter) expr(allowBinOp bool) object.Object {
 			if tok.Type == token.PLUS {
 				t1 = &object.StringObject{Value: s1 + s2}
 			} else {
-				return object.Error("expr() operation '%s' not supported for strings", tok.Literal)
+				return object.CodedError(object.ErrTypeMismatch, "expr() operation '%s' not supported for strings", tok.Literal)
 			}
 		} else {
 
@@ -925,17 +1095,19 @@ func (e *Interpreter) compare(allowBinOp bool) object.Object {
 		return t1
 	}
 
-	if e.offset >= len(e.program) {
-		return t1
+	// If the next token is THEN, or the end of the statement, then
+	// we're going to regard the test as a pass if the first value
+	// was not 0 (number) and not "" (string).
+	//
+	// The statement may end with a comma in "ASSERT ok, message".
+	end := e.offset >= len(e.program)
+	if !end {
+		switch e.program[e.offset].Type {
+		case token.THEN, token.NEWLINE, token.COLON, token.COMMA:
+			end = true
+		}
 	}
-
-	// Get the comparison function
-	op := e.program[e.offset]
-
-	// If the next token is an THEN then we're going
-	// to regard the test as a pass if the first
-	// value was not 0 (number) and not "" (string)
-	if op.Type == token.THEN {
+	if end {
 
 		switch t1.Type() {
 		case object.STRING:
@@ -950,6 +1122,9 @@ func (e *Interpreter) compare(allowBinOp bool) object.Object {
 		return &object.NumberObject{Value: 0}
 	}
 
+	// Get the comparison function
+	op := e.program[e.offset]
+
 	//
 	// OK bump past the comparison function.
 	//
@@ -1161,41 +1336,32 @@ func (e *Interpreter) parseDefFN(offset int) error {
 	}
 
 	//
-	// Build up the body of the function.
+	// The body of the function is the rest of the line.
 	//
-	body := ""
-	for offset < len(e.program) {
-		tok := e.program[offset]
-		if tok.Type == token.NEWLINE {
-			break
-		}
-
-		body += " "
-
-		// Quote strings.  Sigh
-		if tok.Type == token.STRING {
-			body += "\""
-			body += tok.Literal
-			body += "\""
-		} else {
-			body += tok.Literal
-		}
+	start := offset
+	for offset < len(e.program) && e.program[offset].Type != token.NEWLINE {
 		offset++
 	}
 
 	//
 	// Empty body?
 	//
-	if body == "" {
+	if offset == start {
 		return fmt.Errorf("hit end of program processing DEF FN")
 	}
 
+	//
+	// We store the body as text, which we'll tokenize again when
+	// the function is called.
+	//
+	body := token.Render(e.program[start:offset])
+
 	//
 	// Store the definition in our map.
 	//
 	// This will let it be called, by name.
 	//
-	e.fns[name.Literal] = userFunction{name: name.Literal, body: body, args: args}
+	e.fns[name.Literal] = &userFunction{name: name.Literal, body: body, args: args}
 	return nil
 }
 
@@ -1214,8 +1380,8 @@ func (e *Interpreter) callUserFunction(name string, args []object.Object) object
 	// it, obviously!
 	//
 	fun := e.fns[name]
-	if fun.name == "" {
-		return object.Error("User-defined function %s doesn't exist", name)
+	if fun == nil {
+		return object.CodedError(object.ErrUndefinedFunction, "User-defined function %s doesn't exist", name)
 	}
 
 	//
@@ -1225,30 +1391,56 @@ func (e *Interpreter) callUserFunction(name string, args []object.Object) object
 		return object.Error("Argument count mis-match")
 	}
 
+	//
+	// An expression can't choose not to call a function, so one which
+	// calls itself, directly or via others, would never return.
+	//
+	if fun.active {
+		return object.Error("User-defined function %s can't call itself", name)
+	}
+
 	//
 	// OK we're essentially having to implement an `eval` function
 	// to process the body of the user-defined function.
 	//
 	// That means we need to create a temporary tokenizer to
-	// read the body, then evaluate on that second copy.
+	// read the body, then evaluate on that second copy, which
+	// we keep for the next call.
 	//
-	// Create the tokenizer, and the evaluator which use it.
+	if fun.eval == nil {
+		child, err := e.child(fun)
+		if err != nil {
+			return object.Error(err.Error())
+		}
+		fun.eval = child
+	}
+	eval := fun.eval
+	eval.offset = 0
+
 	//
-	// Note without this trailing newline we hit an error:
-	//   	Hit end of program processing term()
+	// The child shares our clock and our context, so that a SLEEP
+	// inside a function can still be interrupted.
 	//
-	// TODO: Fix this, it is obviously a BUG.
+	eval.clock = e.clock
+	eval.context = e.context
+
 	//
-	tokenizer := tokenizer.New(fun.body + "\n")
-	eval, err := New(tokenizer)
-	if err != nil {
-		return object.Error(err.Error())
-	}
+	// Any built-ins it calls are counted against our line.
+	//
+	eval.profile = e.profile
 
 	//
-	// The new instance won't have any variables setup, but that's
-	// OK.  The expression will only refer to the arguments it was
-	// given by name.
+	// It also shares our host, and our input and output.
+	//
+	eval.host = e.host
+	eval.STDIN = e.STDIN
+	eval.STDOUT = e.STDOUT
+	eval.STDERR = e.STDERR
+
+	//
+	// The child has no variables other than the arguments, which
+	// are set afresh for each call, but that's OK.  The expression
+	// will only refer to the arguments it was given by name.
 	//
 	// Populate the variables in the environment of our (child) evaluater.
 	//
@@ -1263,7 +1455,9 @@ func (e *Interpreter) callUserFunction(name string, args []object.Object) object
 	// Now we can evaluate the expression in the context of this
 	// child-evaluator.
 	//
+	fun.active = true
 	out := eval.expr(true)
+	fun.active = false
 	if e.trace {
 		fmt.Printf("\tCalled expr() - result is\n\t%s\n", out.String())
 	}
@@ -1272,6 +1466,36 @@ func (e *Interpreter) callUserFunction(name string, args []object.Object) object
 	return (out)
 }
 
+// child creates the interpreter which evaluates the body of the given
+// user-defined function.
+func (e *Interpreter) child(fun *userFunction) (*Interpreter, error) {
+
+	//
+	// Note without this trailing newline we hit an error:
+	//   	Hit end of program processing term()
+	//
+	// TODO: Fix this, it is obviously a BUG.
+	//
+	tokenizer := tokenizer.New(fun.body + "\n")
+	eval, err := NewWithDialect(tokenizer, e.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	//
+	// The body may call the same functions we can, including those
+	// registered by the host, and other user-defined functions.
+	//
+	eval.functions = e.functions
+	eval.fns = e.fns
+	for i, tok := range eval.program {
+		if _, fun := e.functions.Get(tok.Literal); tok.Type == token.IDENT && fun != nil {
+			eval.program[i].Type = token.BUILTIN
+		}
+	}
+	return eval, nil
+}
+
 // Call the built-in with the given name if we can.
 func (e *Interpreter) callBuiltin(name string) object.Object {
 
@@ -1284,6 +1508,9 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 	// it should expect.
 	//
 	n, fun := e.functions.Get(name)
+	if e.profile != nil {
+		e.profile.called(name)
+	}
 
 	//
 	// skip past the function-call itself
@@ -1294,6 +1521,26 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 		return object.Error("Hit end of program processing builtin %s", name)
 	}
 
+	//
+	// Some dialects call built-ins with their arguments in brackets,
+	// so "LEN(a$) + 1" adds one to the length.
+	//
+	// "RND(1)" is allowed even if RND takes no arguments.
+	//
+	if e.dialect.Parens && n != -1 && e.program[e.offset].Type == token.LBRACKET {
+		args, err := e.arguments(name)
+		if err != nil {
+			return err
+		}
+		if n == 0 && len(args) == 1 {
+			args = nil
+		}
+		if len(args) != n {
+			return object.Error("%s expects %d argument(s), but received %d", name, n, len(args))
+		}
+		return fun(e, args)
+	}
+
 	//
 	// Each built-in takes a specific number of arguments.
 	//
@@ -1301,6 +1548,13 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 	//
 	var args []object.Object
 
+	//
+	// isPrint is true if we're handling PRINT, and trailing is true if
+	// the last thing it was given was a separator.
+	//
+	isPrint := name == "PRINT" || name == "print"
+	trailing := false
+
 	//
 	// Build up the args, converting and evaluating as we go.
 	//
@@ -1319,10 +1573,11 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 			//
 			// Hack
 			//
-			if name == "PRINT" || name == "print" {
+			if isPrint && !(e.dialect.PrintNewline && tok.Type == token.SEMICOLON) {
 
 				args = append(args, &object.StringObject{Value: " "})
 			}
+			trailing = true
 			e.offset++
 			continue
 		}
@@ -1356,6 +1611,17 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 			break
 		}
 
+		//
+		// The words ON and OFF may be given to a built-in, as in
+		// "CURSOR OFF", and are received as one and zero.
+		//
+		if sw, ok := e.onOff(); ok {
+			e.offset++
+			args = append(args, sw)
+			trailing = false
+			continue
+		}
+
 		//
 		// Evaluate the next expression.
 		//
@@ -1372,6 +1638,7 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 		// Append the argument to our list.
 		//
 		args = append(args, obj)
+		trailing = false
 
 		//
 		// Show our current progress.
@@ -1381,6 +1648,14 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 		}
 	}
 
+	//
+	// Some dialects end the output of PRINT with a newline, unless
+	// it ends with a separator.
+	//
+	if isPrint && e.dialect.PrintNewline && !trailing {
+		args = append(args, &object.StringObject{Value: "\n"})
+	}
+
 	//
 	// Actually call the function, now we have the correct number
 	// of arguments to do so.
@@ -1393,6 +1668,27 @@ func (e *Interpreter) callBuiltin(name string) object.Object {
 	return out
 }
 
+// onOff returns the value of the word ON, or OFF, if the current token is
+// either, and ends an argument to a built-in.
+func (e *Interpreter) onOff() (object.Object, bool) {
+	tok := e.program[e.offset]
+	if e.offset+1 < len(e.program) {
+		switch e.program[e.offset+1].Type {
+		case token.NEWLINE, token.COLON, token.COMMA, token.EOF:
+		default:
+			return nil, false
+		}
+	}
+
+	switch {
+	case tok.Type == token.ON:
+		return &object.NumberObject{Value: 1}, true
+	case tok.Type == token.IDENT && strings.EqualFold(tok.Literal, "OFF"):
+		return &object.NumberObject{Value: 0}, true
+	}
+	return nil, false
+}
+
 ////
 //
 // Statement-handlers
@@ -1502,7 +1798,11 @@ func (e *Interpreter) runDIM() error {
 	//
 	// Now we have either two dimensions, or one
 	//
+	// The arrays we create are indexed from zero, so if the dialect
+	// indexes them from one we need one element fewer.
+	//
 	var x object.Object
+	base := float64(e.dialect.Base)
 
 	if sec.Type == token.INT {
 
@@ -1517,7 +1817,10 @@ func (e *Interpreter) runDIM() error {
 			return (fmt.Errorf("dimension too large! %f > 1024", b))
 		}
 
-		x = object.Array(int(a), int(b))
+		if a < base || b < base {
+			return fmt.Errorf("dimensions must be at least %d", e.dialect.Base)
+		}
+		x = object.Array(int(a-base), int(b-base))
 	} else {
 
 		// 1D array
@@ -1526,7 +1829,10 @@ func (e *Interpreter) runDIM() error {
 			return (fmt.Errorf("dimension too large! %f > 1024", a))
 		}
 
-		x = object.Array(0, int(a))
+		if a < base {
+			return fmt.Errorf("dimensions must be at least %d", e.dialect.Base)
+		}
+		x = object.Array(0, int(a-base))
 	}
 
 	// Store the array in the environment
@@ -1724,22 +2030,80 @@ func (e *Interpreter) runForLoop() error {
 	return nil
 }
 
+// lineTarget reads the target of a GOTO/GOSUB statement, and returns
+// the offset in our program at which that line begins.
+//
+// The target is usually a literal line-number, but it may also be an
+// expression which is evaluated at run-time:
+//
+//	GOTO 100
+//	GOTO 100 + choice * 10
+func (e *Interpreter) lineTarget(name string) (int, error) {
+
+	if e.offset >= len(e.program) {
+		return 0, fmt.Errorf("hit end of program processing %s", name)
+	}
+
+	//
+	// Is the target the name of a label?
+	//
+	tok := e.program[e.offset]
+	if tok.Type == token.IDENT {
+		if _, ok := e.lines[tok.Literal]; ok {
+			e.offset++
+			return e.lineOffset(name, tok.Literal)
+		}
+	}
+
+	//
+	// Evaluate the target, which will leave us pointing at the
+	// token after it.
+	//
+	target := e.expr(true)
+	if target.Type() == object.ERROR {
+		return 0, toError(target.(*object.ErrorObject))
+	}
+
+	// We expect the target to be a number
+	if target.Type() != object.NUMBER {
+		return 0, fmt.Errorf("ERROR: %s should be followed by an integer", name)
+	}
+
+	//
+	// Line-numbers are stored in our lookup-table as strings,
+	// exactly as they were written in the source.
+	//
+	id := strconv.FormatFloat(target.(*object.NumberObject).Value, 'f', -1, 64)
+
+	return e.lineOffset(name, id)
+}
+
+// lineOffset returns the offset in our program at which the given
+// line-number, or label, begins - or an error if there is no such line.
+func (e *Interpreter) lineOffset(name string, id string) (int, error) {
+	offset, ok := e.lines[id]
+	if !ok {
+		kind := "Line"
+		if _, err := strconv.ParseFloat(id, 64); err != nil {
+			kind = "Label"
+		}
+		return 0, errorf(object.ErrUndefinedLine, "%s: %s %s does not exist", name, kind, id)
+	}
+	return offset, nil
+}
+
 // runGOSUB handles a control-flow change
 func (e *Interpreter) runGOSUB() error {
 
 	// Skip the GOSUB-instruction itself
 	e.offset++
 
-	if e.offset >= len(e.program) {
-		return fmt.Errorf("hit end of program processing GOSUB")
-	}
-
-	// Get the target
-	target := e.program[e.offset]
-
-	// We expect the target to be an int
-	if target.Type != token.INT {
-		return (fmt.Errorf("ERROR: GOSUB should be followed by an integer"))
+	//
+	// Lookup the offset of the target line-number in our program.
+	//
+	offset, err := e.lineTarget("GOSUB")
+	if err != nil {
+		return err
 	}
 
 	//
@@ -1747,67 +2111,133 @@ func (e *Interpreter) runGOSUB() error {
 	// so that the next RETURN will continue execution at the
 	// next instruction.
 	//
-	// Because we only support one statement per-line we can
-	// handle that by bumping forward.  That should put us on the
-	// LINENO of the following-line.
+	// Finding the target has moved us past it, so we're already
+	// at the right place.
 	//
-	e.offset++
 	e.gstack.Push(e.offset)
 
 	//
-	// Lookup the offset of the given line-number in our program.
+	// Change to executing at the target.
 	//
-	offset, ok := e.lines[target.Literal]
+	e.offset = offset
+	return nil
+}
+
+// runGOTO handles a control-flow change
+func (e *Interpreter) runGOTO() error {
+
+	// Skip the GOTO-instruction
+	e.offset++
 
 	//
-	// If we found it then change to executing there
+	// Lookup the offset of the target line-number in our program.
 	//
-	if ok {
-		e.offset = offset
-		return nil
+	offset, err := e.lineTarget("GOTO")
+	if err != nil {
+		return err
 	}
 
 	//
-	// Otherwise we have an error.
+	// Change to executing there.
 	//
-	return fmt.Errorf("GOSUB: Line %s does not exist", target.Literal)
+	e.offset = offset
+	return nil
 }
 
-// runGOTO handles a control-flow change
-func (e *Interpreter) runGOTO() error {
+// runON handles a computed jump, which takes one of these forms:
+//
+//	ON expr GOTO line1, line2, line3 ..
+//	ON expr GOSUB line1, line2, line3 ..
+//
+// The expression selects the line to jump to, counting from one.  If the
+// value is out of range then no jump is made, and execution continues
+// with the next statement.
+func (e *Interpreter) runON() error {
 
-	// Skip the GOTO-instruction
+	// Skip the ON-instruction
 	e.offset++
 
 	if e.offset >= len(e.program) {
-		return fmt.Errorf("hit end of program processing GOTO")
+		return fmt.Errorf("hit end of program processing ON")
 	}
 
-	// Get the GOTO-target
-	target := e.program[e.offset]
+	//
+	// Installing an error-handler is handled separately.
+	//
+	if e.program[e.offset].Type == token.ERROR {
+		return e.runOnError()
+	}
 
-	// We expect the target to be an int
-	if target.Type != token.INT {
-		return fmt.Errorf("ERROR: GOTO should be followed by an integer")
+	//
+	// Evaluate the selector.
+	//
+	val := e.expr(true)
+	if val.Type() == object.ERROR {
+		return toError(val.(*object.ErrorObject))
+	}
+	if val.Type() != object.NUMBER {
+		return fmt.Errorf("ON expects a numeric expression, got %s", val.String())
+	}
+	choice := int(val.(*object.NumberObject).Value)
+
+	if e.offset >= len(e.program) {
+		return fmt.Errorf("hit end of program processing ON")
 	}
 
 	//
-	// Lookup the offset of the given line-number in our program.
+	// Now we need either GOTO or GOSUB.
 	//
-	offset, ok := e.lines[target.Literal]
+	kind := e.program[e.offset]
+	if kind.Type != token.GOTO && kind.Type != token.GOSUB {
+		return fmt.Errorf("expected GOTO or GOSUB after ON, got %v", kind)
+	}
+	name := string(kind.Type)
+	e.offset++
 
 	//
-	// If we found it then change to executing there
+	// Collect the comma-separated list of line-numbers.
 	//
-	if ok {
-		e.offset = offset
+	var targets []string
+	for e.offset < len(e.program) {
+		tok := e.program[e.offset]
+		if tok.Type == token.COMMA {
+			e.offset++
+			continue
+		}
+		if tok.Type != token.INT && tok.Type != token.IDENT {
+			break
+		}
+		targets = append(targets, tok.Literal)
+		e.offset++
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("ON .. %s should be followed by a list of line-numbers or labels", name)
+	}
+
+	//
+	// Out of range?  Then we fall through to the next statement.
+	//
+	if choice < 1 || choice > len(targets) {
 		return nil
 	}
 
+	offset, err := e.lineOffset(name, targets[choice-1])
+	if err != nil {
+		return err
+	}
+
 	//
-	// Otherwise we have an error.
+	// For GOSUB we store the return address, which is the token
+	// after the list of targets.
 	//
-	return fmt.Errorf("GOTO: Line %s does not exist", target.Literal)
+	if kind.Type == token.GOSUB {
+		e.gstack.Push(e.offset)
+	}
+
+	e.offset = offset
+	e.jump = true
+	return nil
 }
 
 // runINPUT handles input of numbers from the user.
@@ -1901,24 +2331,15 @@ func (e *Interpreter) runINPUT() error {
 	return nil
 }
 
-// runIF handles conditional testing.
-//
-// There are a lot of choices to be made when it comes to IF, such as
-// whether to support an ELSE section or not.  And what to allow
-// inside the matching section generally:
-//
-// A single statement?
-// A block?
+// condition evaluates the condition of an IF, or ASSERT, statement.
 //
-// Here we _only_ allow:
+// The general form is a single comparison, however we also want to
+// allow people to write:
 //
-//	IF $EXPR THEN $STATEMENT ELSE $STATEMENT NEWLINE
+//	IF A=3 OR A=4 THEN ..
 //
-// $STATEMENT will only be a single expression
-func (e *Interpreter) runIF() error {
-
-	// Bump past the IF token
-	e.offset++
+// So we'll combine any further comparisons joined by AND, OR, or XOR.
+func (e *Interpreter) condition() (bool, error) {
 
 	// Get the result of the comparison-function
 	// against the two arguments.
@@ -1926,7 +2347,7 @@ func (e *Interpreter) runIF() error {
 
 	// Error?
 	if res.Type() == object.ERROR {
-		return fmt.Errorf("%s", res.(*object.ErrorObject).Value)
+		return false, toError(res.(*object.ErrorObject))
 	}
 
 	//
@@ -1937,27 +2358,13 @@ func (e *Interpreter) runIF() error {
 		result = (res.(*object.NumberObject).Value == 1)
 	}
 
-	//
-	// The general form of an IF statement is
-	//  IF $COMPARE THEN .. ELSE .. NEWLINE
-	//
-	// However we also want to allow people to write:
-	//
-	//  IF A=3 OR A=4 THEN ..
-	//
-	// So we'll special case things here.
-	//
-	if e.offset >= len(e.program) {
-		return fmt.Errorf("end of program processing IF")
-	}
-
-	// We now expect THEN most of the time
-	target := e.program[e.offset]
-	e.offset++
+	for e.offset < len(e.program) {
 
-	for target.Type == token.AND ||
-		target.Type == token.OR ||
-		target.Type == token.XOR {
+		op := e.program[e.offset].Type
+		if op != token.AND && op != token.OR && op != token.XOR {
+			break
+		}
+		e.offset++
 
 		//
 		// See what the next comparison looks like.
@@ -1965,7 +2372,7 @@ func (e *Interpreter) runIF() error {
 		extra := e.compare(false)
 
 		if extra.Type() == object.ERROR {
-			return fmt.Errorf("%s", extra.(*object.ErrorObject).Value)
+			return false, toError(extra.(*object.ErrorObject))
 		}
 
 		//
@@ -1979,29 +2386,61 @@ func (e *Interpreter) runIF() error {
 		//
 		// Update our result appropriately.
 		//
-		if target.Type == token.AND {
+		if op == token.AND {
 			result = result && extraResult
 		}
-		if target.Type == token.OR {
+		if op == token.OR {
 			result = result || extraResult
 		}
-		if target.Type == token.XOR {
+		if op == token.XOR {
 			// true + false -> true
 			// false + true -> true
 			// false + false -> false
 			// true + true -> false
 			result = (result != extraResult)
 		}
+	}
 
-		// Repeat?
-		if e.offset >= len(e.program) {
-			return fmt.Errorf("end of program processing IF")
-		}
+	return result, nil
+}
 
-		target = e.program[e.offset]
-		e.offset++
+// runIF handles conditional testing.
+//
+// There are a lot of choices to be made when it comes to IF, such as
+// whether to support an ELSE section or not.  And what to allow
+// inside the matching section generally:
+//
+// A single statement?
+// A block?
+//
+// Here we _only_ allow:
+//
+//	IF $EXPR THEN $STATEMENT ELSE $STATEMENT NEWLINE
+//
+// $STATEMENT will only be a single expression
+func (e *Interpreter) runIF() error {
+
+	// Remember where the statement is, for coverage.
+	start := e.offset
+
+	// Bump past the IF token
+	e.offset++
+
+	// Get the result of the condition, which may combine several
+	// comparisons with AND, OR, or XOR.
+	result, err := e.condition()
+	if err != nil {
+		return err
+	}
+
+	if e.offset >= len(e.program) {
+		return fmt.Errorf("end of program processing IF")
 	}
 
+	// We now expect THEN
+	target := e.program[e.offset]
+	e.offset++
+
 	//
 	// Now we're in the THEN section.
 	//
@@ -2009,6 +2448,13 @@ func (e *Interpreter) runIF() error {
 		return fmt.Errorf("expected THEN after IF EXPR, got %v", target)
 	}
 
+	//
+	// Record which way we went, if we're measuring coverage.
+	//
+	if e.coverage != nil {
+		e.coverage.branch(start, result)
+	}
+
 	//
 	// OK so if our comparison succeeded we can execute the single
 	// statement between THEN + ELSE
@@ -2020,7 +2466,10 @@ func (e *Interpreter) runIF() error {
 		//
 		// Execute single statement
 		//
-		e.RunOnce()
+		err := e.RunOnce()
+		if err != nil {
+			return err
+		}
 
 		//
 		// Help me, I'm in Hell.
@@ -2070,8 +2519,13 @@ func (e *Interpreter) runIF() error {
 		tmp := e.program[e.offset]
 		e.offset++
 
-		// If we hit the newline then we're done
+		// If we hit the newline then we're done.
+		//
+		// We step back, because our caller will bump the offset
+		// past the newline - otherwise we'd skip the first token
+		// of the next line.
 		if tmp.Type == token.NEWLINE || tmp.Type == token.EOF {
+			e.offset--
 			run = false
 			continue
 		}
@@ -2080,7 +2534,10 @@ func (e *Interpreter) runIF() error {
 		if tmp.Type == token.ELSE {
 
 			// Execute the single statement
-			e.RunOnce()
+			err := e.RunOnce()
+			if err != nil {
+				return err
+			}
 
 			// Then terminate.
 			run = false
@@ -2158,7 +2615,7 @@ func (e *Interpreter) runLET(skipLet bool) error {
 
 	// Did we get an error in the expression?
 	if res.Type() == object.ERROR {
-		return fmt.Errorf("%s", res.(*object.ErrorObject).Value)
+		return toError(res.(*object.ErrorObject))
 	}
 
 	// Are we handling an array-index?
@@ -2200,7 +2657,7 @@ func (e *Interpreter) runNEXT() error {
 	//
 	data := e.loops.Get(target.Literal)
 	if data.id == "" {
-		return fmt.Errorf("NEXT %s found - without opening FOR", target.Literal)
+		return errorf(object.ErrNextWithoutFor, "NEXT %s found - without opening FOR", target.Literal)
 	}
 
 	//
@@ -2289,11 +2746,23 @@ func (e *Interpreter) runNEXT() error {
 	return nil
 }
 
+// endOfStatement returns true if the token at the given offset, within
+// the supplied tokens, marks the end of a statement.
+func endOfStatement(tokens []token.Token, offset int) bool {
+	if offset >= len(tokens) {
+		return true
+	}
+	switch tokens[offset].Type {
+	case token.NEWLINE, token.COLON, token.ELSE, token.EOF:
+		return true
+	}
+	return false
+}
+
 // Swallow all input until the following newline / EOF.
 //
 // This is used by:
 //
-//	REM
 //	DATA
 //	DEF FN
 func (e *Interpreter) swallowLine() error {
@@ -2369,7 +2838,7 @@ func (e *Interpreter) runREAD() error {
 		// not read too much.
 		//
 		if e.dataOffset >= len(e.data) {
-			return fmt.Errorf("read past the end of our DATA storage - length %d", len(e.data))
+			return errorf(object.ErrOutOfData, "read past the end of our DATA storage - length %d", len(e.data))
 		}
 
 		//
@@ -2541,7 +3010,7 @@ func (e *Interpreter) runRETURN() error {
 
 	// Stack can't be empty
 	if e.gstack.Empty() {
-		return fmt.Errorf("RETURN without GOSUB")
+		return errorf(object.ErrReturnWithoutGosub, "RETURN without GOSUB")
 	}
 
 	// Get the return address
@@ -2575,6 +3044,24 @@ func (e *Interpreter) RunOnce() error {
 		fmt.Printf("RunOnce( %s )\n", tok.String())
 	}
 
+	//
+	// Record the statement, if we're measuring coverage.
+	//
+	if e.coverage != nil {
+		e.coverage.executed(e.offset, tok)
+	}
+
+	//
+	// Record the statement, if we're profiling.
+	//
+	if e.profile != nil {
+		switch tok.Type {
+		case token.NEWLINE, token.COLON, token.LINENO, token.LABEL, token.EOF:
+		default:
+			defer e.beginProfile(tok)()
+		}
+	}
+
 	e.jump = false
 
 	//
@@ -2584,10 +3071,15 @@ func (e *Interpreter) RunOnce() error {
 	//
 	// Logical
 	//
-	case token.NEWLINE:
+	case token.NEWLINE, token.COLON:
 		// NOP
 	case token.LINENO:
 		e.lineno = tok.Literal
+	case token.LABEL:
+		// Skip the ":" which follows the name, if present.
+		if e.offset+1 < len(e.program) && e.program[e.offset+1].Type == token.COLON {
+			e.offset++
+		}
 
 	//
 	// Actual
@@ -2596,10 +3088,12 @@ func (e *Interpreter) RunOnce() error {
 		obj := e.callBuiltin(tok.Literal)
 
 		if obj.Type() == object.ERROR {
-			return fmt.Errorf("%s", obj.(*object.ErrorObject).Value)
+			return toError(obj.(*object.ErrorObject))
 		}
 
 		e.offset--
+	case token.ASSERT:
+		err = e.runASSERT()
 	case token.DEF:
 		err = e.swallowLine()
 	case token.DIM:
@@ -2607,6 +3101,8 @@ func (e *Interpreter) RunOnce() error {
 	case token.END:
 		e.finished = true
 		return nil
+	case token.ERROR:
+		err = e.runERROR()
 	case token.DATA:
 		err = e.swallowLine()
 	case token.FOR:
@@ -2625,8 +3121,11 @@ func (e *Interpreter) RunOnce() error {
 		err = e.runLET(true)
 	case token.NEXT:
 		err = e.runNEXT()
-	case token.REM:
-		err = e.swallowLine()
+	case token.ON:
+		err = e.runON()
+	case token.RESUME:
+		err = e.runRESUME()
+		e.jump = true
 	case token.RETURN:
 		err = e.runRETURN()
 	case token.READ:
@@ -2636,8 +3135,11 @@ func (e *Interpreter) RunOnce() error {
 	case token.IDENT:
 		//
 		// If we receive an ident then we assume it is a LET-less
-		// assignment.
+		// assignment, unless the dialect requires LET.
 		//
+		if e.dialect.RequireLet {
+			return fmt.Errorf("expected a statement, got %s (assignments require LET)", tok.Literal)
+		}
 		err = e.runLET(false)
 	default:
 		//
@@ -2654,7 +3156,7 @@ func (e *Interpreter) RunOnce() error {
 		//
 		result := e.expr(true)
 		if result.Type() == object.ERROR {
-			return fmt.Errorf("%s", result.String())
+			return errorf(result.(*object.ErrorObject).Code, "%s", result.String())
 		}
 	}
 
@@ -2692,15 +3194,41 @@ func (e *Interpreter) Run() error {
 		//
 		select {
 		case <-e.context.Done():
-			return fmt.Errorf("timeout during execution")
+			return ErrTimeout
 		default:
 			// nop
 		}
 
+		//
+		// Count the statements, ignoring the tokens which merely
+		// separate them.
+		//
+		switch e.program[e.offset].Type {
+		case token.NEWLINE, token.COLON, token.LINENO, token.LABEL, token.EOF:
+		default:
+			e.steps++
+			if e.stepLimit > 0 && e.steps > e.stepLimit {
+				return fmt.Errorf("%w, after %d statements", ErrStepLimit, e.stepLimit)
+			}
+		}
+
+		start := e.offset
 		err := e.RunOnce()
 
 		if err != nil {
-			return fmt.Errorf("line %s : %s", e.lineno, err.Error())
+
+			//
+			// If there is an error-handler we'll jump to it,
+			// otherwise the error is fatal.
+			//
+			if e.trap(start, err) {
+				continue
+			}
+			return &LineError{
+				Line:  e.program[start].Line,
+				Label: e.lineAt(start),
+				Err:   err,
+			}
 		}
 	}
 
@@ -2715,13 +3243,22 @@ func (e *Interpreter) Run() error {
 	return nil
 }
 
-// findIndex looks for any indexes following a variable reference.
-// Given a set of tokens it will return :
+// findIndex looks for any indexes following a variable reference,
+// which are written as "a[1, 2]", or "a(1, 2)" if the dialect uses
+// brackets.
+//
+// The indexes are returned as written, and may be expressions.
 func (e *Interpreter) findIndex() ([]int, error) {
 
 	// return values
 	var indexes []int
-	run := true
+
+	open := token.Type(token.LINDEX)
+	close := token.Type(token.RINDEX)
+	if e.dialect.Parens {
+		open = token.LBRACKET
+		close = token.RBRACKET
+	}
 
 	// if the next token is after the end of the program we're done
 	if e.offset+1 >= len(e.program) {
@@ -2730,7 +3267,7 @@ func (e *Interpreter) findIndex() ([]int, error) {
 
 	// If the next token is not "[" we're not looking at an indexed
 	// expression at all, so we can terminate.
-	if e.program[e.offset].Type != token.LINDEX {
+	if e.program[e.offset].Type != open {
 		return indexes, nil
 	}
 
@@ -2738,38 +3275,28 @@ func (e *Interpreter) findIndex() ([]int, error) {
 	e.offset++
 
 	// Now collect indexes..
-	for e.offset < len(e.program) && run {
+	for e.offset < len(e.program) {
 
 		// if we find "]" we've finished
-		if e.program[e.offset].Type == token.RINDEX {
+		if e.program[e.offset].Type == close {
 			e.offset++
-			run = false
-		} else {
-
-			if e.program[e.offset].Type == token.INT {
-
-				a, _ := strconv.ParseFloat(e.program[e.offset].Literal, 64)
-
-				indexes = append(indexes, int(a))
-			} else if e.program[e.offset].Type == token.IDENT {
-
-				x := e.GetVariable(e.program[e.offset].Literal)
-				if x.Type() == object.NUMBER {
-					indexes = append(indexes, int(x.(*object.NumberObject).Value))
-				} else {
-					return indexes, fmt.Errorf("array indexes must be numbers")
-				}
-			} else {
-
-				// if we've not got a number and not got a comm
-				// then that's an error.
-				if e.program[e.offset].Type != token.COMMA {
+			return indexes, nil
+		}
 
-					return indexes, fmt.Errorf("unexpected value found when looking for index: %s", e.program[e.offset].String())
-				}
-			}
+		// Skip the commas between indexes.
+		if e.program[e.offset].Type == token.COMMA && len(indexes) > 0 {
 			e.offset++
+			continue
+		}
+
+		x := e.expr(true)
+		if x.Type() == object.ERROR {
+			return indexes, fmt.Errorf("unexpected value found when looking for index: %s", x.(*object.ErrorObject).Value)
 		}
+		if x.Type() != object.NUMBER {
+			return indexes, fmt.Errorf("array indexes must be numbers")
+		}
+		indexes = append(indexes, int(x.(*object.NumberObject).Value))
 	}
 
 	return indexes, nil
@@ -2822,6 +3349,7 @@ func (e *Interpreter) SetArrayVariable(id string, index []int, val object.Object
 
 	// Otherwise assume we can index appropriately.
 	a := x.(*object.ArrayObject)
+	index = e.rebase(index)
 
 	// update the value
 	if len(index) == 1 {
@@ -2829,7 +3357,7 @@ func (e *Interpreter) SetArrayVariable(id string, index []int, val object.Object
 		// 1d array
 		res := a.Set(0, index[0], val)
 		if res.Type() == object.ERROR {
-			return fmt.Errorf("%s", res.(*object.ErrorObject).Value)
+			return toError(res.(*object.ErrorObject))
 		}
 	}
 	if len(index) == 2 {
@@ -2837,7 +3365,7 @@ func (e *Interpreter) SetArrayVariable(id string, index []int, val object.Object
 		// 2d array
 		res := a.Set(index[0], index[1], val)
 		if res.Type() == object.ERROR {
-			return fmt.Errorf("%s", res.(*object.ErrorObject).Value)
+			return toError(res.(*object.ErrorObject))
 		}
 	}
 
@@ -2856,6 +3384,13 @@ func (e *Interpreter) GetVariable(id string) object.Object {
 	return object.Error("The variable '%s' doesn't exist", id)
 }
 
+// Variables returns the contents of every variable, keyed by name.
+//
+// Useful for testing/embedding.
+func (e *Interpreter) Variables() map[string]object.Object {
+	return e.vars.All()
+}
+
 // GetArrayVariable gets the contents of the specified array value.
 //
 // Useful for testing/embedding
@@ -2875,6 +3410,7 @@ func (e *Interpreter) GetArrayVariable(id string, index []int) object.Object {
 	// Otherwise we assume we've got an array
 	// index.
 	a := x.(*object.ArrayObject)
+	index = e.rebase(index)
 
 	var ob object.Object
 	if len(index) == 1 {
@@ -2886,6 +3422,30 @@ func (e *Interpreter) GetArrayVariable(id string, index []int) object.Object {
 	return ob
 }
 
+// rebase converts array indexes, as written in the program, into
+// offsets from zero.
+func (e *Interpreter) rebase(index []int) []int {
+	out := make([]int, len(index))
+	for i, n := range index {
+		out[i] = n - e.dialect.Base
+	}
+	return out
+}
+
+// Dialect returns the dialect which the program is written in.
+func (e *Interpreter) Dialect() Dialect {
+	return e.dialect
+}
+
+// Builtins returns the names of the built-in functions which are
+// available, along with the number of arguments each requires.
+//
+// Each function is registered in both lower-case and upper-case, so
+// both forms are present.
+func (e *Interpreter) Builtins() map[string]int {
+	return e.functions.Names()
+}
+
 // RegisterBuiltin registers a function as a built-in, so that it can
 // be called from the users' BASIC program.
 //
@@ -2920,5 +3480,16 @@ func (e *Interpreter) RegisterBuiltin(name string, nArgs int, ft builtin.Signatu
 			// Change the type of the token.
 			e.program[i].Type = token.BUILTIN
 		}
+
+		// A line which begins "PRINT:" isn't defining a label
+		// called "PRINT", so undo that.
+		if e.program[i].Type == token.LABEL &&
+			i+1 < len(e.program) && e.program[i+1].Type == token.COLON &&
+			(e.program[i].Literal == lName ||
+				e.program[i].Literal == uName) {
+
+			e.program[i].Type = token.BUILTIN
+			delete(e.lines, e.program[i].Literal)
+		}
 	}
 }
@@ -4,9 +4,14 @@ package eval
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/skx/gobasic/builtin"
 	"github.com/skx/gobasic/object"
 	"github.com/skx/gobasic/token"
 	"github.com/skx/gobasic/tokenizer"
@@ -182,6 +187,30 @@ func TestDefFn(t *testing.T) {
 
 }
 
+// TestDefFnStrings tests that strings within the body of a function
+// survive, even if they contain quotes and newlines.
+func TestDefFnStrings(t *testing.T) {
+	input := `10 DEF FN greet(x) = "Say ""Hi"", " + x + "\n"
+20 LET a = FN greet("Steve")
+`
+	e, err := FromString(input)
+	if err != nil {
+		t.Fatalf("Error parsing %s - %s", input, err.Error())
+	}
+	err = e.Run()
+	if err != nil {
+		t.Fatalf("Unexpected error running %s - %s", input, err.Error())
+	}
+
+	a := e.GetVariable("a")
+	if a.Type() != object.STRING {
+		t.Fatalf("Variable had wrong type: %s", a.String())
+	}
+	if a.(*object.StringObject).Value != "Say \"Hi\", Steve\n" {
+		t.Errorf("Variable had the wrong value: %q", a.(*object.StringObject).Value)
+	}
+}
+
 // TestDim tests basic DIM functionality for single and 2dimensional
 // arrays
 func TestDim(t *testing.T) {
@@ -443,6 +472,137 @@ func TestEOF(t *testing.T) {
 	}
 }
 
+// TestErrorTrapping tests ON ERROR GOTO, RESUME, and the ERR/ERL/ERR$
+// primitives.
+func TestErrorTrapping(t *testing.T) {
+
+	type Test struct {
+		Input string
+		Var   string
+		Val   string
+	}
+
+	tests := []Test{
+
+		// RESUME NEXT continues with the following line.
+		{Input: `10 ON ERROR GOTO 100
+20 LET a = 1 / 0
+30 LET r = r + STR$ ERL
+40 END
+100 LET r = ERR$ + " @ "
+110 RESUME NEXT
+`, Var: "r", Val: "Division by zero @ 20"},
+
+		// RESUME NEXT continues with the following statement, which
+		// may be upon the same line.
+		{Input: `10 ON ERROR GOTO 100
+20 LET r = "a" : LET x = 1 / 0 : LET r = r + "c" : LET r = r + "d"
+30 END
+100 LET r = r + "b"
+110 RESUME NEXT
+`, Var: "r", Val: "abcd"},
+
+		// RESUME retries the statement which failed.
+		{Input: `10 ON ERROR GOTO 100
+20 LET d = 0
+30 LET r = 10 / d
+40 END
+100 LET d = 2
+110 RESUME
+`, Var: "r", Val: "5"},
+
+		// RESUME line continues at the given line.
+		{Input: `10 ON ERROR GOTO 100
+20 DIM a(3)
+30 LET a[10] = 1
+40 LET r = "wrong"
+50 END
+100 LET r = ERR$
+110 RESUME 50
+`, Var: "r", Val: "Set-Array access out of bounds (Y)"},
+
+		// ERROR raises a user-defined error.
+		{Input: `10 ON ERROR GOTO 100
+20 ERROR 42
+30 END
+100 LET r = ERR
+110 RESUME NEXT
+`, Var: "r", Val: "42"},
+
+		// Errors inside IF statements are trapped too.
+		{Input: `10 ON ERROR GOTO 100
+20 IF 1 = 1 THEN GOSUB 999
+30 END
+100 LET r = ERR
+110 RESUME NEXT
+`, Var: "r", Val: "8"},
+	}
+
+	for _, test := range tests {
+		e, err := FromString(test.Input)
+		if err != nil {
+			t.Errorf("Error parsing %s - %s", test.Input, err.Error())
+			continue
+		}
+		err = e.Run()
+		if err != nil {
+			t.Errorf("Unexpected error running %s - %s", test.Input, err.Error())
+			continue
+		}
+
+		cur := e.GetVariable(test.Var)
+		out := ""
+		switch cur.Type() {
+		case object.STRING:
+			out = cur.(*object.StringObject).Value
+		case object.NUMBER:
+			out = strconv.FormatFloat(cur.(*object.NumberObject).Value, 'f', -1, 64)
+		}
+		if out != test.Val {
+			t.Errorf("Expected %s to be %s, got %s", test.Var, test.Val, cur.String())
+		}
+	}
+
+	//
+	// Now some failures.
+	//
+	fails := []struct {
+		Input string
+		Error string
+	}{
+		{Input: "10 RESUME\n", Error: "RESUME without error"},
+		{Input: "10 ON ERROR\n", Error: "expected GOTO after ON ERROR"},
+		{Input: "10 ON ERROR GOTO\n", Error: "should be followed by an integer"},
+		{Input: "10 ON ERROR GOTO 100\n", Error: "Line 100 does not exist"},
+		{Input: "10 ERROR \"steve\"\n", Error: "numeric error-code"},
+
+		// An error inside the handler is fatal.
+		{Input: "10 ON ERROR GOTO 100\n20 ERROR 1\n100 ERROR 2\n", Error: "Error 2"},
+
+		// ON ERROR GOTO 0 removes the handler.
+		{Input: "10 ON ERROR GOTO 100\n20 ON ERROR GOTO 0\n30 ERROR 3\n100 END\n", Error: "Error 3"},
+
+		// RESUME to a missing line.
+		{Input: "10 ON ERROR GOTO 100\n20 ERROR 1\n100 RESUME 200\n", Error: "RESUME: Line 200 does not exist"},
+	}
+
+	for _, test := range fails {
+		e, err := FromString(test.Input)
+		if err != nil {
+			t.Errorf("Error parsing %s - %s", test.Input, err.Error())
+			continue
+		}
+		err = e.Run()
+		if err == nil {
+			t.Errorf("Expected to see an error, but didn't: %s", test.Input)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.Error) {
+			t.Errorf("Our error-message wasn't what we expected: %s", err.Error())
+		}
+	}
+}
+
 // TestExprTerm tests that expr() errors on unclosed brackets.
 func TestExprTerm(t *testing.T) {
 	input := `10 LET a = ( 3 + 3 * 33
@@ -553,6 +713,108 @@ func TestFN(t *testing.T) {
 	}
 }
 
+// TestFNNested tests that user-defined functions may call each other, and
+// the builtins registered by the host.
+func TestFNNested(t *testing.T) {
+	e, err := FromString(`
+ 10 DEF FN twice(x) = (COUNT x) + (COUNT x)
+ 20 DEF FN four(x) = FN twice(x) + FN twice(x)
+ 30 LET t = FN four( 3 )
+`)
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	total := 0
+	e.SetHost(&total)
+	e.RegisterBuiltin("COUNT", 1, func(env builtin.Environment, args []object.Object) object.Object {
+		total := env.Data().(*Interpreter).Host().(*int)
+		*total += int(args[0].(*object.NumberObject).Value)
+		return args[0]
+	})
+	if err = e.Run(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if total != 12 {
+		t.Errorf("COUNT received %d, not 12", total)
+	}
+	if v := e.GetVariable("t").(*object.NumberObject).Value; v != 12 {
+		t.Errorf("Unexpected result %f", v)
+	}
+
+	//
+	// A function may be called many times, including with the result
+	// of calling it.
+	//
+	e, err = FromString(`
+ 10 DEF FN inc(x) = x + 1
+ 20 LET t = 0
+ 30 FOR i = 1 TO 1000
+ 40   LET t = FN inc(FN inc(t))
+ 50 NEXT i
+`)
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	if err = e.Run(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if v := e.GetVariable("t").(*object.NumberObject).Value; v != 2000 {
+		t.Errorf("Unexpected result %f", v)
+	}
+
+	//
+	// But it can't call itself, as it could never stop.
+	//
+	for _, src := range []string{
+		"10 DEF FN forever(x) = FN forever(x + 1)\n20 LET t = FN forever( 1 )\n",
+		"10 DEF FN a(x) = FN b(x)\n20 DEF FN b(x) = FN a(x) + 1\n30 LET t = FN b( 1 )\n",
+	} {
+		e, err = FromString(src)
+		if err != nil {
+			t.Fatalf("Error parsing program: %s", err.Error())
+		}
+		err = e.Run()
+		if err == nil || !strings.Contains(err.Error(), "can't call itself") {
+			t.Errorf("Expected an error running %q, got %v", src, err)
+		}
+	}
+}
+
+// TestBuiltinOnOff tests that ON and OFF may be given to a built-in.
+func TestBuiltinOnOff(t *testing.T) {
+	e, err := FromString(`
+ 10 SWITCH ON, OFF, off
+ 20 SWITCH on : SWITCH OFF
+ 30 LET OFF = 3
+ 40 SWITCH OFF + 1
+`)
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	var got []float64
+	e.RegisterBuiltin("SWITCH", -1, func(env builtin.Environment, args []object.Object) object.Object {
+		for _, arg := range args {
+			got = append(got, arg.(*object.NumberObject).Value)
+		}
+		return &object.NumberObject{Value: 0}
+	})
+	if err = e.Run(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// OFF is a variable, when it is part of an expression.
+	expected := []float64{1, 0, 0, 1, 0, 4}
+	if len(got) != len(expected) {
+		t.Fatalf("SWITCH received %v, not %v", got, expected)
+	}
+	for i, v := range expected {
+		if got[i] != v {
+			t.Errorf("SWITCH received %v, not %v", got, expected)
+			break
+		}
+	}
+}
+
 // TestFor performs testing of our looping primitive
 func TestFor(t *testing.T) {
 
@@ -661,10 +923,10 @@ func TestFor(t *testing.T) {
 func TestGoSub(t *testing.T) {
 
 	//
-	// This will fail because the target should be a literal.
+	// This will fail because the target should be a number.
 	//
 	fail1 := `
- 10 LET t = 200
+ 10 LET t = "200"
  20 GOSUB t
 200 END
 `
@@ -735,10 +997,10 @@ func TestGoSub(t *testing.T) {
 func TestGoto(t *testing.T) {
 
 	//
-	// This will fail because the target should be a literal.
+	// This will fail because the target should be a number.
 	//
 	fail1 := `
- 10 LET t = 200
+ 10 LET t = "200"
  20 GOTO t
 200 END
 `
@@ -803,6 +1065,50 @@ func TestGoto(t *testing.T) {
 		t.Errorf("Expected x to be %s, got %s", "Steve", out)
 	}
 
+	//
+	// Computed targets work too.
+	//
+	ok2 := `
+ 10 LET t = 2
+ 20 GOTO t * 10 + 20
+ 30 LET a = "Wrong"
+ 40 LET a = "Right"
+ 50 GOSUB t * 50
+ 60 END
+100 LET b = "Called"
+110 RETURN
+`
+	e, err = FromString(ok2)
+	if err != nil {
+		t.Errorf("Error parsing %s - %s", ok2, err.Error())
+	}
+	err = e.Run()
+	if err != nil {
+		t.Errorf("We found an unexpected error: %s", err.Error())
+	}
+	for name, val := range map[string]string{"a": "Right", "b": "Called"} {
+		cur = e.GetVariable(name)
+		if cur.Type() != object.STRING || cur.(*object.StringObject).Value != val {
+			t.Errorf("Expected %s to be %s, got %s", name, val, cur.String())
+		}
+	}
+
+	//
+	// A computed target which doesn't exist is an error.
+	//
+	fail3 := `10 LET t = 3
+20 GOTO t * 1000
+`
+	e, err = FromString(fail3)
+	if err != nil {
+		t.Errorf("Error parsing %s - %s", fail3, err.Error())
+	}
+	err = e.Run()
+	if err == nil {
+		t.Errorf("Expected to see an error, but didn't.")
+	} else if !strings.Contains(err.Error(), "GOTO: Line 3000 does not exist") {
+		t.Errorf("Our error-message wasn't what we expected: %s", err.Error())
+	}
 }
 
 // TestIF performs testing of our IF implementation.
@@ -815,7 +1121,7 @@ func TestIF(t *testing.T) {
 	tests := []Test{
 		{Input: "10 IF 1 < 3 THEN LET res=3", Result: 3},
 		{Input: "20 IF 1 > 3 THEN LET res=1 ELSE let res=33", Result: 33},
-		{Input: "30 IF 1 THEN LET res=21 ELSE PRINT \"OK\n\":", Result: 21},
+		{Input: "30 IF 1 THEN LET res=21 ELSE PRINT \"OK\\n\":", Result: 21},
 		{Input: "30 IF 1 > 3 THEN LET res=21\n", Result: -1},
 		{Input: "30 IF 1 < 3 THEN LET res=21\n", Result: -1},
 		{Input: "30 IF 1 <> \"steve\" + 3 THEN LET res=21\n", Result: -1},
@@ -1036,6 +1342,68 @@ func TestINPUT(t *testing.T) {
 	}
 }
 
+// TestLabels tests that labels may be used in place of line-numbers.
+func TestLabels(t *testing.T) {
+
+	ok := `
+LET a = ""
+LET i = 0
+loop:
+  LET i = i + 1
+  LET a = a + "."
+  IF i < 3 THEN loop
+GOSUB sub
+ON 2 GOSUB sub, other
+IF i = 3 THEN GOTO done ELSE GOTO loop
+LET a = "wrong"
+*done
+END
+sub: LET a = a + "s"
+RETURN
+other:
+  LET a = a + "o"
+  RETURN
+`
+	e, err := FromString(ok)
+	if err != nil {
+		t.Fatalf("Error parsing %s - %s", ok, err.Error())
+	}
+	err = e.Run()
+	if err != nil {
+		t.Errorf("We found an unexpected error: %s", err.Error())
+	}
+	cur := e.GetVariable("a")
+	if cur.Type() != object.STRING || cur.(*object.StringObject).Value != "...so" {
+		t.Errorf("Labels didn't work as expected: %s", cur.String())
+	}
+
+	//
+	// A missing label is an error.
+	//
+	fail := "10 ON 1 GOTO missing\n"
+	e, err = FromString(fail)
+	if err != nil {
+		t.Fatalf("Error parsing %s - %s", fail, err.Error())
+	}
+	err = e.Run()
+	if err == nil {
+		t.Errorf("Expected to see an error, but didn't.")
+	} else if !strings.Contains(err.Error(), "Label missing does not exist") {
+		t.Errorf("Our error-message wasn't what we expected: %s", err.Error())
+	}
+
+	//
+	// A builtin followed by ":" isn't a label.
+	//
+	e, err = FromString("PRINT: LET a = 1\n")
+	if err != nil {
+		t.Fatalf("Error parsing program - %s", err.Error())
+	}
+	if _, ok := e.lines["PRINT"]; ok {
+		t.Errorf("PRINT was treated as a label")
+	}
+}
+
 // TestLet performs sanity-checking on our LET implementation.
 func TestLet(t *testing.T) {
 
@@ -1177,6 +1545,34 @@ func TestMismatchedTypes(t *testing.T) {
 	}
 }
 
+// TestIllegal tests that input the tokenizer couldn't understand is
+// rejected when the program is loaded.
+func TestIllegal(t *testing.T) {
+
+	tests := map[string]string{
+		"10 PRINT 1.2.3\n":           "line 1, column 10: malformed number 1.2.3",
+		"10 LET a = 3\n20 LET b = ?": "line 2, column 12: unexpected character \"?\"",
+		"10 PRINT \"Steve\n":         "line 1, column 10: unterminated string",
+	}
+
+	for input, expected := range tests {
+		_, err := New(tokenizer.New(input))
+		if err == nil {
+			t.Errorf("Expected an error parsing %s, got none", input)
+			continue
+		}
+		if err.Error() != expected {
+			t.Errorf("Unexpected error for %s, got %s", input, err.Error())
+		}
+	}
+
+	// Within a comment they're fine.
+	_, err := New(tokenizer.New("10 REM Isn't this #1?\n20 END\n"))
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+}
+
 // TestMismatchedTypesTerm tests that term() errors on mismatched types.
 func TestMismatchedTypesTerm(t *testing.T) {
 	input := `10 LET a="steve"
@@ -1301,6 +1697,75 @@ func TestNext(t *testing.T) {
 	}
 }
 
+// TestOn tests computed jumps via ON .. GOTO and ON .. GOSUB.
+func TestOn(t *testing.T) {
+
+	//
+	// Each subroutine appends to a string, so we can see which ran.
+	//
+	ok := `
+ 10 LET a = ""
+ 20 FOR i = 0 TO 4
+ 30 ON i GOSUB 100, 200, 300
+ 40 NEXT i
+ 50 ON 2 GOTO 60, 70
+ 60 LET a = a + "!"
+ 70 END
+100 LET a = a + "1"
+110 RETURN
+200 LET a = a + "2"
+210 RETURN
+300 LET a = a + "3"
+310 RETURN
+`
+	e, err := FromString(ok)
+	if err != nil {
+		t.Errorf("Error parsing %s - %s", ok, err.Error())
+	}
+	err = e.Run()
+	if err != nil {
+		t.Errorf("We found an unexpected error: %s", err.Error())
+	}
+	cur := e.GetVariable("a")
+	if cur.Type() != object.STRING || cur.(*object.StringObject).Value != "123" {
+		t.Errorf("ON GOSUB didn't work as expected: %s", cur.String())
+	}
+
+	//
+	// Now some failures.
+	//
+	type Test struct {
+		Input string
+		Error string
+	}
+	tests := []Test{
+		{Input: `10 ON`, Error: "end of program processing ON"},
+		{Input: `10 ON 1`, Error: "end of program processing ON"},
+		{Input: `10 ON "steve" GOTO 10`, Error: "ON expects a numeric expression"},
+		{Input: `10 ON 1 PRINT 10`, Error: "expected GOTO or GOSUB after ON"},
+		{Input: "10 ON 1 GOTO\n", Error: "should be followed by a list of line-numbers"},
+		{Input: `10 ON 2 GOSUB 10, 20`, Error: "GOSUB: Line 20 does not exist"},
+		{Input: `10 ON 1 GOTO 20 + 1`, Error: "GOTO: Line 20 does not exist"},
+		{Input: `10 ON x GOTO 10`, Error: "doesn't exist"},
+	}
+
+	for _, test := range tests {
+		e, err = FromString(test.Input)
+		if err != nil {
+			t.Errorf("Error parsing %s - %s", test.Input, err.Error())
+			continue
+		}
+		err = e.Run()
+		if err == nil {
+			t.Errorf("Expected to see an error, but didn't: %s", test.Input)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.Error) {
+			t.Errorf("Our error-message wasn't what we expected: %s", err.Error())
+		}
+	}
+}
+
 // TestRead ensures that the READ statement is sane.
 func TestRead(t *testing.T) {
 
@@ -1736,7 +2201,7 @@ func TestZero(t *testing.T) {
 // of newlines.
 func TestSwallowLine(t *testing.T) {
 
-	input := `10 REM "This is a test"  So is this
+	input := `10 DATA "This is a test", 3, 4
 20 PRINT "OK"
 `
 
@@ -1757,8 +2222,8 @@ func TestSwallowLine(t *testing.T) {
 	}
 
 	// offset should now be bigger
-	if e.offset != 6 {
-		t.Fatalf("our offset was %d not %d", e.offset, 6)
+	if e.offset != 7 {
+		t.Fatalf("our offset was %d not %d", e.offset, 7)
 	}
 
 	// And we should have a newline as the next token
@@ -1766,3 +2231,170 @@ func TestSwallowLine(t *testing.T) {
 		t.Fatalf("did not get a line number got %v", e.program[e.offset])
 	}
 }
+
+// TestSleepInterrupted ensures that a SLEEP is interrupted when the
+// context of the interpreter is cancelled.
+func TestSleepInterrupted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	e, err := NewWithContext(ctx, tokenizer.New("10 SLEEP 60\n"))
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+
+	start := time.Now()
+	err = e.Run()
+	if err == nil {
+		t.Errorf("Expected an error, found none")
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("SLEEP was not interrupted")
+	}
+
+	//
+	// A replacement clock is used by the builtins.
+	//
+	e, err = FromString("10 LET t = TIMER\n")
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	e.SetClock(fixedClock{})
+	if e.Clock() == nil {
+		t.Errorf("Clock was not set")
+	}
+	err = e.Run()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	out := e.GetVariable("t")
+	if out.Type() != object.NUMBER || out.(*object.NumberObject).Value != 3600 {
+		t.Errorf("TIMER didn't use our clock: %s", out.String())
+	}
+}
+
+// fixedClock is a clock which always claims it is 1AM.
+type fixedClock struct{}
+
+func (f fixedClock) Now() time.Time {
+	return time.Date(2000, time.January, 1, 1, 0, 0, 0, time.UTC)
+}
+
+func (f fixedClock) Sleep(ctx context.Context, d time.Duration) error {
+	return nil
+}
+
+// TestTimeout ensures a program which never ends is stopped when the
+// context of the interpreter is cancelled.
+func TestTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	e, err := NewWithContext(ctx, tokenizer.New("10 GOTO 10\n"))
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	err = e.Run()
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
+
+// TestStepLimit ensures a program is stopped once it has executed too
+// many statements.
+func TestStepLimit(t *testing.T) {
+	e, err := FromString("5 LET a = 0\n10 LET a = a + 1\n20 GOTO 10\n")
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	e.SetStepLimit(100)
+
+	err = e.Run()
+	if !errors.Is(err, ErrStepLimit) {
+		t.Fatalf("Expected the step limit to be exceeded, got %v", err)
+	}
+	out := e.GetVariable("a")
+	if out.Type() != object.NUMBER || out.(*object.NumberObject).Value != 50 {
+		t.Errorf("Unexpected number of statements executed: %s", out.String())
+	}
+
+	//
+	// A program within the limit runs to completion.
+	//
+	e, err = FromString("10 LET a = 1\n20 PRINT a\n")
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	e.STDOUT = bufio.NewWriter(&strings.Builder{})
+	e.SetStepLimit(2)
+	if err = e.Run(); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+}
+
+// TestHost ensures the builtins an application registers can reach its
+// state via the interpreter.
+func TestHost(t *testing.T) {
+	type counter struct{ calls int }
+
+	run := func() *counter {
+		e, err := FromString("10 LET a = COUNT\n20 LET a = COUNT\n")
+		if err != nil {
+			t.Fatalf("Error parsing program: %s", err.Error())
+		}
+		c := &counter{}
+		e.SetHost(c)
+		e.RegisterBuiltin("COUNT", 0, func(env builtin.Environment, args []object.Object) object.Object {
+			c := env.Data().(*Interpreter).Host().(*counter)
+			c.calls++
+			return &object.NumberObject{Value: float64(c.calls)}
+		})
+		if err = e.Run(); err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+		return c
+	}
+
+	// Each interpreter has its own state.
+	if a, b := run(), run(); a.calls != 2 || b.calls != 2 {
+		t.Errorf("Unexpected calls %d %d", a.calls, b.calls)
+	}
+}
+
+// TestLineError ensures a failing statement is reported upon the line
+// which holds it, even after a jump.
+func TestLineError(t *testing.T) {
+	for _, src := range []string{
+		"10 GOTO 30\n20 END\n30 PRINT 1 / 0\n",
+		"10 GOSUB 30\n20 END\n30 PRINT 1 / 0\n",
+	} {
+		e, err := FromString(src)
+		if err != nil {
+			t.Fatalf("Error parsing program: %s", err.Error())
+		}
+		err = e.Run()
+
+		var l *LineError
+		if !errors.As(err, &l) {
+			t.Fatalf("Expected a LineError, got %v", err)
+		}
+		if l.Line != 3 || l.Label != "30" {
+			t.Errorf("Error reported at %d/%s", l.Line, l.Label)
+		}
+		if err.Error() != "line 30 : Division by zero" {
+			t.Errorf("Unexpected error %q", err.Error())
+		}
+	}
+
+	//
+	// Lines without a number are identified by their position.
+	//
+	e, err := FromString("LET a = 1\nPRINT b\n")
+	if err != nil {
+		t.Fatalf("Error parsing program: %s", err.Error())
+	}
+	err = e.Run()
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2 : ") {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -105,6 +105,7 @@ func FuzzEval(f *testing.F) {
 			"got token",
 			"access out of bounds",
 			"argument count mis-match",
+			"assertion failed",
 			"def fn: expected ",
 			"dimension too large",
 			"division by zero",
@@ -125,6 +126,7 @@ func FuzzEval(f *testing.F) {
 			"input should be",
 			"invalid prompt-type",
 			"length of strings cannot exceed",
+			"malformed number",
 			"must be an integer",
 			"must be >0",
 			"next variable",
@@ -143,9 +145,11 @@ func FuzzEval(f *testing.F) {
 			"timeout during execution",
 			"type mismatch between",
 			"unclosed for loop",
+			"unexpected character",
 			"unexpected token",
 			"unexpected value found when looking for index",
 			"unhandled token",
+			"unterminated string",
 			"while searching for argument",
 			"without opening for",
 		}
@@ -0,0 +1,438 @@
+// profile.go contains the support for profiling programs, recording how
+// often each line is executed, how long it takes, and which built-in
+// functions it calls.
+//
+// The results may be written as an annotated listing of the program, or
+// as a profile which `go tool pprof` understands.
+
+package eval
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skx/gobasic/token"
+)
+
+// LineProfile holds the statistics for a single line of a program.
+type LineProfile struct {
+
+	// Line is the line of the source which these statistics
+	// describe, counting from one.
+	Line int
+
+	// Label is the BASIC line-number of the line, if it has one.
+	Label string
+
+	// Statements is the number of statements which were executed
+	// upon the line.
+	Statements int
+
+	// Time is the total time spent executing those statements,
+	// excluding any statements they executed themselves, such as
+	// the statement which follows THEN.
+	Time time.Duration
+
+	// Builtins holds the number of times each built-in function was
+	// called by the line, indexed by the name of the built-in.
+	Builtins map[string]int
+}
+
+// Calls returns the total number of built-in functions the line called.
+func (l LineProfile) Calls() int {
+	n := 0
+	for _, c := range l.Builtins {
+		n += c
+	}
+	return n
+}
+
+// Profile holds the statistics gathered while a program runs.
+type Profile struct {
+
+	// Lines holds the statistics for each line which was executed,
+	// in the order the lines appear in the source.
+	Lines []LineProfile
+
+	// Duration is the time from when profiling began until the most
+	// recent statement finished.
+	Duration time.Duration
+
+	// start is the time at which profiling began.
+	start time.Time
+
+	// samples holds the statistics for each distinct GOSUB stack.
+	samples []*profileSample
+}
+
+// profileSample holds the statistics for statements which executed with
+// the same stack of GOSUB calls.
+type profileSample struct {
+
+	// stack holds the source lines of the statement, and of the GOSUB
+	// calls which led to it, innermost first.
+	stack []int
+
+	// statements, nanos, and calls hold the number of statements,
+	// the time they took, and the number of built-in calls they made.
+	statements int64
+	nanos      int64
+	calls      int64
+}
+
+// profiler gathers statistics while a program runs.
+type profiler struct {
+
+	// start is the time at which profiling began, and end the time
+	// at which the most recent statement finished.
+	start time.Time
+	end   time.Time
+
+	// lines holds the statistics for each source line.
+	lines map[int]*LineProfile
+
+	// labels holds the BASIC line-number of each source line.
+	labels map[int]string
+
+	// samples holds the statistics for each GOSUB stack, indexed by
+	// its text.
+	samples map[string]*profileSample
+
+	// line and sample are the statistics of the statement which is
+	// currently executing.
+	line   *LineProfile
+	sample *profileSample
+
+	// nested is the time spent in statements executed by the current
+	// statement, such as the statement which follows THEN, which is
+	// recorded against them rather than it.
+	nested time.Duration
+}
+
+// SetProfile allows the user to enable the gathering of statistics
+// about the lines of the program as it runs, which may then be retrieved
+// via Profile.
+//
+// Enabling profiling discards any statistics gathered previously.
+func (e *Interpreter) SetProfile(val bool) {
+	e.profile = nil
+	if val {
+		now := e.clock.Now()
+		e.profile = &profiler{
+			start:   now,
+			end:     now,
+			lines:   make(map[int]*LineProfile),
+			labels:  make(map[int]string),
+			samples: make(map[string]*profileSample),
+		}
+
+		//
+		// A GOSUB jumps past the line-number of its target, so
+		// we can't rely upon e.lineno to name the line.
+		//
+		for _, tok := range e.program {
+			if tok.Type == token.LINENO {
+				e.profile.labels[tok.Line] = tok.Literal
+			}
+		}
+	}
+}
+
+// Profile returns the statistics gathered so far, or nil if profiling
+// has not been enabled via SetProfile.
+func (e *Interpreter) Profile() *Profile {
+	if e.profile == nil {
+		return nil
+	}
+
+	p := &Profile{
+		Duration: e.profile.end.Sub(e.profile.start),
+		start:    e.profile.start,
+	}
+	for _, l := range e.profile.lines {
+		line := *l
+		line.Builtins = make(map[string]int)
+		for k, v := range l.Builtins {
+			line.Builtins[k] = v
+		}
+		p.Lines = append(p.Lines, line)
+	}
+	sort.Slice(p.Lines, func(i, j int) bool {
+		return p.Lines[i].Line < p.Lines[j].Line
+	})
+
+	var keys []string
+	for k := range e.profile.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := *e.profile.samples[k]
+		p.samples = append(p.samples, &s)
+	}
+	return p
+}
+
+// beginProfile records the start of the given statement, returning a
+// function to be called when it has finished.
+func (e *Interpreter) beginProfile(tok token.Token) func() {
+	p := e.profile
+
+	l := p.lines[tok.Line]
+	if l == nil {
+		l = &LineProfile{Line: tok.Line, Label: p.labels[tok.Line], Builtins: make(map[string]int)}
+		p.lines[tok.Line] = l
+	}
+
+	//
+	// The stack holds our line, and the lines of the GOSUB calls
+	// which led to it.  Each return address follows the GOSUB which
+	// pushed it.
+	//
+	stack := []int{tok.Line}
+	returns := e.gstack.Values()
+	for i := len(returns) - 1; i >= 0; i-- {
+		if returns[i] > 0 && returns[i] <= len(e.program) {
+			stack = append(stack, e.program[returns[i]-1].Line)
+		}
+	}
+
+	var key strings.Builder
+	for _, n := range stack {
+		key.WriteString(strconv.Itoa(n))
+		key.WriteString(" ")
+	}
+	s := p.samples[key.String()]
+	if s == nil {
+		s = &profileSample{stack: stack}
+		p.samples[key.String()] = s
+	}
+
+	line, sample, nested := p.line, p.sample, p.nested
+	p.line = l
+	p.sample = s
+	p.nested = 0
+
+	start := e.clock.Now()
+	return func() {
+		p.end = e.clock.Now()
+		elapsed := p.end.Sub(start)
+		l.Statements++
+		l.Time += elapsed - p.nested
+		s.statements++
+		s.nanos += int64(elapsed - p.nested)
+
+		p.line, p.sample, p.nested = line, sample, nested+elapsed
+	}
+}
+
+// called records a call to the given built-in function.
+func (p *profiler) called(name string) {
+	if p.line == nil {
+		return
+	}
+	p.line.Builtins[strings.ToUpper(name)]++
+	p.sample.calls++
+}
+
+// WriteListing writes the source of the program, which is given, with
+// each line annotated by the number of statements it executed, the time
+// they took, and the number of built-in functions they called.
+//
+// A summary of the built-in functions called follows the listing.
+func (p *Profile) WriteListing(w io.Writer, src string) error {
+	out := bufio.NewWriter(w)
+
+	lines := make(map[int]LineProfile)
+	builtins := make(map[string]int)
+	for _, l := range p.Lines {
+		lines[l.Line] = l
+		for k, v := range l.Builtins {
+			builtins[k] += v
+		}
+	}
+
+	fmt.Fprintf(out, "%10s %12s %8s | %s\n", "count", "time", "builtins", "source")
+	for i, text := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		l, ok := lines[i+1]
+		if !ok {
+			fmt.Fprintf(out, "%10s %12s %8s | %s\n", "", "", "", text)
+			continue
+		}
+		fmt.Fprintf(out, "%10d %12s %8d | %s\n", l.Statements, l.Time.Round(time.Microsecond), l.Calls(), text)
+	}
+
+	if len(builtins) > 0 {
+		var names []string
+		for k := range builtins {
+			names = append(names, k)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if builtins[names[i]] != builtins[names[j]] {
+				return builtins[names[i]] > builtins[names[j]]
+			}
+			return names[i] < names[j]
+		})
+
+		fmt.Fprintf(out, "\n%10s | %s\n", "calls", "builtin")
+		for _, n := range names {
+			fmt.Fprintf(out, "%10d | %s\n", builtins[n], n)
+		}
+	}
+
+	fmt.Fprintf(out, "\ntotal time %s\n", p.Duration.Round(time.Microsecond))
+	return out.Flush()
+}
+
+// WritePprof writes the profile in the format which `go tool pprof`
+// understands, a gzipped protocol buffer.
+//
+// Each line of the program is shown as a function, named after its
+// line-number, within the file at the given path.  The lines of any GOSUB
+// calls are its callers, so the cumulative time of a GOSUB includes the
+// time spent in the subroutine.
+func (p *Profile) WritePprof(w io.Writer, path string) error {
+	var b protobuf
+
+	// The string table, which must begin with the empty string.
+	strs := []string{""}
+	index := make(map[string]int64)
+	str := func(s string) int64 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = int64(len(strs))
+		strs = append(strs, s)
+		return index[s]
+	}
+
+	// sample_type
+	for _, t := range [][2]string{{"statements", "count"}, {"time", "nanoseconds"}, {"builtins", "count"}} {
+		var v protobuf
+		v.int(1, str(t[0]))
+		v.int(2, str(t[1]))
+		b.bytes(1, v)
+	}
+
+	// sample
+	for _, s := range p.samples {
+		var v protobuf
+		var ids []int64
+		for _, n := range s.stack {
+			ids = append(ids, int64(n))
+		}
+		v.packed(1, ids)
+		v.packed(2, []int64{s.statements, s.nanos, s.calls})
+		b.bytes(2, v)
+	}
+
+	// location and function, both of which are identified by the
+	// source line.
+	labels := make(map[int]string)
+	for _, l := range p.Lines {
+		labels[l.Line] = l.Label
+	}
+	seen := make(map[int]bool)
+	var used []int
+	for _, s := range p.samples {
+		for _, n := range s.stack {
+			if !seen[n] {
+				seen[n] = true
+				used = append(used, n)
+			}
+		}
+	}
+	sort.Ints(used)
+	for _, n := range used {
+		var line protobuf
+		line.int(1, int64(n))
+		line.int(2, int64(n))
+
+		var loc protobuf
+		loc.int(1, int64(n))
+		loc.bytes(4, line)
+		b.bytes(4, loc)
+	}
+	for _, n := range used {
+		name := fmt.Sprintf("line %d", n)
+		if labels[n] != "" {
+			name = "line " + labels[n]
+		}
+
+		var fn protobuf
+		fn.int(1, int64(n))
+		fn.int(2, str(name))
+		fn.int(3, str(name))
+		fn.int(4, str(path))
+		fn.int(5, int64(n))
+		b.bytes(5, fn)
+	}
+
+	// time_nanos, duration_nanos, and the period.
+	period := str("time")
+	unit := str("nanoseconds")
+	def := str("time")
+
+	for _, s := range strs {
+		b.bytes(6, protobuf(s))
+	}
+	b.int(9, p.start.UnixNano())
+	b.int(10, int64(p.Duration))
+	var pt protobuf
+	pt.int(1, period)
+	pt.int(2, unit)
+	b.bytes(11, pt)
+	b.int(12, 1)
+	b.int(14, def)
+
+	z := gzip.NewWriter(w)
+	if _, err := z.Write(b); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// protobuf holds a protocol buffer message as we encode it.
+//
+// We encode only the wire-types which the pprof format requires.
+type protobuf []byte
+
+// varint appends a number in the variable-length encoding.
+func (b *protobuf) varint(n uint64) {
+	for n >= 0x80 {
+		*b = append(*b, byte(n)|0x80)
+		n >>= 7
+	}
+	*b = append(*b, byte(n))
+}
+
+// int appends a numeric field, omitting it if it is zero.
+func (b *protobuf) int(field int, n int64) {
+	if n == 0 {
+		return
+	}
+	b.varint(uint64(field) << 3)
+	b.varint(uint64(n))
+}
+
+// bytes appends a string, or a nested message.
+func (b *protobuf) bytes(field int, v []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// packed appends a repeated numeric field.
+func (b *protobuf) packed(field int, v []int64) {
+	var p protobuf
+	for _, n := range v {
+		p.varint(uint64(n))
+	}
+	b.bytes(field, p)
+}
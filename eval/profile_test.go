@@ -0,0 +1,145 @@
+// profile_test.go - Test-cases for our profiler.
+
+package eval
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// stepClock is a clock which advances by a millisecond every time it is
+// read.
+type stepClock struct {
+	now time.Time
+}
+
+// Now returns the current time, and then advances it.
+func (s *stepClock) Now() time.Time {
+	s.now = s.now.Add(time.Millisecond)
+	return s.now
+}
+
+// Sleep advances the time by the given duration.
+func (s *stepClock) Sleep(ctx context.Context, d time.Duration) error {
+	s.now = s.now.Add(d)
+	return nil
+}
+
+// TestProfile tests the statistics which are gathered.
+func TestProfile(t *testing.T) {
+	src := `10 LET t = 0
+20 FOR i = 1 TO 3
+30 GOSUB 100
+40 NEXT i
+50 IF t > 0 THEN PRINT ""
+60 END
+100 LET t = t + ABS i : LET t = t + LEN "ab"
+110 RETURN
+`
+	e, err := New(tokenizer.New(src))
+	if err != nil {
+		t.Fatalf("error parsing: %s", err.Error())
+	}
+
+	if e.Profile() != nil {
+		t.Errorf("expected no profile before profiling is enabled")
+	}
+
+	e.SetClock(&stepClock{})
+	e.SetProfile(true)
+	if err = e.Run(); err != nil {
+		t.Fatalf("error running: %s", err.Error())
+	}
+
+	p := e.Profile()
+	if len(p.Lines) != 8 {
+		t.Fatalf("unexpected lines %v", p.Lines)
+	}
+
+	l := p.Lines[6]
+	if l.Line != 7 || l.Label != "100" {
+		t.Errorf("unexpected line %d, %s", l.Line, l.Label)
+	}
+	if l.Statements != 6 || l.Time != 6*time.Millisecond {
+		t.Errorf("unexpected statistics %d, %s", l.Statements, l.Time)
+	}
+	if l.Builtins["ABS"] != 3 || l.Builtins["LEN"] != 3 || l.Calls() != 6 {
+		t.Errorf("unexpected calls %v", l.Builtins)
+	}
+	if p.Lines[2].Statements != 3 || p.Lines[2].Calls() != 0 {
+		t.Errorf("unexpected statistics for GOSUB %v", p.Lines[2])
+	}
+
+	// The PRINT the IF runs takes a millisecond, which isn't counted
+	// twice, and the IF reads the clock twice around it.
+	if p.Lines[4].Statements != 2 || p.Lines[4].Time != 3*time.Millisecond || p.Lines[4].Calls() != 1 {
+		t.Errorf("unexpected statistics for IF %v", p.Lines[4])
+	}
+
+	//
+	// The listing holds each line of the source.
+	//
+	var listing bytes.Buffer
+	if err = p.WriteListing(&listing, src); err != nil {
+		t.Fatalf("error writing listing: %s", err.Error())
+	}
+	for _, expected := range []string{
+		"         6          6ms        6 | 100 LET t = t + ABS i",
+		"         3          3ms        0 | 110 RETURN",
+		"         3 | ABS",
+	} {
+		if !strings.Contains(listing.String(), expected) {
+			t.Errorf("listing doesn't contain %q\n%s", expected, listing.String())
+		}
+	}
+
+	//
+	// The pprof profile is compressed, and names the lines.
+	//
+	var out bytes.Buffer
+	if err = p.WritePprof(&out, "test.bas"); err != nil {
+		t.Fatalf("error writing profile: %s", err.Error())
+	}
+	z, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("profile isn't compressed: %s", err.Error())
+	}
+	data, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("error reading profile: %s", err.Error())
+	}
+	for _, expected := range []string{"line 100", "line 30", "test.bas", "nanoseconds", "builtins"} {
+		if !bytes.Contains(data, []byte(expected)) {
+			t.Errorf("profile doesn't contain %q", expected)
+		}
+	}
+
+	//
+	// Disabling profiling discards the statistics.
+	//
+	e.SetProfile(false)
+	if e.Profile() != nil {
+		t.Errorf("expected no profile after profiling is disabled")
+	}
+}
+
+// TestProtobuf tests the encoding of the pprof profile.
+func TestProtobuf(t *testing.T) {
+	var b protobuf
+	b.int(1, 0)
+	b.int(1, 300)
+	b.bytes(2, []byte("hi"))
+	b.packed(3, []int64{1, 2})
+
+	expected := []byte{0x08, 0xAC, 0x02, 0x12, 2, 'h', 'i', 0x1A, 2, 1, 2}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("unexpected encoding % X", []byte(b))
+	}
+}
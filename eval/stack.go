@@ -43,6 +43,15 @@ func (s *Stack) Pop() (int, error) {
 	return res, nil
 }
 
+// Values returns a copy of the items upon our stack, with the most
+// recently pushed last.
+func (s *Stack) Values() []int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]int(nil), s.s...)
+}
+
 // Empty returns `true` if our stack is empty.
 func (s *Stack) Empty() bool {
 
@@ -38,3 +38,15 @@ func (v *Variables) Get(name string) object.Object {
 	defer v.lock.Unlock()
 	return (v.data[name])
 }
+
+// All returns a copy of every variable, keyed by name.
+func (v *Variables) All() map[string]object.Object {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	out := make(map[string]object.Object, len(v.data))
+	for name, val := range v.data {
+		out[name] = val
+	}
+	return out
+}
@@ -53,3 +53,22 @@ func TestString(t *testing.T) {
 		t.Errorf("Our value was lost!")
 	}
 }
+
+// TestAll: Test we can retrieve every variable.
+func TestAll(t *testing.T) {
+
+	v := NewVars()
+	v.Set("a", &object.NumberObject{Value: 1})
+	v.Set("b$", &object.StringObject{Value: "x"})
+
+	all := v.All()
+	if len(all) != 2 || all["a"].(*object.NumberObject).Value != 1 {
+		t.Errorf("Unexpected variables %v", all)
+	}
+
+	// Changing the copy doesn't change the originals.
+	delete(all, "a")
+	if v.Get("a") == nil {
+		t.Errorf("Our value was lost!")
+	}
+}
@@ -0,0 +1,19 @@
+REM
+REM This program demonstrates using labels, rather than line-numbers.
+REM
+REM A label is a name at the start of a line, followed by ":", or
+REM prefixed with "*".
+REM
+LET i = 1
+
+loop:
+  GOSUB show
+  LET i = i + 1
+  IF i <= 5 THEN loop
+
+PRINT "Done\n"
+END
+
+*show
+  PRINT "Iteration ", i, "\n"
+  RETURN
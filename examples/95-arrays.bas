@@ -54,5 +54,5 @@
 
 10000 REM ASCII-codes of the digits 0-9
 10010 DATA 48, 49, 50, 51, 52, 53, 55, 55, 56, 57
-10000 REM ASCII-codes of the letters A-F
+10020 REM ASCII-codes of the letters A-F
 10030 DATA 65, 66, 67, 68, 69, 70
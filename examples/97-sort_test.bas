@@ -0,0 +1,32 @@
+ 10 REM
+ 20 REM This program demonstrates testing BASIC code with ASSERT.
+ 30 REM
+ 40 REM Run it with "gobasic test examples/", which feeds it the
+ 50 REM numbers in 97-sort_test.stdin, and compares what it prints
+ 60 REM with 97-sort_test.golden.
+ 70 REM
+
+100 DIM a(5)
+110 FOR i = 0 TO 4
+120   INPUT "", n : a[i] = n
+130 NEXT i
+
+200 GOSUB 1000
+
+300 FOR i = 0 TO 3
+310   ASSERT a[i] <= a[i + 1], "the numbers are not sorted"
+320 NEXT i
+330 ASSERT a[0] = 1 AND a[4] = 9
+
+400 FOR i = 0 TO 4
+410   PRINT a[i], "\n"
+420 NEXT i
+430 END
+
+1000 REM Sort the array a, of five numbers, into ascending order.
+1010 FOR i = 0 TO 3
+1020   FOR j = 0 TO 3 - i
+1030     IF a[j] > a[j + 1] THEN SWAP a[j], a[j + 1]
+1040   NEXT j
+1050 NEXT i
+1060 RETURN
@@ -0,0 +1,149 @@
+// diff.go contains a simple line-based differ, used to show the changes
+// the formatter would make.
+
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// Diff returns the differences between the two texts, in the unified
+// diff format, or the empty string if they are identical.
+func Diff(name string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+
+	x := splitLines(a)
+	y := splitLines(b)
+
+	//
+	// Find the longest common subsequence of lines, working
+	// backwards so that we can walk forward through the result.
+	//
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	//
+	// Now build up the list of operations; ' ' for a common line,
+	// '-' for a line which was removed, and '+' for one added.
+	//
+	type op struct {
+		kind byte
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, op{' ', x[i]})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', x[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', y[j]})
+			j++
+		}
+	}
+
+	//
+	// Finally group the operations into hunks, with some
+	// context around each change.
+	//
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("--- %s.orig\n+++ %s\n", name, name))
+
+	for start := 0; start < len(ops); {
+
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk until we find enough unchanged lines.
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		// Count the lines on each side, to build the header.
+		oldStart, newStart := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != '+' {
+				oldStart++
+			}
+			if o.kind != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+
+		out.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount))
+		for _, o := range ops[from:to] {
+			out.WriteString(fmt.Sprintf("%c%s\n", o.kind, o.text))
+		}
+		start = to
+	}
+
+	return out.String()
+}
+
+// splitLines splits text into lines, ignoring any final newline.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,333 @@
+// Package format contains the code to format BASIC programs in a
+// canonical style.
+//
+// The formatter is built upon our tokenizer, so the formatted program is
+// produced from the same tokens that the interpreter would execute:
+//
+//   - Keywords, and the names of built-in functions, are upper-cased.
+//   - Tokens are separated by single spaces, with no spaces inside
+//     brackets or before commas.
+//   - Line-numbers are right-aligned, and the bodies of FOR loops are
+//     indented.
+//   - Runs of blank lines are collapsed.
+//
+// The text of comments, and of string literals, is preserved exactly.
+//
+// After formatting we tokenize both the input and the output, and refuse
+// to return a result which would behave differently to the original.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/token"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// Formatter holds our state.
+type Formatter struct {
+
+	// builtins holds the names of the known built-in functions,
+	// and the number of arguments they require.
+	builtins map[string]int
+}
+
+// New returns a formatter which knows about the built-in functions that
+// the given map contains, as returned by eval.Interpreter.Builtins.
+func New(builtins map[string]int) *Formatter {
+	return &Formatter{builtins: builtins}
+}
+
+// Source formats the given program, using the built-in functions that
+// the standard interpreter provides.
+func Source(src string) (string, error) {
+	e, err := eval.New(tokenizer.New(""))
+	if err != nil {
+		return "", err
+	}
+	return New(e.Builtins()).Format(src)
+}
+
+// item is a token, along with the text it was read from.
+type item struct {
+	tok token.Token
+	raw string
+}
+
+// Format formats the given program.
+func (f *Formatter) Format(src string) (string, error) {
+
+	//
+	// Read each line, and the tokens it contains.
+	//
+	var lines [][]item
+	width := 0
+	for _, text := range strings.Split(src, "\n") {
+		items := f.tokens(text)
+		if len(items) > 0 && items[0].tok.Type == token.LINENO {
+			if len(items[0].raw) > width {
+				width = len(items[0].raw)
+			}
+		}
+		lines = append(lines, items)
+	}
+
+	//
+	// Now render them.
+	//
+	var out strings.Builder
+	depth := 0
+	blank := false
+	for _, items := range lines {
+
+		if len(items) == 0 {
+			blank = out.Len() > 0
+			continue
+		}
+		if blank {
+			out.WriteString("\n")
+			blank = false
+		}
+
+		// Output the line-number, if any, right-aligned.
+		number := ""
+		if items[0].tok.Type == token.LINENO {
+			number = items[0].raw
+			items = items[1:]
+		}
+		if width > 0 {
+			out.WriteString(fmt.Sprintf("%*s", width, number))
+		}
+
+		// A NEXT is indented to match the FOR which opened the loop.
+		indent := depth
+		if len(items) > 0 && items[0].tok.Type == token.NEXT && indent > 0 {
+			indent--
+		}
+		for _, it := range items {
+			switch it.tok.Type {
+			case token.FOR:
+				depth++
+			case token.NEXT:
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+
+		// Labels are never indented.
+		if len(items) > 0 && f.isLabel(items) {
+			indent = 0
+		}
+
+		if len(items) > 0 {
+			if width > 0 {
+				out.WriteString(" ")
+			}
+			out.WriteString(strings.Repeat("  ", indent))
+			out.WriteString(f.render(items))
+		}
+		out.WriteString("\n")
+	}
+
+	result := out.String()
+
+	//
+	// Finally make sure that we've not changed the meaning of the
+	// program.
+	//
+	if err := f.compare(src, result); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// tokens returns the tokens which the given line contains, along with
+// the text of each.
+//
+// A comment is returned as a single token, whose text is the whole of
+// the comment.
+func (f *Formatter) tokens(text string) []item {
+	var items []item
+
+	chars := []rune(text)
+	t := tokenizer.New(text)
+
+	prev := 0
+	for {
+		tok := t.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+
+		raw := strings.TrimLeft(string(chars[prev:t.Offset()]), " \t\r")
+		prev = t.Offset()
+
+		if tok.Type == token.REM {
+			raw = comment(tok.Literal)
+		}
+		items = append(items, item{tok: tok, raw: raw})
+	}
+	return items
+}
+
+// comment returns the canonical text of a comment, which is unchanged
+// other than the case of the "REM" keyword, and any trailing whitespace.
+func comment(text string) string {
+	text = strings.TrimRight(text, " \t\r")
+	if len(text) >= 3 && strings.EqualFold(text[:3], "REM") {
+		return "REM" + text[3:]
+	}
+	return text
+}
+
+// isLabel returns true if the given tokens define a label, either as
+// "*name" or "name:".
+func (f *Formatter) isLabel(items []item) bool {
+	if items[0].tok.Type == token.LABEL {
+		return true
+	}
+	return len(items) == 2 && items[0].tok.Type == token.IDENT &&
+		items[1].tok.Type == token.COLON && !f.isBuiltin(items[0].tok.Literal)
+}
+
+// isBuiltin returns true if the given name is a built-in function.
+func (f *Formatter) isBuiltin(name string) bool {
+	_, ok := f.builtins[name]
+	return ok
+}
+
+// text returns the canonical text of the given token.
+func (f *Formatter) text(it item) string {
+	tok := it.tok
+
+	switch tok.Type {
+	case token.STRING, token.LABEL, token.REM, token.INT, token.LINENO:
+		return it.raw
+	case token.IDENT:
+
+		// Built-in functions are registered in both upper- and
+		// lower-case, mixed-case names are variables.
+		if f.isBuiltin(tok.Literal) && isASCII(tok.Literal) {
+			return strings.ToUpper(tok.Literal)
+		}
+		return tok.Literal
+	}
+
+	if token.LookupIdentifier(tok.Literal) != token.IDENT {
+		return strings.ToUpper(tok.Literal)
+	}
+	return tok.Literal
+}
+
+// render returns the canonical text of the given tokens.
+func (f *Formatter) render(items []item) string {
+	var out strings.Builder
+
+	for i, it := range items {
+		if i > 0 && f.space(items, i) {
+			out.WriteString(" ")
+		}
+		out.WriteString(f.text(it))
+	}
+	return out.String()
+}
+
+// space returns true if there should be a space before the given token.
+func (f *Formatter) space(items []item, i int) bool {
+	prev := items[i-1].tok
+	cur := items[i].tok
+
+	switch cur.Type {
+	case token.RBRACKET, token.RINDEX, token.COMMA, token.SEMICOLON:
+		return false
+	case token.COLON:
+		return !f.isLabel(items)
+	case token.LBRACKET, token.LINDEX:
+
+		// Arrays, user-defined functions, and built-ins which take
+		// a fixed number of arguments look like function-calls.
+		if prev.Type == token.IDENT {
+			n, ok := f.builtins[prev.Literal]
+			return ok && n < 0
+		}
+	}
+
+	switch prev.Type {
+	case token.LBRACKET, token.LINDEX:
+		return false
+	}
+	return true
+}
+
+// compare ensures that the two programs are equivalent, returning an
+// error describing the first difference if they are not.
+func (f *Formatter) compare(a string, b string) error {
+	ta := significant(a)
+	tb := significant(b)
+
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		if !f.equivalent(ta[i], tb[i]) {
+			return fmt.Errorf("formatting would change the program, %v became %v", ta[i], tb[i])
+		}
+	}
+	if len(ta) != len(tb) {
+		return fmt.Errorf("formatting would change the program, it had %d tokens and would have %d", len(ta), len(tb))
+	}
+	return nil
+}
+
+// equivalent returns true if the two tokens have the same meaning.
+func (f *Formatter) equivalent(a token.Token, b token.Token) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Literal == b.Literal {
+		return true
+	}
+	if a.Type == token.REM {
+		return comment(a.Literal) == comment(b.Literal)
+	}
+
+	// Keywords are case-insensitive, as are built-ins.
+	if a.Type != token.IDENT || f.isBuiltin(a.Literal) {
+		return strings.EqualFold(a.Literal, b.Literal) &&
+			(a.Type != token.IDENT || f.isBuiltin(b.Literal))
+	}
+	return false
+}
+
+// significant returns the tokens of the given program, ignoring any
+// blank lines.
+func significant(src string) []token.Token {
+	var out []token.Token
+
+	t := tokenizer.New(src)
+	for {
+		tok := t.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		if tok.Type == token.NEWLINE && (len(out) == 0 || out[len(out)-1].Type == token.NEWLINE) {
+			continue
+		}
+		out = append(out, tok)
+	}
+
+	if len(out) > 0 && out[len(out)-1].Type == token.NEWLINE {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// isASCII returns true if the string contains only ASCII characters.
+func isASCII(s string) bool {
+	for _, c := range s {
+		if c > 127 {
+			return false
+		}
+	}
+	return true
+}
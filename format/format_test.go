@@ -0,0 +1,170 @@
+// format_test.go - Test-cases for our formatter.
+
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFormat tests some simple programs are formatted as expected.
+func TestFormat(t *testing.T) {
+
+	type Test struct {
+		Input  string
+		Output string
+	}
+
+	tests := []Test{
+
+		// Keywords and builtins are upper-cased, variables are not.
+		{Input: "10 let a=len \"steve\"\n20 print a,\"\\n\"\n",
+			Output: "10 LET a = LEN \"steve\"\n20 PRINT a, \"\\n\"\n"},
+
+		// Mixed-case names aren't builtins, so they're left alone.
+		{Input: "10 Len = 3\n",
+			Output: "10 Len = 3\n"},
+
+		// Line-numbers are aligned.
+		{Input: "5 END\n100 END\n   DATA 1,2\n",
+			Output: "  5 END\n100 END\n    DATA 1, 2\n"},
+
+		// Comments and strings are preserved.
+		{Input: "10 rem   Hello,   \"World\"  \n20 PRINT \"a  \\\"b\\\"  c\"  : rem x\n",
+			Output: "10 REM   Hello,   \"World\"\n20 PRINT \"a  \\\"b\\\"  c\" : REM x\n"},
+
+		// Apostrophe comments may appear anywhere.
+		{Input: "10 print 1 '  don't \"panic\"  \n'  the end\n",
+			Output: "10 PRINT 1 '  don't \"panic\"\n   '  the end\n"},
+
+		// Blank lines are collapsed, and removed from the start and end.
+		{Input: "\n\n10 END\n\n\n\n20 END\n\n\n",
+			Output: "10 END\n\n20 END\n"},
+
+		// Loops are indented.
+		{Input: "10 FOR i=1 TO 3\n20 FOR j = 1 TO 3\n30 PRINT i*j\n40 NEXT j\n50 NEXT i\n",
+			Output: "10 FOR i = 1 TO 3\n20   FOR j = 1 TO 3\n30     PRINT i * j\n40   NEXT j\n50 NEXT i\n"},
+
+		// Brackets, arrays, and functions.
+		{Input: "10 DIM a( 3,3 )\n20 a[ 1,2 ]=SIN( 3 ) + FN f( 2 )\n30 PRINT ( 1+2 ) * -3\n",
+			Output: "10 DIM a(3, 3)\n20 a[1, 2] = SIN(3) + FN f(2)\n30 PRINT (1 + 2) * -3\n"},
+
+		// Labels.
+		{Input: "start :\n  *sub\ngoto   start\n",
+			Output: "start:\n*sub\nGOTO start\n"},
+
+		// Subtraction remains subtraction.
+		{Input: "10 LET a=3-3\n20 LET b=a-3\n30 LET c=3 -3\n",
+			Output: "10 LET a = 3 - 3\n20 LET b = a - 3\n30 LET c = 3 - 3\n"},
+	}
+
+	for _, test := range tests {
+		out, err := Source(test.Input)
+		if err != nil {
+			t.Errorf("unexpected error formatting %q: %s", test.Input, err.Error())
+			continue
+		}
+		if out != test.Output {
+			t.Errorf("formatting %q gave %q, expected %q", test.Input, out, test.Output)
+		}
+
+		// Formatting is idempotent.
+		again, err := Source(out)
+		if err != nil {
+			t.Errorf("unexpected error reformatting %q: %s", out, err.Error())
+			continue
+		}
+		if again != out {
+			t.Errorf("reformatting %q gave %q", out, again)
+		}
+	}
+}
+
+// TestExamples ensures that all our examples can be formatted.
+func TestExamples(t *testing.T) {
+
+	files, err := filepath.Glob("../examples/*.bas")
+	if err != nil {
+		t.Fatalf("failed to find examples: %s", err.Error())
+	}
+	if len(files) == 0 {
+		t.Fatalf("failed to find examples")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", file, err.Error())
+		}
+
+		out, err := Source(string(data))
+		if err != nil {
+			t.Errorf("failed to format %s: %s", file, err.Error())
+			continue
+		}
+
+		again, err := Source(out)
+		if err != nil || again != out {
+			t.Errorf("formatting %s is not idempotent", file)
+		}
+	}
+}
+
+// TestCompare ensures that we spot changes in meaning.
+func TestCompare(t *testing.T) {
+
+	f := New(map[string]int{"print": -1, "PRINT": -1})
+
+	if f.compare("10 print 3\n\n", "10 PRINT 3") != nil {
+		t.Errorf("unexpected difference")
+	}
+	if f.compare("10 print 3", "10 PRINT 4") == nil {
+		t.Errorf("expected a difference")
+	}
+	if f.compare("10 print 3", "10 PRINT 3 : END") == nil {
+		t.Errorf("expected a difference")
+	}
+	if f.compare("10 a = 3", "10 A = 3") == nil {
+		t.Errorf("variables are case-sensitive")
+	}
+	if f.compare("10 Print 3", "10 PRINT 3") == nil {
+		t.Errorf("mixed-case names aren't builtins")
+	}
+}
+
+// TestDiff tests our diff output.
+func TestDiff(t *testing.T) {
+
+	if Diff("x", "a\nb\n", "a\nb\n") != "" {
+		t.Errorf("identical inputs should have no diff")
+	}
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+
+	expected := `--- x.orig
++++ x
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`
+	out := Diff("x", a, b)
+	if out != expected {
+		t.Errorf("unexpected diff:\n%s", out)
+	}
+
+	// Two distant changes are shown as two hunks.
+	b = "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+	out = Diff("x", a, b)
+	if strings.Count(out, "@@ -") != 2 {
+		t.Errorf("expected two hunks:\n%s", out)
+	}
+}
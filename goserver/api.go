@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+)
+
+// apiRequest is the body of a request to /api/run.
+type apiRequest struct {
+
+	// Program holds the source of the program to run.
+	Program string `json:"program"`
+
+	// Stdin holds the input the program may read via INPUT.
+	Stdin string `json:"stdin,omitempty"`
+}
+
+// apiError describes the error which ended a program.
+type apiError struct {
+
+	// Message describes the error.
+	Message string `json:"message"`
+
+	// Line is the line of the source which failed, counting from
+	// one, if known.
+	Line int `json:"line,omitempty"`
+
+	// Label is the BASIC line-number of the line which failed, if
+	// it has one.
+	Label string `json:"label,omitempty"`
+}
+
+// apiResponse is the result of a request to /api/run.
+type apiResponse struct {
+
+	// Stdout and Stderr hold the output of the program.
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+
+	// Status is zero if the program ran to completion, one if it
+	// failed, and two if it was stopped because it ran for too long.
+	Status int `json:"status"`
+
+	// Error describes why the program failed, if it did.
+	Error *apiError `json:"error,omitempty"`
+
+	// Variables holds the final value of each variable; numbers,
+	// strings, and arrays of them.
+	Variables map[string]interface{} `json:"variables"`
+
+	// Images holds the base64-encoded images written by SAVE, each in
+	// the format chosen by the name it was saved with.
+	Images []string `json:"images"`
+
+	// Audio holds the sound made by BEEP, PLAY, and SOUND, as a
+	// base64-encoded WAV file, if there was any.
+	Audio string `json:"audio,omitempty"`
+}
+
+// The exit-status of a program.
+const (
+	statusOK     = 0
+	statusFailed = 1
+	statusKilled = 2
+)
+
+// apiHandler handles requests to /api/run, which runs the program in the
+// JSON body of the request and returns its results as JSON:
+//
+//	curl -d '{"program": "10 INPUT \"\", a\n20 PRINT a * 2\n", "stdin": "21\n"}' \
+//	    http://localhost:8080/api/run
+func (s *server) apiHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		apiFail(w, http.StatusMethodNotAllowed, "only POST requests are supported")
+		return
+	}
+
+	var req apiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiFail(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err.Error()))
+		return
+	}
+
+	x := s.execute(r.Context(), req.Program, req.Stdin)
+
+	res := &apiResponse{
+		Stdout:    x.stdout.String(),
+		Stderr:    x.stderr.String(),
+		Variables: make(map[string]interface{}),
+		Images:    []string{},
+	}
+	res.Status, res.Error = s.outcome(x.err)
+
+	if x.e != nil {
+		for name, val := range x.e.Variables() {
+			res.Variables[name] = jsonValue(val)
+		}
+	}
+	for _, img := range x.media.images {
+		res.Images = append(res.Images, base64.StdEncoding.EncodeToString(img.data))
+	}
+
+	audio, err := x.media.audio()
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res.Audio = audio
+
+	apiWrite(w, http.StatusOK, res)
+}
+
+// outcome returns the exit-status of a program which ended with the
+// given error, and a description of the error, if any.
+func (s *server) outcome(err error) (int, *apiError) {
+	if err == nil {
+		return statusOK, nil
+	}
+
+	message, killed := s.killed(err)
+
+	status := statusFailed
+	if killed {
+		status = statusKilled
+	}
+
+	res := &apiError{Message: message}
+	var l *eval.LineError
+	if errors.As(err, &l) {
+		res.Line = l.Line
+		res.Label = l.Label
+	}
+	return status, res
+}
+
+// jsonValue converts the value of a variable into a form which may be
+// encoded as JSON.
+//
+// Arrays become lists, or lists of lists if they have two dimensions,
+// and numbers which JSON can't represent, such as infinity, become
+// strings.
+func jsonValue(val object.Object) interface{} {
+	switch v := val.(type) {
+	case *object.NumberObject:
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			return fmt.Sprint(v.Value)
+		}
+		return v.Value
+	case *object.StringObject:
+		return v.Value
+	case *object.ArrayObject:
+		if v.X == 0 {
+			row := make([]interface{}, v.Y)
+			for y := range row {
+				row[y] = jsonValue(v.Get(0, y))
+			}
+			return row
+		}
+		rows := make([]interface{}, v.X)
+		for x := range rows {
+			row := make([]interface{}, v.Y)
+			for y := range row {
+				row[y] = jsonValue(v.Get(x, y))
+			}
+			rows[x] = row
+		}
+		return rows
+	default:
+		return val.String()
+	}
+}
+
+// apiFail reports a request which couldn't be handled.
+func apiFail(w http.ResponseWriter, status int, message string) {
+	apiWrite(w, status, map[string]string{"error": message})
+}
+
+// apiWrite sends the given value as JSON.
+func apiWrite(w http.ResponseWriter, status int, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
@@ -0,0 +1,118 @@
+// api_test.go - Test-cases for running programs via /api/run.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run sends the given request to /api/run, returning the response and
+// its HTTP status.
+func run(t *testing.T, s *server, req apiRequest) (map[string]interface{}, int) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %s", err.Error())
+	}
+	r := httptest.NewRequest("POST", "/api/run", strings.NewReader(string(body)))
+
+	var res map[string]interface{}
+	w := request(t, s.apiHandler, r, &res)
+	return res, w.Code
+}
+
+// TestAPI tests the results of running programs.
+func TestAPI(t *testing.T) {
+	s := newServer()
+
+	res, code := run(t, s, apiRequest{
+		Program: `10 INPUT "", a
+20 LET b$ = "x"
+30 DIM c(2)
+40 LET c[1] = a * 2
+50 PRINT a * 2, "\n"
+`,
+		Stdin: "21\n",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status %d", code)
+	}
+
+	expected := map[string]interface{}{
+		"stdout": "42 \n",
+		"stderr": "",
+		"status": float64(statusOK),
+		"variables": map[string]interface{}{
+			"a":  float64(21),
+			"b$": "x",
+			"c":  []interface{}{float64(0), float64(42), float64(0)},
+		},
+		"images": []interface{}{},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("Unexpected response %v", res)
+	}
+
+	// Arrays of two dimensions are returned a row at a time.
+	res, _ = run(t, s, apiRequest{Program: `10 DIM a(2, 1)
+20 LET a[0, 1] = 1
+30 LET a[1, 0] = 10
+40 LET a[2, 1] = 21
+`})
+	rows := []interface{}{
+		[]interface{}{float64(0), float64(1)},
+		[]interface{}{float64(10), float64(0)},
+		[]interface{}{float64(0), float64(21)},
+	}
+	if got := res["variables"].(map[string]interface{})["a"]; !reflect.DeepEqual(got, rows) {
+		t.Errorf("Unexpected array %v", got)
+	}
+
+	// A program which fails reports where, and what it did first.
+	res, _ = run(t, s, apiRequest{Program: "10 PRINT \"a\"\n20 LET z = 1\n30 PRINT 1 / 0\n"})
+	if res["status"] != float64(statusFailed) || res["stdout"] != "a" {
+		t.Errorf("Unexpected response %v", res)
+	}
+	failure, _ := res["error"].(map[string]interface{})
+	if failure["line"] != float64(3) || failure["label"] != "30" || !strings.Contains(failure["message"].(string), "Division by zero") {
+		t.Errorf("Unexpected error %v", res["error"])
+	}
+	if res["variables"].(map[string]interface{})["z"] != float64(1) {
+		t.Errorf("Unexpected variables %v", res["variables"])
+	}
+
+	// As does a program which is stopped.
+	s.timeout = 100 * time.Millisecond
+	res, _ = run(t, s, apiRequest{Program: "10 GOTO 10\n"})
+	if res["status"] != float64(statusKilled) {
+		t.Errorf("Unexpected response %v", res)
+	}
+
+	// Images, and sounds, are returned.
+	s = newServer()
+	res, _ = run(t, s, apiRequest{Program: "10 SAVE \"a.png\"\n20 SAVE \"b.svg\"\n30 BEEP 0.1, 0\n"})
+	if images := res["images"].([]interface{}); len(images) != 2 || res["audio"] == nil {
+		t.Errorf("Unexpected response %v", res)
+	}
+}
+
+// TestAPIRequests tests that bad requests are rejected.
+func TestAPIRequests(t *testing.T) {
+	s := newServer()
+
+	var res map[string]interface{}
+	w := request(t, s.apiHandler, httptest.NewRequest("GET", "/api/run", nil), &res)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "POST" {
+		t.Errorf("Unexpected response %d %v", w.Code, res)
+	}
+
+	w = request(t, s.apiHandler, httptest.NewRequest("POST", "/api/run", strings.NewReader("{")), &res)
+	if w.Code != http.StatusBadRequest || !strings.Contains(res["error"].(string), "invalid request") {
+		t.Errorf("Unexpected response %d %v", w.Code, res)
+	}
+}
@@ -9,23 +9,32 @@
 // points, lines, circles, and view a rendered image containing the output.
 //
 // Graphing SIN and similar functions becomes very simple and natural.
+//
+// The primitives are those of the graphics package, and the images they
+// save are returned to the browser, rather than written to disk.
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
+	"io"
 	"log"
+	"mime"
 	"net/http"
-	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/skx/gobasic/builtin"
 	"github.com/skx/gobasic/eval"
-	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/graphics"
+	"github.com/skx/gobasic/sound"
 	"github.com/skx/gobasic/tokenizer"
 
 	_ "embed" // embedded-resource magic
@@ -34,402 +43,224 @@ import (
 //go:embed data/index.html
 var indexResource string
 
-// img holds the canvas we draw into.
-var img *image.RGBA
-
-// col holds our currently selected colour
-var col color.RGBA
-
-// Setup default color (black)
-func init() {
-	col = color.RGBA{0, 0, 0, 255}
-}
+// maxFrames is the most frames of animation a script may record,
+// maxShapes the most shapes it may draw, and maxSound the longest sound
+// it may make, which limit the memory it uses.
+const (
+	maxFrames = 100
+	maxShapes = 10000
+	maxSound  = time.Minute
+)
 
-// plotFunction is the golang implementation of the DOT primitive.
-//
-// It is invoked with two arguments (NUMBER NUMBER) and sets
-// the corresponding pixel in our canvas to be Red.
-func plotFunction(env builtin.Environment, args []object.Object) object.Object {
+// picture is an image written by SAVE.
+type picture struct {
 
-	var x, y float64
+	// mime is the type of the image, chosen by the name it was saved
+	// with.
+	mime string
 
-	//
-	// Get the args: X, Y
-	//
-	if args[0].Type() == object.NUMBER {
-		x = args[0].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for X")
-	}
-	if args[1].Type() == object.NUMBER {
-		y = args[1].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for Y")
-	}
+	// data holds the contents of the image.
+	data []byte
+}
 
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		c := color.RGBA{255, 255, 255, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
-	}
+// media holds the images a script has saved, and the sound it made.
+type media struct {
 
-	// Draw the dot
-	img.Set(int(x), int(y), col)
+	// images holds the images written by SAVE.
+	images []*picture
 
-	return &object.NumberObject{Value: 0.0}
+	// synth holds the sound made by BEEP, PLAY, and SOUND.
+	synth *sound.Synth
 }
 
-// saveFunction is the golang implementation of the SAVE primitive,
-// which is made available to BASIC.
+// create returns a writer which records a new image.
 //
-// We save the image-canvas to a temporary file, and set that filename
-// within the BASIC environment.
-func saveFunction(env builtin.Environment, args []object.Object) object.Object {
-
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		c := color.RGBA{255, 255, 255, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
-	}
-
-	// Generate a temporary filename
-	tmpfile, _ := os.CreateTemp("", "goserver")
-
-	// Now write out the image.
-	f, _ := os.OpenFile(tmpfile.Name(), os.O_WRONLY|os.O_CREATE, 0600)
-	defer f.Close()
-	png.Encode(f, img)
-
-	// And save the temporary filename in a variable
-
-	env.Data().(*eval.Interpreter).SetVariable("file.name", &object.StringObject{Value: tmpfile.Name()})
-
-	// Finally we can nuke the image
-	img = nil
-
-	return &object.NumberObject{Value: 0.0}
+// It is used as the Create option of the script's canvas, so that SAVE
+// never writes to the filesystem of the server.
+func (m *media) create(path string) (io.WriteCloser, error) {
+	img := &picture{mime: mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))}
+	m.images = append(m.images, img)
+	return &pictureWriter{img: img}, nil
 }
 
-// colorFunction allows the user to change the current colour.
-func colorFunction(env builtin.Environment, args []object.Object) object.Object {
-
-	var r, g, b float64
-
-	//
-	// Get the args
-	//
-	if args[0].Type() == object.NUMBER {
-		r = args[0].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for R")
+// audio returns the sound the script made as a base64-encoded WAV file,
+// or nothing if it made none.
+func (m *media) audio() (string, error) {
+	if m.synth == nil || m.synth.Duration() == 0 {
+		return "", nil
 	}
-
-	if args[1].Type() == object.NUMBER {
-		g = args[1].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for G")
+	var buf bytes.Buffer
+	if err := m.synth.WriteWAV(&buf); err != nil {
+		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
 
-	if args[2].Type() == object.NUMBER {
-		b = args[2].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for B")
-	}
+// pictureWriter receives the contents of an image.
+type pictureWriter struct {
+	img *picture
+	buf bytes.Buffer
+}
 
-	// Update the colour.
-	col = color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+// Write records part of the image.
+func (w *pictureWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
 
-	return &object.NumberObject{Value: 0.0}
+// Close stores the completed image.
+func (w *pictureWriter) Close() error {
+	w.img.data = w.buf.Bytes()
+	return nil
 }
 
-// circleFunction allows drawing a circle upon our image.
-func circleFunction(env builtin.Environment, args []object.Object) object.Object {
+// server holds the limits applied to the scripts we run.
+type server struct {
 
-	var xx, yy, rr float64
+	// timeout is the time a script may run for.
+	timeout time.Duration
 
-	//
-	// Get the args
-	//
-	if args[0].Type() == object.NUMBER {
-		xx = args[0].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for X")
-	}
+	// steps is the number of statements a script may execute, or
+	// zero for no limit.
+	steps int
 
-	if args[1].Type() == object.NUMBER {
-		yy = args[1].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for Y")
-	}
+	// turtle is true if scripts may steer the turtle, in which case
+	// they can't use its primitives' names, such as LEFT, as
+	// variables.
+	turtle bool
 
-	if args[2].Type() == object.NUMBER {
-		rr = args[2].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for R")
-	}
+	// idle is the time an interactive session may go unused before
+	// it is stopped, and forgotten.
+	idle time.Duration
 
-	//
-	// They need to be ints.
-	//
-	x0 := int(xx)
-	y0 := int(yy)
-	r := int(rr)
-
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		c := color.RGBA{255, 255, 255, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
-	}
+	// maxSessions is the number of interactive sessions which may run
+	// at once.
+	maxSessions int
 
-	// Now circle-magic happens.
-	x, y, dx, dy := r-1, 0, 1, 1
-	err := dx - (int(r) * 2)
-
-	for x > y {
-		img.Set(x0+x, y0+y, col)
-		img.Set(x0+y, y0+x, col)
-		img.Set(x0-y, y0+x, col)
-		img.Set(x0-x, y0+y, col)
-		img.Set(x0-x, y0-y, col)
-		img.Set(x0-y, y0-x, col)
-		img.Set(x0+y, y0-x, col)
-		img.Set(x0+x, y0-y, col)
-
-		if err <= 0 {
-			y++
-			err += dy
-			dy += 2
-		}
-		if err > 0 {
-			x--
-			dx += 2
-			err += dx - (r * 2)
-		}
-	}
+	// lock protects sessions.
+	lock sync.Mutex
 
-	// All done.
-	return &object.NumberObject{Value: 0.0}
+	// sessions holds the interactive sessions, by ID.
+	sessions map[string]*session
 }
 
-// lineFunction draws a line.
-func lineFunction(env builtin.Environment, args []object.Object) object.Object {
+// execution holds the outcome of running a script.
+type execution struct {
 
-	var xx1, yy1, xx2, yy2 float64
+	// e is the interpreter which ran the script, or nil if the
+	// script couldn't be parsed.
+	e *eval.Interpreter
 
-	if args[0].Type() == object.NUMBER {
-		xx1 = args[0].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for X1")
-	}
-	if args[1].Type() == object.NUMBER {
-		yy1 = args[1].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for Y1")
-	}
-	if args[2].Type() == object.NUMBER {
-		xx2 = args[2].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for X2")
-	}
-	if args[3].Type() == object.NUMBER {
-		yy2 = args[3].(*object.NumberObject).Value
-	} else {
-		return object.Error("Wrong type for Y2")
-	}
+	// media holds the images, and the sound, the script made.
+	media *media
 
-	//
-	// They need to be ints.
-	//
-	x1 := int(xx1)
-	x2 := int(xx2)
-	y1 := int(yy1)
-	y2 := int(yy2)
-
-	// If we have no image, create it.
-	if img == nil {
-		img = image.NewRGBA(image.Rect(0, 0, 600, 400))
-		c := color.RGBA{255, 255, 255, 255}
-		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
-	}
+	// stdout and stderr hold the output of the script.
+	stdout bytes.Buffer
+	stderr bytes.Buffer
 
-	var dx, dy, e, slope int
+	// err holds the error which ended the script, if any.
+	err error
+}
 
-	// Because drawing p1 -> p2 is equivalent to draw p2 -> p1,
-	// I sort points in x-axis order to handle only half of possible cases.
-	if x1 > x2 {
-		x1, y1, x2, y2 = x2, y2, x1, y1
+// interpreter creates an interpreter for the given script, with its own
+// canvas and synthesiser, and the primitives which use them.
+func (s *server) interpreter(ctx context.Context, code string) (*eval.Interpreter, *media, error) {
+	e, err := eval.NewWithContext(ctx, tokenizer.New(code))
+	if err != nil {
+		return nil, nil, err
 	}
+	e.SetStepLimit(s.steps)
+
+	m := &media{}
+	graphics.Register(e, graphics.Options{
+		Width:     600,
+		Height:    400,
+		MaxFrames: maxFrames,
+		MaxShapes: maxShapes,
+		Turtle:    s.turtle,
+		Create:    m.create,
+	})
+	m.synth = sound.Register(e, sound.Options{MaxDuration: maxSound})
+
+	return e, m, nil
+}
 
-	dx, dy = x2-x1, y2-y1
-	// Because point is x-axis ordered, dx cannot be negative
-	if dy < 0 {
-		dy = -dy
+// execute runs the given script, with the given input.
+//
+// The script is stopped if it runs for too long, or if the request is
+// abandoned.
+func (s *server) execute(ctx context.Context, code string, stdin string) *execution {
+	x := &execution{media: &media{}}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	e, m, err := s.interpreter(ctx, code)
+	if err != nil {
+		x.err = err
+		return x
 	}
+	x.e = e
+	x.media = m
 
-	switch {
+	e.STDIN = bufio.NewReader(strings.NewReader(stdin))
+	e.STDOUT = bufio.NewWriter(&x.stdout)
+	e.STDERR = bufio.NewWriter(&x.stderr)
 
-	// Is line a point ?
-	case x1 == x2 && y1 == y2:
-		img.Set(x1, y1, col)
+	x.err = e.Run()
 
-	// Is line an horizontal ?
-	case y1 == y2:
-		for ; dx != 0; dx-- {
-			img.Set(x1, y1, col)
-			x1++
-		}
-		img.Set(x1, y1, col)
+	e.STDOUT.Flush()
+	e.STDERR.Flush()
 
-	// Is line a vertical ?
-	case x1 == x2:
-		if y1 > y2 {
-			y1 = y2
-			//, y2 = y2, y1
-		}
-		for ; dy != 0; dy-- {
-			img.Set(x1, y1, col)
-			y1++
-		}
-		img.Set(x1, y1, col)
-
-	// Is line a diagonal ?
-	case dx == dy:
-		if y1 < y2 {
-			for ; dx != 0; dx-- {
-				img.Set(x1, y1, col)
-				x1++
-				y1++
-			}
-		} else {
-			for ; dx != 0; dx-- {
-				img.Set(x1, y1, col)
-				x1++
-				y1--
-			}
-		}
-		img.Set(x1, y1, col)
-
-	// wider than high ?
-	case dx > dy:
-		if y1 < y2 {
-			// BresenhamDxXRYD(img, x1, y1, x2, y2, col)
-			dy, e, slope = 2*dy, dx, 2*dx
-			for ; dx != 0; dx-- {
-				img.Set(x1, y1, col)
-				x1++
-				e -= dy
-				if e < 0 {
-					y1++
-					e += slope
-				}
-			}
-		} else {
-			// BresenhamDxXRYU(img, x1, y1, x2, y2, col)
-			dy, e, slope = 2*dy, dx, 2*dx
-			for ; dx != 0; dx-- {
-				img.Set(x1, y1, col)
-				x1++
-				e -= dy
-				if e < 0 {
-					y1--
-					e += slope
-				}
-			}
-		}
-		img.Set(x2, y2, col)
+	return x
+}
 
-	// higher than wide.
+// killed returns a description of the error which ended a script, and
+// whether the script was stopped because it ran for too long.
+func (s *server) killed(err error) (string, bool) {
+	switch {
+	case err == nil:
+		return "", false
+	case errors.Is(err, eval.ErrTimeout):
+		return fmt.Sprintf("your script was stopped after running for %s", s.timeout), true
+	case errors.Is(err, eval.ErrStepLimit):
+		return fmt.Sprintf("your script was stopped after running %d statements", s.steps), true
 	default:
-		if y1 < y2 {
-			// BresenhamDyXRYD(img, x1, y1, x2, y2, col)
-			dx, e, slope = 2*dx, dy, 2*dy
-			for ; dy != 0; dy-- {
-				img.Set(x1, y1, col)
-				y1++
-				e -= dx
-				if e < 0 {
-					x1++
-					e += slope
-				}
-			}
-		} else {
-			// BresenhamDyXRYU(img, x1, y1, x2, y2, col)
-			dx, e, slope = 2*dx, dy, 2*dy
-			for ; dy != 0; dy-- {
-				img.Set(x1, y1, col)
-				y1--
-				e -= dx
-				if e < 0 {
-					x1++
-					e += slope
-				}
-			}
-		}
-		img.Set(x2, y2, col)
+		return err.Error(), false
 	}
-	// All done.
-	return &object.NumberObject{Value: 0.0}
 }
 
 // Runs the script the user submitted.
 //
-// Returns the base64-encoded version of the output image.
-//
-// More reliable than it has any reason to be.
-func runScript(code string) (string, error) {
-	t := tokenizer.New(code)
-
-	e, err := eval.New(t)
-	if err != nil {
-		return "", err
-	}
-	e.RegisterBuiltin("CIRCLE", 3, circleFunction)
-	e.RegisterBuiltin("COLOR", 3, colorFunction)
-	e.RegisterBuiltin("COLOUR", 3, colorFunction)
-	e.RegisterBuiltin("LINE", 4, lineFunction)
-	e.RegisterBuiltin("PLOT", 2, plotFunction)
-	e.RegisterBuiltin("SAVE", 0, saveFunction)
-
-	err = e.Run()
-	if err != nil {
-		return "", err
+// Returns the final image it saved, if any, and the base64-encoded WAV
+// of any sound it made.
+func (s *server) runScript(ctx context.Context, code string) (*picture, string, error) {
+	x := s.execute(ctx, code, "")
+	if x.err != nil {
+		return nil, "", x.err
 	}
 
-	// Get the name of the file the SAVE function wrote to
-	pathObj := e.GetVariable("file.name")
-	if pathObj == nil {
-		return "", fmt.Errorf("your script did not include a 'SAVE' statement")
-	}
-	if pathObj.Type() == object.ERROR {
-		return "", fmt.Errorf("your script did not include a 'SAVE' statement: %s", pathObj.(*object.ErrorObject).Value)
-	}
-	path := pathObj.(*object.StringObject).Value
-
-	// Read the file
-	b, err := os.ReadFile(path)
+	audio, err := x.media.audio()
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
-	// BASE64 encode it.
-	encoded := base64.StdEncoding.EncodeToString(b)
-
-	// remove the temporary file
-	os.Remove(path)
-
-	// And return the value.
-	return encoded, nil
+	images := x.media.images
+	if len(images) == 0 {
+		if audio != "" {
+			return nil, audio, nil
+		}
+		return nil, "", fmt.Errorf("your script did not include a 'SAVE' statement")
+	}
+	return images[len(images)-1], audio, nil
 }
 
 // Called via a HTTP-request.
 //
 // If GET serve `index.html`.
 //
-// If POST serve a PNG created by executing the user-submitted code.
-func handler(w http.ResponseWriter, r *http.Request) {
+// If POST serve the image, and any sound, created by executing the
+// user-submitted code.
+func (s *server) handler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.Error(w, "404 not found.", http.StatusNotFound)
 		return
@@ -445,26 +276,37 @@ func handler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		code := r.FormValue("code")
-		out, err := runScript(code)
+		img, audio, err := s.runScript(r.Context(), code)
 
 		// Encode as JSON
 		type Result struct {
 			Result string
 			Error  string
+
+			// Type is the type of the image in Result.
+			Type string
+
+			// Audio holds the base64-encoded WAV of any sound.
+			Audio string
+
+			// Killed is true if the script was stopped because
+			// it ran for too long.
+			Killed bool
 		}
 
 		//
 		// The error, if any, as a string,
 		//
-		error := ""
-		if err != nil {
-			error = err.Error()
-		}
+		error, killed := s.killed(err)
 
 		//
 		// Create the result-object and JSON-encode.
 		//
-		res := &Result{Result: out, Error: error}
+		res := &Result{Audio: audio, Error: error, Killed: killed}
+		if img != nil {
+			res.Result = base64.StdEncoding.EncodeToString(img.data)
+			res.Type = img.mime
+		}
 		js, err := json.Marshal(res)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -483,10 +325,26 @@ func handler(w http.ResponseWriter, r *http.Request) {
 // Entry-point.
 func main() {
 
+	s := &server{sessions: make(map[string]*session)}
+	flag.DurationVar(&s.timeout, "timeout", 5*time.Second, "The time each script may run for.")
+	flag.IntVar(&s.steps, "steps", 10000000, "The number of statements each script may execute, or zero for no limit.")
+	flag.BoolVar(&s.turtle, "turtle", true, "Allow scripts to steer the turtle, whose primitives' names they then can't use as variables.")
+	flag.DurationVar(&s.idle, "idle", 5*time.Minute, "The time an interactive session may be idle before it is stopped.")
+	flag.IntVar(&s.maxSessions, "sessions", 100, "The number of interactive sessions which may run at once.")
+	flag.Parse()
+
+	//
+	// Stop the sessions which have been abandoned.
+	//
+	go s.expire()
+
 	//
 	// We'll bind a handler.
 	//
-	http.HandleFunc("/", handler)
+	http.HandleFunc("/", s.handler)
+	http.HandleFunc("/api/run", s.apiHandler)
+	http.HandleFunc("/api/session", s.sessionHandler)
+	http.HandleFunc("/api/session/", s.sessionHandler)
 
 	fmt.Printf("Listening on http://localhost:8080/\n")
 
@@ -0,0 +1,180 @@
+// main_test.go - Test-cases for running scripts from the browser.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newServer returns a server with generous limits, which tests lower as
+// they need to.
+func newServer() *server {
+	return &server{
+		timeout:     5 * time.Second,
+		turtle:      true,
+		idle:        time.Minute,
+		maxSessions: 10,
+		sessions:    make(map[string]*session),
+	}
+}
+
+// request sends the given request to the handler, and decodes the JSON
+// it responds with.  It may be called from any goroutine.
+func request(t *testing.T, handler http.HandlerFunc, r *http.Request, out interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Errorf("Invalid response %q: %s", w.Body.String(), err.Error())
+		}
+	}
+	return w
+}
+
+// pageResult is the response to a script submitted from the page.
+type pageResult struct {
+	Result string
+	Error  string
+	Type   string
+	Audio  string
+	Killed bool
+}
+
+// submit runs the given script as the page does.
+func submit(t *testing.T, s *server, code string) pageResult {
+	form := url.Values{"code": {code}}.Encode()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var res pageResult
+	request(t, s.handler, r, &res)
+	return res
+}
+
+// TestKilled tests that scripts which run for too long are stopped.
+func TestKilled(t *testing.T) {
+	s := newServer()
+	s.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	res := submit(t, s, "10 GOTO 10\n")
+	if !res.Killed || !strings.Contains(res.Error, "stopped after running for 100ms") {
+		t.Errorf("Unexpected result %v", res)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("The script ran for %s", d)
+	}
+
+	s = newServer()
+	s.steps = 1000
+	res = submit(t, s, "10 GOTO 10\n")
+	if !res.Killed || !strings.Contains(res.Error, "stopped after running 1000 statements") {
+		t.Errorf("Unexpected result %v", res)
+	}
+
+	// Scripts which fail are reported, but weren't killed.
+	res = submit(t, s, "10 PRINT 1 / 0\n")
+	if res.Killed || !strings.Contains(res.Error, "Division by zero") {
+		t.Errorf("Unexpected result %v", res)
+	}
+
+	// As are those which draw too much, long before they're killed.
+	s = newServer()
+	start = time.Now()
+	res = submit(t, s, "10 PLOT 1, 1\n20 GOTO 10\n")
+	if res.Killed || !strings.Contains(res.Error, "at most 10000 shapes may be drawn") {
+		t.Errorf("Unexpected result %v", res)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("The script ran for %s", d)
+	}
+}
+
+// TestCanvases tests that scripts which run at the same time are each
+// given a canvas of their own.
+func TestCanvases(t *testing.T) {
+	s := newServer()
+
+	// Each script waits, so that both are drawing at once.
+	colours := []int{2, 4}
+	results := make([]pageResult, len(colours))
+	var wg sync.WaitGroup
+	for i, c := range colours {
+		wg.Add(1)
+		go func(i, c int) {
+			defer wg.Done()
+			results[i] = submit(t, s, strings.Replace(`10 INK c
+20 BOX 0, 0, 599, 399
+30 SLEEP 0.2
+40 SAVE
+`, "c", string(rune('0'+c)), 1))
+		}(i, c)
+	}
+	wg.Wait()
+
+	var first []byte
+	for i, res := range results {
+		if res.Error != "" || res.Type != "image/png" {
+			t.Fatalf("Unexpected result %v", res)
+		}
+		data, err := base64.StdEncoding.DecodeString(res.Result)
+		if err != nil {
+			t.Fatalf("Invalid image: %s", err.Error())
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Invalid image: %s", err.Error())
+		}
+
+		// Red and green are 2 and 4.
+		r, g, _, _ := img.At(300, 200).RGBA()
+		if (colours[i] == 2 && (r == 0 || g != 0)) || (colours[i] == 4 && (r != 0 || g == 0)) {
+			t.Errorf("Image %d has the wrong colour %d/%d", i, r, g)
+		}
+		if first != nil && bytes.Equal(first, data) {
+			t.Errorf("Both scripts returned the same image")
+		}
+		first = data
+	}
+
+	// A script which saves nothing is told so.
+	res := submit(t, s, "10 PRINT 1\n")
+	if !strings.Contains(res.Error, "did not include a 'SAVE'") {
+		t.Errorf("Unexpected result %v", res)
+	}
+}
+
+// TestTurtle tests that scripts may steer the turtle, and that its
+// primitives' names are then keywords, unless it's turned off.
+func TestTurtle(t *testing.T) {
+	s := newServer()
+
+	res := submit(t, s, "10 FORWARD 100 : SAVE\n")
+	if res.Error != "" || res.Type != "image/png" {
+		t.Errorf("Unexpected result %v", res)
+	}
+	res = submit(t, s, "10 LET left = 1\n20 SAVE\n")
+	if !strings.Contains(res.Error, "expected IDENT after LET") {
+		t.Errorf("Unexpected result %v", res)
+	}
+
+	s.turtle = false
+	res = submit(t, s, "10 LET left = 1\n20 LET home = left + 1\n30 SAVE\n")
+	if res.Error != "" || res.Type != "image/png" {
+		t.Errorf("Unexpected result %v", res)
+	}
+	res = submit(t, s, "10 FORWARD 100\n20 SAVE\n")
+	if res.Error == "" {
+		t.Errorf("Unexpected result %v", res)
+	}
+}
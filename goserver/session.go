@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skx/gobasic/eval"
+)
+
+// maxOutput is the most output a session may produce, after which it is
+// stopped.  Nobody is going to read more than this in their browser.
+const maxOutput = 1024 * 1024
+
+// errTooManySessions is returned by start when no more sessions may be
+// run until some of those which are running have finished.
+var errTooManySessions = errors.New("too many programs are running, please try again later")
+
+// session is a program which is run interactively, reading its input
+// from the requests of the user as it needs it.
+type session struct {
+
+	// id identifies the session.
+	id string
+
+	// cancel stops the program.
+	cancel context.CancelFunc
+
+	// timer stops the program once it has run for too long.  It is
+	// paused while the program waits for input, so spent holds the
+	// time it ran for before it last waited, and resumed the time
+	// it last stopped waiting.
+	timer   *time.Timer
+	limit   time.Duration
+	spent   time.Duration
+	resumed time.Time
+
+	// lock protects the fields which follow.
+	lock sync.Mutex
+
+	// changed is closed, and replaced, whenever the state of the
+	// session changes, to wake those waiting for it.
+	changed chan struct{}
+
+	// output holds everything the program has written.
+	output []byte
+
+	// input holds the input which the program hasn't read yet.
+	input []byte
+
+	// waiting is true while the program is waiting for input.
+	waiting bool
+
+	// done is true once the program has finished, and err holds the
+	// error which ended it, if any.  We may set err before the program
+	// has finished, if we stopped it.
+	done bool
+	err  error
+
+	// used is the time the session was last used.
+	used time.Time
+}
+
+// notify wakes everybody waiting for the session to change.
+//
+// The caller must hold the lock.
+func (s *session) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// pause stops the timer while the program waits for input.
+//
+// The caller must hold the lock.
+func (s *session) pause() {
+	if s.timer.Stop() {
+		s.spent += time.Since(s.resumed)
+	}
+}
+
+// resume restarts the timer once the program has its input.
+//
+// The caller must hold the lock.
+func (s *session) resume() {
+	s.resumed = time.Now()
+	s.timer.Reset(s.limit - s.spent)
+}
+
+// expired stops the program when its time is up.
+func (s *session) expired() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err == nil && !s.done {
+		s.err = eval.ErrTimeout
+	}
+	s.cancel()
+}
+
+// sessionOutput receives the output of a program.
+type sessionOutput struct {
+	s *session
+}
+
+// Write records the output, so it can be sent to the user.
+func (o *sessionOutput) Write(p []byte) (int, error) {
+	s := o.s
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.output)+len(p) > maxOutput {
+		if s.err == nil {
+			s.err = fmt.Errorf("your script produced more than %d bytes of output", maxOutput)
+		}
+		s.cancel()
+		return 0, s.err
+	}
+	s.output = append(s.output, p...)
+	s.notify()
+	return len(p), nil
+}
+
+// sessionInput supplies the input of a program.
+type sessionInput struct {
+	s   *session
+	ctx context.Context
+}
+
+// Read returns the input the user has sent, waiting for them to send
+// some if there is none.  The time spent waiting doesn't count towards
+// the time the program may run for.
+func (i *sessionInput) Read(p []byte) (int, error) {
+	s := i.s
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.input) == 0 {
+		s.pause()
+		defer s.resume()
+	}
+
+	for len(s.input) == 0 {
+		if !s.waiting {
+			s.waiting = true
+			s.notify()
+		}
+
+		changed := s.changed
+		s.lock.Unlock()
+		select {
+		case <-changed:
+		case <-i.ctx.Done():
+			s.lock.Lock()
+			s.waiting = false
+			return 0, io.EOF
+		}
+		s.lock.Lock()
+	}
+
+	s.waiting = false
+	n := copy(p, s.input)
+	s.input = s.input[n:]
+	return n, nil
+}
+
+// sessionState is the response to the requests upon a session.
+type sessionState struct {
+
+	// ID identifies the session.
+	ID string `json:"id"`
+
+	// Output holds the output which followed the offset given in the
+	// request, and Offset is the offset which follows it.
+	Output string `json:"output"`
+	Offset int    `json:"offset"`
+
+	// Waiting is true if the program is waiting for input.
+	Waiting bool `json:"waiting"`
+
+	// Done is true once the program has finished, at which point
+	// Status, and Error, describe how it ended, as for /api/run.
+	Done   bool      `json:"done"`
+	Status int       `json:"status"`
+	Error  *apiError `json:"error,omitempty"`
+}
+
+// running returns the number of sessions whose programs haven't finished.
+//
+// The caller must hold the lock.
+func (s *server) running() int {
+	n := 0
+	for _, ss := range s.sessions {
+		ss.lock.Lock()
+		if !ss.done {
+			n++
+		}
+		ss.lock.Unlock()
+	}
+	return n
+}
+
+// start launches the given program in a new session.
+//
+// The program may run for as long as other scripts, not counting the
+// time it spends waiting for input, and only so many may run at once.
+func (s *server) start(code string) (*session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e, _, err := s.interpreter(ctx, code)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ss := &session{
+		id:      hex.EncodeToString(id),
+		cancel:  cancel,
+		changed: make(chan struct{}),
+		used:    time.Now(),
+		limit:   s.timeout,
+		resumed: time.Now(),
+	}
+	e.STDIN = bufio.NewReader(&sessionInput{s: ss, ctx: ctx})
+	e.STDOUT = bufio.NewWriter(&sessionOutput{s: ss})
+	e.STDERR = bufio.NewWriter(&sessionOutput{s: ss})
+
+	s.lock.Lock()
+	if s.running() >= s.maxSessions {
+		s.lock.Unlock()
+		cancel()
+		return nil, errTooManySessions
+	}
+	ss.timer = time.AfterFunc(s.timeout, ss.expired)
+	s.sessions[ss.id] = ss
+	s.lock.Unlock()
+
+	go func() {
+		err := e.Run()
+		e.STDOUT.Flush()
+		e.STDERR.Flush()
+
+		ss.lock.Lock()
+		ss.timer.Stop()
+		ss.done = true
+		ss.waiting = false
+		if ss.err == nil {
+			ss.err = err
+		}
+		ss.notify()
+		ss.lock.Unlock()
+
+		cancel()
+	}()
+
+	return ss, nil
+}
+
+// state returns the state of the session, with the output following the
+// given offset.
+//
+// If there is no new output, and the program hasn't finished, we wait
+// for up to the given time for that to change.
+func (s *server) state(ss *session, offset int, wait time.Duration) *sessionState {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if offset < 0 || offset > len(ss.output) {
+		offset = len(ss.output)
+	}
+
+	expired := false
+	for offset == len(ss.output) && !ss.done && !expired {
+		changed := ss.changed
+		ss.lock.Unlock()
+		select {
+		case <-changed:
+		case <-timer.C:
+			expired = true
+		}
+		ss.lock.Lock()
+
+		// Waiting for input is worth reporting, even without
+		// any output before it.
+		if ss.waiting {
+			break
+		}
+	}
+	ss.used = time.Now()
+
+	res := &sessionState{
+		ID:      ss.id,
+		Output:  string(ss.output[offset:]),
+		Offset:  len(ss.output),
+		Waiting: ss.waiting,
+		Done:    ss.done,
+	}
+	if ss.done {
+		res.Status, res.Error = s.outcome(ss.err)
+	}
+	return res
+}
+
+// expire regularly stops the sessions which haven't been used for a
+// while, which is how those the user has abandoned go away.
+func (s *server) expire() {
+	for range time.Tick(s.idle / 4) {
+		s.expireIdle()
+	}
+}
+
+// expireIdle stops, and forgets, the sessions which haven't been used
+// for longer than we allow.
+func (s *server) expireIdle() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, ss := range s.sessions {
+		ss.lock.Lock()
+		idle := time.Since(ss.used) > s.idle
+		ss.lock.Unlock()
+
+		if idle {
+			ss.cancel()
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// sessionHandler handles the requests which run programs interactively:
+//
+//	POST   /api/session           - start the program in the JSON body.
+//	GET    /api/session/ID?offset=N - return the output following N.
+//	POST   /api/session/ID/input  - send a line of input.
+//	DELETE /api/session/ID        - stop the program.
+//
+// Each responds with the state of the session.
+func (s *server) sessionHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/session"), "/")
+
+	if path == "" {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			apiFail(w, http.StatusMethodNotAllowed, "only POST requests are supported")
+			return
+		}
+
+		var req apiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apiFail(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err.Error()))
+			return
+		}
+
+		ss, err := s.start(req.Program)
+		if err == errTooManySessions {
+			apiFail(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if err != nil {
+			status, res := s.outcome(err)
+			apiWrite(w, http.StatusOK, &sessionState{Done: true, Status: status, Error: res})
+			return
+		}
+
+		// Return promptly, with whatever it has written so far.
+		apiWrite(w, http.StatusOK, s.state(ss, 0, 100*time.Millisecond))
+		return
+	}
+
+	id, action := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		id, action = path[:i], path[i+1:]
+	}
+
+	s.lock.Lock()
+	ss := s.sessions[id]
+	s.lock.Unlock()
+	if ss == nil {
+		apiFail(w, http.StatusNotFound, "the session does not exist, or has expired")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		apiWrite(w, http.StatusOK, s.state(ss, offset, 10*time.Second))
+
+	case action == "" && r.Method == "DELETE":
+		ss.cancel()
+		s.lock.Lock()
+		delete(s.sessions, id)
+		s.lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+
+	case action == "input" && r.Method == "POST":
+		var req struct {
+			Line string `json:"line"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apiFail(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err.Error()))
+			return
+		}
+
+		ss.lock.Lock()
+		done := ss.done
+		if !done {
+			ss.input = append(ss.input, strings.TrimRight(req.Line, "\r\n")+"\n"...)
+			ss.waiting = false
+			ss.notify()
+		}
+		ss.lock.Unlock()
+
+		if done {
+			apiFail(w, http.StatusConflict, "the program has finished")
+			return
+		}
+		apiWrite(w, http.StatusOK, s.state(ss, -1, 0))
+
+	default:
+		apiFail(w, http.StatusNotFound, "404 not found.")
+	}
+}
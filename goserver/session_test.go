@@ -0,0 +1,201 @@
+// session_test.go - Test-cases for running programs interactively.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decode returns the state of a session which the handler responded
+// with, or nothing if it failed.
+func decode(t *testing.T, w *httptest.ResponseRecorder) (sessionState, int) {
+	var res sessionState
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Errorf("Invalid response %q: %s", w.Body.String(), err.Error())
+		}
+	}
+	return res, w.Code
+}
+
+// start starts the given program in a new session.
+func start(t *testing.T, s *server, code string) (sessionState, int) {
+	body := fmt.Sprintf(`{"program": %q}`, code)
+	r := httptest.NewRequest("POST", "/api/session", strings.NewReader(body))
+
+	return decode(t, request(t, s.sessionHandler, r, nil))
+}
+
+// poll returns the state of a session, with the output which follows the
+// given offset.
+func poll(t *testing.T, s *server, id string, offset int) (sessionState, int) {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s?offset=%d", id, offset), nil)
+
+	return decode(t, request(t, s.sessionHandler, r, nil))
+}
+
+// finish polls a session until its program has finished, returning its
+// state, and all the output which followed the given offset.
+func finish(t *testing.T, s *server, id string, offset int) (sessionState, string) {
+	output := ""
+	for i := 0; i < 10; i++ {
+		res, code := poll(t, s, id, offset)
+		if code != http.StatusOK {
+			t.Fatalf("Unexpected status %d", code)
+		}
+		output += res.Output
+		offset = res.Offset
+		if res.Done {
+			return res, output
+		}
+	}
+	t.Fatalf("The program didn't finish")
+	return sessionState{}, ""
+}
+
+// send sends a line of input to a session.
+func send(t *testing.T, s *server, id string, line string) (sessionState, int) {
+	body := fmt.Sprintf(`{"line": %q}`, line)
+	r := httptest.NewRequest("POST", "/api/session/"+id+"/input", strings.NewReader(body))
+
+	return decode(t, request(t, s.sessionHandler, r, nil))
+}
+
+// TestSession tests the life of a session, from start to finish.
+func TestSession(t *testing.T) {
+	s := newServer()
+
+	res, code := start(t, s, `10 INPUT "name? ", n$
+20 PRINT "hi " + n$ + "\n"
+30 PRINT 1 / 0
+`)
+	if code != http.StatusOK || res.ID == "" {
+		t.Fatalf("Unexpected response %d %v", code, res)
+	}
+	if !res.Waiting || res.Done || res.Output != "name? " || res.Offset != 6 {
+		t.Fatalf("Unexpected state %v", res)
+	}
+	id := res.ID
+
+	// Until there's more output, those polling wait for it.
+	s.lock.Lock()
+	ss := s.sessions[id]
+	s.lock.Unlock()
+	if res := s.state(ss, 6, 50*time.Millisecond); !res.Waiting || res.Output != "" || res.Offset != 6 {
+		t.Errorf("Unexpected state %v", res)
+	}
+
+	if _, code = send(t, s, id, "bob\r\n"); code != http.StatusOK {
+		t.Fatalf("Unexpected status %d", code)
+	}
+	res, output := finish(t, s, id, 6)
+	if output != "hi bob\n" || res.Waiting {
+		t.Errorf("Unexpected state %v, output %q", res, output)
+	}
+	if res.Status != statusFailed || res.Error == nil || res.Error.Line != 3 {
+		t.Errorf("Unexpected outcome %v %v", res, res.Error)
+	}
+
+	// Once it has finished it wants no more input.
+	if _, code = send(t, s, id, "more"); code != http.StatusConflict {
+		t.Errorf("Unexpected status %d", code)
+	}
+
+	// Until it's deleted it may still be read.
+	w := request(t, s.sessionHandler, httptest.NewRequest("DELETE", "/api/session/"+id, nil), nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Unexpected status %d", w.Code)
+	}
+	if _, code = poll(t, s, id, 0); code != http.StatusNotFound {
+		t.Errorf("Unexpected status %d", code)
+	}
+
+	// Programs which can't be parsed are finished before they start.
+	res, code = start(t, s, "10 PRINT \"a\n")
+	if code != http.StatusOK || !res.Done || res.Status != statusFailed || res.ID != "" {
+		t.Errorf("Unexpected response %d %v", code, res)
+	}
+}
+
+// TestSessionIdle tests that sessions which aren't used are stopped, and
+// forgotten.
+func TestSessionIdle(t *testing.T) {
+	s := newServer()
+
+	res, _ := start(t, s, "10 INPUT \"> \", a\n")
+	if !res.Waiting {
+		t.Fatalf("Unexpected state %v", res)
+	}
+	s.lock.Lock()
+	ss := s.sessions[res.ID]
+	s.lock.Unlock()
+
+	// A session which was used recently is kept.
+	s.expireIdle()
+	if _, code := poll(t, s, res.ID, 0); code != http.StatusOK {
+		t.Errorf("Unexpected status %d", code)
+	}
+
+	s.idle = 10 * time.Millisecond
+	time.Sleep(20 * time.Millisecond)
+	s.expireIdle()
+	if _, code := poll(t, s, res.ID, 0); code != http.StatusNotFound {
+		t.Errorf("Unexpected status %d", code)
+	}
+
+	// The program was stopped, once it noticed.
+	for i := 0; ; i++ {
+		if s.state(ss, -1, 10*time.Millisecond).Done {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("The program wasn't stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestSessionLimits tests that sessions are limited in number, and in the
+// time they spend running, but not in the time they spend waiting.
+func TestSessionLimits(t *testing.T) {
+	s := newServer()
+	s.maxSessions = 1
+	s.timeout = 200 * time.Millisecond
+
+	res, code := start(t, s, "10 INPUT \"> \", a\n20 GOTO 20\n")
+	if code != http.StatusOK || !res.Waiting {
+		t.Fatalf("Unexpected response %d %v", code, res)
+	}
+	id := res.ID
+
+	if _, code = start(t, s, "10 PRINT 1\n"); code != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected status %d", code)
+	}
+
+	// Waiting for input takes longer than the program may run for.
+	time.Sleep(300 * time.Millisecond)
+	if res, _ = poll(t, s, id, 0); res.Done {
+		t.Fatalf("The program was stopped while waiting %v", res)
+	}
+
+	started := time.Now()
+	send(t, s, id, "1")
+	res, _ = finish(t, s, id, res.Offset)
+	if res.Status != statusKilled || !strings.Contains(res.Error.Message, "stopped after running for 200ms") {
+		t.Errorf("Unexpected outcome %v %v", res, res.Error)
+	}
+	if d := time.Since(started); d > 2*time.Second {
+		t.Errorf("The program ran for %s", d)
+	}
+
+	// Once it has finished another may start.
+	if _, code = start(t, s, "10 PRINT 1\n"); code != http.StatusOK {
+		t.Errorf("Unexpected status %d", code)
+	}
+}
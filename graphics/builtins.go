@@ -0,0 +1,293 @@
+package graphics
+
+import (
+	"image/color"
+
+	"github.com/skx/gobasic/builtin"
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+)
+
+// Register creates a canvas with the given options, and makes the
+// primitives which draw upon it available to the given interpreter.
+func Register(e *eval.Interpreter, opts Options) *Canvas {
+	c := New(opts)
+	c.Register(e)
+	return c
+}
+
+// Register makes the primitives which draw upon the canvas available to
+// the given interpreter:
+//
+//	PLOT x, y               Set a pixel.
+//	DRAW dx, dy             Draw a line from the last point, by the distance.
+//	LINE x1, y1, x2, y2     Draw a line.
+//	CIRCLE x, y, r          Draw a circle.
+//	BOX x1, y1, x2, y2      Fill a rectangle; RECT is the same.
+//	FILL x, y               Flood the area around the point.
+//	TEXT x, y, s$           Write some text.
+//	INK n                   Choose one of the eight colours to draw with,
+//	INK r, g, b             or any other; COLOR and COLOUR are the same.
+//	PAPER n                 Choose the colour behind text.
+//	PAPER r, g, b
+//	FRAME [delay]           Record a frame of an animation.
+//	SAVE ["file.png"]       Save the image as a PNG, SVG, or GIF.
+//
+// If Options.Turtle is set the turtle is steered by:
+//
+//	FORWARD n, BACK n       Move, drawing unless the pen is up.
+//	LEFT n, RIGHT n         Turn by a number of degrees.
+//	SETHEADING n            Face a direction, in degrees clockwise from up.
+//	PENUP, PENDOWN          Lift, or lower, the pen.
+//	HOME                    Return to the centre, facing up.
+//	PENCOLOR n              The same as INK; PENCOLOUR is too.
+//
+// The top-left corner of the canvas is at 0, 0, and the numbers given
+// to each primitive must be between -1000000 and 1000000.  Those which
+// draw fail once the canvas has as many shapes as Options.MaxShapes.
+func (c *Canvas) Register(e *eval.Interpreter) {
+	e.RegisterBuiltin("BOX", 4, c.boxFunction)
+	e.RegisterBuiltin("CIRCLE", 3, c.circleFunction)
+	e.RegisterBuiltin("COLOR", -1, c.inkFunction)
+	e.RegisterBuiltin("COLOUR", -1, c.inkFunction)
+	e.RegisterBuiltin("DRAW", 2, c.drawFunction)
+	e.RegisterBuiltin("FILL", 2, c.fillFunction)
+	e.RegisterBuiltin("FRAME", -1, c.frameFunction)
+	e.RegisterBuiltin("INK", -1, c.inkFunction)
+	e.RegisterBuiltin("LINE", 4, c.lineFunction)
+	e.RegisterBuiltin("PAPER", -1, c.paperFunction)
+	e.RegisterBuiltin("PLOT", 2, c.plotFunction)
+	e.RegisterBuiltin("RECT", 4, c.boxFunction)
+	e.RegisterBuiltin("SAVE", -1, c.saveFunction)
+	e.RegisterBuiltin("TEXT", 3, c.textFunction)
+
+	if c.opts.Turtle {
+		c.registerTurtle(e)
+	}
+}
+
+// limit is the largest number, either way, which the primitives accept,
+// which is far beyond any canvas.
+const limit = 1000000
+
+// numbers returns the arguments given to a primitive as integers.
+func numbers(args []object.Object) ([]int, object.Object) {
+	var out []int
+	for i := range args {
+		n, err := argument(args, i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, int(n))
+	}
+	return out, nil
+}
+
+// argument returns the given argument of a primitive, checking that it is
+// a number within our limit.
+func argument(args []object.Object, i int) (float64, object.Object) {
+	n, ok := args[i].(*object.NumberObject)
+	if !ok {
+		return 0, object.CodedError(object.ErrTypeMismatch, "Wrong type for argument %d", i+1)
+	}
+	if !(n.Value >= -limit && n.Value <= limit) {
+		return 0, object.CodedError(object.ErrIllegalFunction, "Argument %d must be between -%d and %d", i+1, limit, limit)
+	}
+	return n.Value, nil
+}
+
+// drawing returns the arguments given to a primitive which draws, as
+// integers, once it's sure the canvas has room for another shape.
+func (c *Canvas) drawing(args []object.Object) ([]int, object.Object) {
+	if err := c.full(); err != nil {
+		return nil, err
+	}
+	return numbers(args)
+}
+
+// full returns an error if the canvas has as many shapes as it may, not
+// counting the paper.
+func (c *Canvas) full() object.Object {
+	if c.opts.MaxShapes > 0 && len(c.shapes) > c.opts.MaxShapes {
+		return object.CodedError(object.ErrIllegalFunction, "at most %d shapes may be drawn", c.opts.MaxShapes)
+	}
+	return nil
+}
+
+// done is the value returned by the primitives.
+func done() object.Object {
+	return &object.NumberObject{Value: 0}
+}
+
+// plotFunction implements PLOT.
+func (c *Canvas) plotFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args)
+	if err != nil {
+		return err
+	}
+	c.Plot(n[0], n[1])
+	return done()
+}
+
+// drawFunction implements DRAW.
+func (c *Canvas) drawFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args)
+	if err != nil {
+		return err
+	}
+	c.Draw(n[0], n[1])
+	return done()
+}
+
+// lineFunction implements LINE.
+func (c *Canvas) lineFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args)
+	if err != nil {
+		return err
+	}
+	c.Line(n[0], n[1], n[2], n[3])
+	return done()
+}
+
+// circleFunction implements CIRCLE.
+func (c *Canvas) circleFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args)
+	if err != nil {
+		return err
+	}
+	c.Circle(n[0], n[1], n[2])
+	return done()
+}
+
+// boxFunction implements BOX, and RECT.
+func (c *Canvas) boxFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args)
+	if err != nil {
+		return err
+	}
+	c.Box(n[0], n[1], n[2], n[3])
+	return done()
+}
+
+// fillFunction implements FILL.
+func (c *Canvas) fillFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args)
+	if err != nil {
+		return err
+	}
+	c.Fill(n[0], n[1])
+	return done()
+}
+
+// textFunction implements TEXT; numbers are written as PRINT would.
+func (c *Canvas) textFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := c.drawing(args[:2])
+	if err != nil {
+		return err
+	}
+
+	text := ""
+	switch v := args[2].(type) {
+	case *object.StringObject:
+		text = v.Value
+	case *object.NumberObject:
+		text = builtin.STR(env, args[2:]).(*object.StringObject).Value
+	default:
+		return object.CodedError(object.ErrTypeMismatch, "Wrong type for argument 3")
+	}
+
+	c.Text(n[0], n[1], text)
+	return done()
+}
+
+// colour returns the colour chosen by the arguments given to INK, or
+// PAPER, which are either one of our numbered colours, or the red,
+// green, and blue parts of one.
+func colour(name string, args []object.Object) (color.RGBA, object.Object) {
+	n, err := numbers(args)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	switch len(n) {
+	case 1:
+		if n[0] < 0 || n[0] >= len(Colours) {
+			return color.RGBA{}, object.CodedError(object.ErrIllegalFunction, "%s colour must be between 0 and %d", name, len(Colours)-1)
+		}
+		return Colours[n[0]], nil
+	case 3:
+		part := func(v int) uint8 {
+			if v < 0 {
+				return 0
+			}
+			if v > 255 {
+				return 255
+			}
+			return uint8(v)
+		}
+		return color.RGBA{part(n[0]), part(n[1]), part(n[2]), 0xff}, nil
+	default:
+		return color.RGBA{}, object.Error("%s expects a colour, or its red, green, and blue parts, but received %d argument(s)", name, len(n))
+	}
+}
+
+// inkFunction implements INK, COLOR, and COLOUR.
+func (c *Canvas) inkFunction(env builtin.Environment, args []object.Object) object.Object {
+	col, err := colour("INK", args)
+	if err != nil {
+		return err
+	}
+	c.Ink = col
+	return done()
+}
+
+// paperFunction implements PAPER.
+func (c *Canvas) paperFunction(env builtin.Environment, args []object.Object) object.Object {
+	col, err := colour("PAPER", args)
+	if err != nil {
+		return err
+	}
+	c.Paper = col
+	return done()
+}
+
+// frameFunction implements FRAME.
+func (c *Canvas) frameFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers(args)
+	if err != nil {
+		return err
+	}
+	if len(n) > 1 {
+		return object.Error("FRAME expects at most one argument, but received %d", len(n))
+	}
+
+	delay := 0
+	if len(n) == 1 {
+		delay = n[0]
+	}
+	if err := c.Frame(delay); err != nil {
+		return object.CodedError(object.ErrIllegalFunction, "%s", err.Error())
+	}
+	return done()
+}
+
+// saveFunction implements SAVE.
+func (c *Canvas) saveFunction(env builtin.Environment, args []object.Object) object.Object {
+	path := c.opts.File
+	switch len(args) {
+	case 0:
+	case 1:
+		s, ok := args[0].(*object.StringObject)
+		if !ok {
+			return object.CodedError(object.ErrTypeMismatch, "SAVE expects the name of a file")
+		}
+		path = s.Value
+	default:
+		return object.Error("SAVE expects at most one argument, but received %d", len(args))
+	}
+
+	if err := c.Save(path); err != nil {
+		return object.CodedError(object.ErrIllegalFunction, "%s", err.Error())
+	}
+	return done()
+}
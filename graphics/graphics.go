@@ -0,0 +1,416 @@
+// Package graphics provides drawing primitives which may be added to any
+// BASIC interpreter, along with the canvas they draw upon.
+//
+// Each interpreter is given a canvas of its own:
+//
+//	e, _ := eval.New(tokenizer.New(src))
+//	canvas := graphics.Register(e, graphics.Options{})
+//
+// The program may then draw upon it, and save the result:
+//
+//	10 INK 2
+//	20 CIRCLE 100, 100, 50
+//	30 BOX 10, 10, 40, 40
+//	40 TEXT 60, 180, "HELLO"
+//	50 SAVE "hello.png"
+//
+// There is also a turtle, if Options.Turtle is set, which starts in the
+// centre of the canvas facing up, and draws as it moves:
+//
+//	10 FOR I = 1 TO 5
+//	20   FORWARD 100
+//	30   RIGHT 144
+//	40 NEXT I
+//
+// Images may be saved as PNG, SVG, or GIF files, and the frames recorded
+// via FRAME are saved as an animated GIF.
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Options control the canvas created by New.
+type Options struct {
+
+	// Width and Height are the size of the canvas, which defaults
+	// to 600x400.
+	Width  int
+	Height int
+
+	// Delay is the time between the frames of an animation, in
+	// hundredths of a second, used when FRAME isn't given one.  It
+	// defaults to ten.
+	Delay int
+
+	// MaxFrames is the most frames which may be recorded, or zero
+	// for no limit.
+	MaxFrames int
+
+	// MaxShapes is the most shapes which may be drawn, or zero for
+	// no limit.  Each is kept, so that the image may be saved as an
+	// SVG, so this limits the memory a program may use.
+	MaxShapes int
+
+	// Turtle adds the primitives which steer the turtle.  Their
+	// names, such as LEFT and HOME, become keywords which programs
+	// can't use as variables, so they must be asked for.
+	Turtle bool
+
+	// File is the name of the file written by SAVE when it isn't
+	// given one.  It defaults to "out.png".
+	File string
+
+	// Create opens the files written by SAVE, which allows the host
+	// to decide where they go, or to keep them in memory.  It
+	// defaults to os.Create.
+	Create func(path string) (io.WriteCloser, error)
+}
+
+// Canvas holds an image, and the state of the primitives which draw upon
+// it.
+type Canvas struct {
+
+	// Image holds the picture.
+	Image *image.RGBA
+
+	// Ink is the colour we draw with, and Paper the colour behind
+	// any text.
+	Ink   color.RGBA
+	Paper color.RGBA
+
+	// x and y are the position of the pen, from which DRAW starts.
+	x int
+	y int
+
+	// turtle holds the state of the turtle.
+	turtle turtle
+
+	// opts holds our options.
+	opts Options
+
+	// shapes holds the SVG elements which describe what we've drawn.
+	shapes []string
+
+	// frames and delays hold the frames of any animation.
+	frames []*image.Paletted
+	delays []int
+}
+
+// Colours holds the colours which may be chosen by number, via INK and
+// PAPER, which are the eight colours of the ZX Spectrum.
+var Colours = []color.RGBA{
+	{0x00, 0x00, 0x00, 0xff}, // black
+	{0x00, 0x00, 0xff, 0xff}, // blue
+	{0xff, 0x00, 0x00, 0xff}, // red
+	{0xff, 0x00, 0xff, 0xff}, // magenta
+	{0x00, 0xff, 0x00, 0xff}, // green
+	{0x00, 0xff, 0xff, 0xff}, // cyan
+	{0xff, 0xff, 0x00, 0xff}, // yellow
+	{0xff, 0xff, 0xff, 0xff}, // white
+}
+
+// New creates a blank, white, canvas, upon which we draw in black.
+func New(opts Options) *Canvas {
+	if opts.Width <= 0 {
+		opts.Width = 600
+	}
+	if opts.Height <= 0 {
+		opts.Height = 400
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = 10
+	}
+	if opts.File == "" {
+		opts.File = "out.png"
+	}
+	if opts.Create == nil {
+		opts.Create = func(path string) (io.WriteCloser, error) {
+			return os.Create(path)
+		}
+	}
+
+	c := &Canvas{
+		Image: image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height)),
+		Ink:   Colours[0],
+		Paper: Colours[7],
+		opts:  opts,
+	}
+	draw.Draw(c.Image, c.Image.Bounds(), &image.Uniform{c.Paper}, image.Point{}, draw.Src)
+	c.Home()
+	c.shape(`<rect width="%d" height="%d" fill="%s"/>`, opts.Width, opts.Height, hex(c.Paper))
+
+	return c
+}
+
+// shape records an SVG element.
+func (c *Canvas) shape(format string, args ...interface{}) {
+	c.shapes = append(c.shapes, fmt.Sprintf(format, args...))
+}
+
+// hex returns the given colour in the form used by SVG.
+func hex(col color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", col.R, col.G, col.B)
+}
+
+// Plot sets a single pixel, and moves the pen there.
+func (c *Canvas) Plot(x, y int) {
+	c.Image.Set(x, y, c.Ink)
+	c.shape(`<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`, x, y, hex(c.Ink))
+	c.x, c.y = x, y
+}
+
+// Line draws a line between the two points, and moves the pen to the
+// second.
+func (c *Canvas) Line(x1, y1, x2, y2 int) {
+	c.x, c.y = x2, y2
+	c.shape(`<line x1="%d.5" y1="%d.5" x2="%d.5" y2="%d.5" stroke="%s" stroke-linecap="square"/>`,
+		x1, y1, x2, y2, hex(c.Ink))
+
+	//
+	// Only the part of the line upon the canvas is drawn, so that
+	// long lines take no longer than short ones.
+	//
+	var visible bool
+	x1, y1, x2, y2, visible = c.clip(x1, y1, x2, y2)
+	if !visible {
+		return
+	}
+
+	//
+	// Bresenham's algorithm, which handles lines in any direction.
+	//
+	dx, sx := x2-x1, 1
+	if dx < 0 {
+		dx, sx = -dx, -1
+	}
+	dy, sy := y2-y1, 1
+	if dy < 0 {
+		dy, sy = -dy, -1
+	}
+	dy = -dy
+
+	e := dx + dy
+	for {
+		c.Image.Set(x1, y1, c.Ink)
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+// clip returns the part of the line between the two points which lies
+// upon the canvas, or a pixel beyond it, and false if none of it does.
+//
+// Lines which start and end upon the canvas are returned unchanged.
+func (c *Canvas) clip(x1, y1, x2, y2 int) (int, int, int, int, bool) {
+	b := c.Image.Bounds().Inset(-1)
+	if (image.Point{x1, y1}).In(b) && (image.Point{x2, y2}).In(b) {
+		return x1, y1, x2, y2, true
+	}
+
+	//
+	// The Liang-Barsky algorithm finds the fractions of the line, t0
+	// and t1, between which it lies within each edge of the canvas.
+	//
+	fx, fy := float64(x1), float64(y1)
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	t0, t1 := 0.0, 1.0
+	edges := [][2]float64{
+		{-dx, fx - float64(b.Min.X)},
+		{dx, float64(b.Max.X-1) - fx},
+		{-dy, fy - float64(b.Min.Y)},
+		{dy, float64(b.Max.Y-1) - fy},
+	}
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		switch {
+		case p == 0 && q < 0:
+			return 0, 0, 0, 0, false
+		case p < 0:
+			t0 = math.Max(t0, q/p)
+		case p > 0:
+			t1 = math.Min(t1, q/p)
+		}
+	}
+	if t0 > t1 {
+		return 0, 0, 0, 0, false
+	}
+
+	return int(math.Round(fx + t0*dx)), int(math.Round(fy + t0*dy)),
+		int(math.Round(fx + t1*dx)), int(math.Round(fy + t1*dy)), true
+}
+
+// Draw draws a line from the pen, by the given distance, as the ZX
+// Spectrum's DRAW does.
+func (c *Canvas) Draw(dx, dy int) {
+	c.Line(c.x, c.y, c.x+dx, c.y+dy)
+}
+
+// Circle draws the outline of a circle.
+func (c *Canvas) Circle(x0, y0, r int) {
+	if r < 0 {
+		r = -r
+	}
+	c.shape(`<circle cx="%d.5" cy="%d.5" r="%d" fill="none" stroke="%s"/>`, x0, y0, r, hex(c.Ink))
+
+	//
+	// There's nothing to draw if the circle is beside the canvas, or
+	// if the canvas is within it.
+	//
+	b := c.Image.Bounds()
+	if x0+r < b.Min.X || x0-r >= b.Max.X || y0+r < b.Min.Y || y0-r >= b.Max.Y {
+		return
+	}
+	far := math.Hypot(math.Max(float64(x0-b.Min.X), float64(b.Max.X-x0)),
+		math.Max(float64(y0-b.Min.Y), float64(b.Max.Y-y0)))
+	if far < float64(r)-1 {
+		return
+	}
+
+	//
+	// The midpoint algorithm; we draw an eighth of the circle, and
+	// reflect it to find the rest.
+	//
+	x, y, e := r, 0, 1-r
+	for x >= y {
+		for _, p := range [][2]int{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}} {
+			c.Image.Set(x0+p[0], y0+p[1], c.Ink)
+		}
+		y++
+		if e < 0 {
+			e += 2*y + 1
+		} else {
+			x--
+			e += 2*(y-x) + 1
+		}
+	}
+}
+
+// Box fills the rectangle which has the given corners.
+func (c *Canvas) Box(x1, y1, x2, y2 int) {
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	c.shape(`<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x1, y1, x2-x1+1, y2-y1+1, hex(c.Ink))
+
+	r := image.Rect(x1, y1, x2+1, y2+1)
+	draw.Draw(c.Image, r, &image.Uniform{c.Ink}, image.Point{}, draw.Src)
+}
+
+// Fill floods the area around the given point, which is the same colour
+// as it, with the ink.
+func (c *Canvas) Fill(x, y int) {
+	if !(image.Point{x, y}).In(c.Image.Bounds()) {
+		return
+	}
+	target := c.Image.RGBAAt(x, y)
+	if target == c.Ink {
+		return
+	}
+
+	is := func(x, y int) bool {
+		return (image.Point{x, y}).In(c.Image.Bounds()) && c.Image.RGBAAt(x, y) == target
+	}
+
+	//
+	// We fill a row at a time, and look for more to fill in the
+	// rows above and below each, recording each row as a part of a
+	// single SVG path.
+	//
+	var path strings.Builder
+	seeds := []image.Point{{x, y}}
+	for len(seeds) > 0 {
+		p := seeds[len(seeds)-1]
+		seeds = seeds[:len(seeds)-1]
+		if !is(p.X, p.Y) {
+			continue
+		}
+
+		left, right := p.X, p.X
+		for is(left-1, p.Y) {
+			left--
+		}
+		for is(right+1, p.Y) {
+			right++
+		}
+		for i := left; i <= right; i++ {
+			c.Image.SetRGBA(i, p.Y, c.Ink)
+		}
+		fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", left, p.Y, right-left+1, right-left+1)
+
+		for _, ny := range []int{p.Y - 1, p.Y + 1} {
+			for i := left; i <= right; i++ {
+				if is(i, ny) && (i == left || !is(i-1, ny)) {
+					seeds = append(seeds, image.Point{i, ny})
+				}
+			}
+		}
+	}
+	c.shape(`<path d="%s" fill="%s"/>`, path.String(), hex(c.Ink))
+}
+
+// Text writes the given text, with its top-left corner at the given
+// point, in the ink upon the paper.
+//
+// Characters which aren't printable ASCII are shown as "?", and a newline
+// starts a new line beneath the first.
+func (c *Canvas) Text(x, y int, text string) {
+	if path := c.text(x, y, text, true); path != "" {
+		c.shape(`<path d="%s" fill="%s"/>`, path, hex(c.Paper))
+	}
+	if path := c.text(x, y, text, false); path != "" {
+		c.shape(`<path d="%s" fill="%s"/>`, path, hex(c.Ink))
+	}
+}
+
+// text draws either the background, or the characters, of the given
+// text, and returns an SVG path which does the same.
+func (c *Canvas) text(x, y int, text string, background bool) string {
+	var path strings.Builder
+	cx, cy := x, y
+	for _, r := range text {
+		if r == '\n' {
+			cx, cy = x, cy+fontHeight
+			continue
+		}
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+
+		if background {
+			draw.Draw(c.Image, image.Rect(cx, cy, cx+fontWidth, cy+fontHeight), &image.Uniform{c.Paper}, image.Point{}, draw.Src)
+			fmt.Fprintf(&path, "M%d %dh%dv%dh-%dz", cx, cy, fontWidth, fontHeight, fontWidth)
+		} else {
+			for row, bits := range font[r-' '] {
+				for col := 0; col < 5; col++ {
+					if bits&(1<<(4-col)) != 0 {
+						c.Image.Set(cx+col, cy+row, c.Ink)
+						fmt.Fprintf(&path, "M%d %dh1v1h-1z", cx+col, cy+row)
+					}
+				}
+			}
+		}
+		cx += fontWidth
+	}
+	return path.String()
+}
@@ -0,0 +1,337 @@
+// graphics_test.go - Test-cases for our drawing primitives.
+
+package graphics
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/internal/evaltest"
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// memory holds the files written by a canvas.
+type memory map[string]*bytes.Buffer
+
+// file is a file which is kept in memory.
+type file struct {
+	*bytes.Buffer
+}
+
+func (f file) Close() error {
+	return nil
+}
+
+// create is used as the Create option, so files are kept in memory.
+func (m memory) create(path string) (io.WriteCloser, error) {
+	m[path] = &bytes.Buffer{}
+	return file{m[path]}, nil
+}
+
+// options returns the options of a small canvas, with its turtle, which
+// keeps the files it saves in the given memory.
+func options(files memory) Options {
+	return Options{Width: 40, Height: 30, Turtle: true, Create: files.create}
+}
+
+// run runs the given program with a small canvas, returning it and the
+// files it saved.
+func run(t *testing.T, src string) (*Canvas, memory, error) {
+	var c *Canvas
+	files := memory{}
+	_, err := evaltest.Run(t, src, func(e *eval.Interpreter) {
+		c = Register(e, options(files))
+	})
+	return c, files, err
+}
+
+var (
+	black = Colours[0]
+	red   = Colours[2]
+	white = Colours[7]
+)
+
+// TestPrimitives tests that the primitives draw what we expect.
+func TestPrimitives(t *testing.T) {
+
+	type Pixel struct {
+		X, Y   int
+		Colour color.RGBA
+	}
+
+	type Test struct {
+		Source string
+		Pixels []Pixel
+	}
+
+	tests := []Test{
+		{Source: "10 PLOT 3, 4\n",
+			Pixels: []Pixel{{3, 4, black}, {4, 4, white}}},
+
+		{Source: "10 INK 2\n20 LINE 0, 0, 10, 5\n",
+			Pixels: []Pixel{{0, 0, red}, {10, 5, red}, {2, 1, red}, {8, 4, red}, {5, 5, white}}},
+
+		// Lines may be drawn in any direction.
+		{Source: "10 LINE 10, 5, 0, 0\n20 LINE 2, 20, 2, 10\n",
+			Pixels: []Pixel{{0, 0, black}, {10, 5, black}, {2, 15, black}}},
+
+		// DRAW continues from the last point.
+		{Source: "10 PLOT 5, 5\n20 DRAW 5, 0\n30 DRAW 0, 5\n",
+			Pixels: []Pixel{{10, 5, black}, {10, 10, black}, {7, 7, white}}},
+
+		{Source: "10 COLOUR 0, 0, 255\n20 CIRCLE 15, 15, 10\n",
+			Pixels: []Pixel{{25, 15, Colours[1]}, {15, 5, Colours[1]}, {15, 15, white}}},
+
+		{Source: "10 BOX 12, 8, 10, 6\n",
+			Pixels: []Pixel{{10, 6, black}, {12, 8, black}, {11, 7, black}, {13, 8, white}}},
+
+		// FILL stops at the edges of the area.
+		{Source: "10 INK 2\n20 BOX 0, 10, 39, 10\n30 INK 4\n40 FILL 5, 20\n",
+			Pixels: []Pixel{{0, 29, Colours[4]}, {39, 11, Colours[4]}, {5, 10, red}, {5, 5, white}}},
+
+		// The letter "T" is a bar across the top, and a stem.
+		{Source: "10 PAPER 2\n20 TEXT 1, 1, \"T\"\n",
+			Pixels: []Pixel{{1, 1, black}, {5, 1, black}, {3, 7, black}, {1, 7, red}, {6, 9, red}, {7, 1, white}}},
+	}
+
+	for _, test := range tests {
+		c, _, err := run(t, test.Source)
+		if err != nil {
+			t.Errorf("Error running %q: %s", test.Source, err.Error())
+			continue
+		}
+		for _, p := range test.Pixels {
+			got := c.Image.RGBAAt(p.X, p.Y)
+			if got != p.Colour {
+				t.Errorf("%q: pixel %d,%d is %v, not %v", test.Source, p.X, p.Y, got, p.Colour)
+			}
+		}
+	}
+}
+
+// TestTurtle tests that the turtle draws where we expect.
+func TestTurtle(t *testing.T) {
+
+	// The turtle starts in the centre, at 20, 15, facing up.
+	c, _, err := run(t, `10 PENCOLOR 2
+20 FORWARD 10 : RIGHT 90 : FORWARD 5
+30 PENUP : HOME : LEFT 90 : PENDOWN
+40 FORWARD 10 : BACK 20
+50 SETHEADING 180 : FORWARD 3
+60 DEF FN side(s) = (FORWARD s) + (LEFT 90)
+70 PENCOLOR 4 : PENUP : HOME : BACK 10 : PENDOWN : SETHEADING 90
+80 LET x = FN side(4) + FN side(4)
+`)
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+
+	for _, p := range []struct {
+		X, Y   int
+		Colour color.RGBA
+	}{
+		{20, 15, red}, {20, 10, red}, {20, 5, red}, {25, 5, red}, {26, 5, white},
+		{10, 15, red}, {30, 15, red}, {30, 18, red}, {30, 19, white},
+		{20, 25, Colours[4]}, {24, 25, Colours[4]}, {24, 21, Colours[4]}, {22, 23, white},
+	} {
+		if got := c.Image.RGBAAt(p.X, p.Y); got != p.Colour {
+			t.Errorf("pixel %d,%d is %v, not %v", p.X, p.Y, got, p.Colour)
+		}
+	}
+
+	// Headings are kept between 0 and 360.
+	c.SetHeading(0 - 450)
+	if c.turtle.heading != 270 {
+		t.Errorf("Unexpected heading %f", c.turtle.heading)
+	}
+
+	// Unless the turtle is asked for its names may be variables.
+	e, err := evaltest.Run(t, "10 LET left = 90\n20 LET home = left + 1\n", func(e *eval.Interpreter) {
+		Register(e, Options{Width: 40, Height: 30})
+	})
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+	if got := e.GetVariable("home").(*object.NumberObject).Value; got != 91 {
+		t.Errorf("home was %v, not 91", got)
+	}
+}
+
+// TestSave tests saving images in each format.
+func TestSave(t *testing.T) {
+	_, files, err := run(t, `10 INK 2
+20 BOX 0, 0, 9, 9
+30 FRAME
+40 INK 4
+50 CIRCLE 20, 15, 5 : FILL 20, 15
+60 TEXT 0, 20, "Hi"
+70 FRAME 50
+80 SAVE "a.PNG"
+90 SAVE "a.svg" : SAVE "a.gif" : SAVE
+`)
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+
+	img, err := png.Decode(bytes.NewReader(files["a.PNG"].Bytes()))
+	if err != nil {
+		t.Fatalf("Error decoding PNG: %s", err.Error())
+	}
+	if r, g, b, _ := img.At(5, 5).RGBA(); r != 0xffff || g != 0 || b != 0 {
+		t.Errorf("Unexpected colour %v", img.At(5, 5))
+	}
+	if files["out.png"] == nil {
+		t.Errorf("SAVE didn't use the default name")
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(files["a.gif"].Bytes()))
+	if err != nil {
+		t.Fatalf("Error decoding GIF: %s", err.Error())
+	}
+	if len(anim.Image) != 2 || anim.Delay[0] != 10 || anim.Delay[1] != 50 {
+		t.Errorf("Unexpected animation %d %v", len(anim.Image), anim.Delay)
+	}
+	if r, _, _, _ := anim.Image[0].At(5, 5).RGBA(); r != 0xffff {
+		t.Errorf("Unexpected colour in first frame %v", anim.Image[0].At(5, 5))
+	}
+
+	svg := files["a.svg"].String()
+	for _, expected := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="40" height="30"`,
+		`<rect width="40" height="30" fill="#ffffff"/>`,
+		`<rect x="0" y="0" width="10" height="10" fill="#ff0000"/>`,
+		`<circle cx="20.5" cy="15.5" r="5" fill="none" stroke="#00ff00"/>`,
+		`<path d="M0 20h6v9h-6zM6 20h6v9h-6z" fill="#ffffff"/>`,
+		"</svg>",
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("SVG doesn't contain %q\n%s", expected, svg)
+		}
+	}
+}
+
+// TestErrors tests that mistakes are reported.
+func TestErrors(t *testing.T) {
+	tests := map[string]string{
+		"10 PLOT \"a\", 1\n":     "Wrong type for argument 1",
+		"10 INK 8\n":             "INK colour must be between 0 and 7",
+		"10 PAPER 1, 2\n":        "PAPER expects a colour",
+		"10 SAVE \"a.jpg\"\n":    "the name must end in .png, .svg, or .gif",
+		"10 SAVE 3\n":            "SAVE expects the name of a file",
+		"10 FRAME 1, 2\n":        "FRAME expects at most one argument",
+		"10 FRAME 0 - 1\n":       "can't be negative",
+		"10 TEXT 1, 1, \"a\" +":  "",
+		"10 LINE 0, 0, 4e8, 0\n": "Argument 3 must be between -1000000 and 1000000",
+		"10 CIRCLE 1, 1, 2e9\n":  "Argument 3 must be between",
+		"10 FORWARD 1e300\n":     "Argument 1 must be between",
+	}
+
+	evaltest.Errors(t, tests, func(e *eval.Interpreter) {
+		Register(e, options(memory{}))
+	})
+
+	//
+	// Animations may be limited.
+	//
+	c := New(Options{MaxFrames: 1})
+	if err := c.Frame(0); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if err := c.Frame(0); err == nil || c.Frames() != 1 {
+		t.Errorf("Expected an error recording too many frames")
+	}
+}
+
+// TestHuge tests that shapes which are far larger than the canvas are
+// drawn as quickly as those upon it.
+func TestHuge(t *testing.T) {
+	src := `10 LINE 0, 0, 1000000, 0
+20 LINE 0 - 1000000, 0 - 1000000, 1000000, 1000000
+30 LINE 1000000, 0, 1000000, 1000000
+40 CIRCLE 20, 15, 1000000
+50 CIRCLE 0, 0, 990000
+60 FORWARD 1000000
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	e, err := eval.NewWithContext(ctx, tokenizer.New(src))
+	if err != nil {
+		t.Fatalf("Error parsing %q: %s", src, err.Error())
+	}
+	e.SetStepLimit(10)
+	c := Register(e, Options{Width: 40, Height: 30, Turtle: true})
+
+	start := time.Now()
+	if err = e.Run(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("Drawing took %s", d)
+	}
+
+	// The parts upon the canvas are drawn.
+	for _, p := range [][2]int{{39, 0}, {10, 10}, {29, 29}, {20, 1}} {
+		if got := c.Image.RGBAAt(p[0], p[1]); got != black {
+			t.Errorf("Pixel %v is %v", p, got)
+		}
+	}
+	if got := c.Image.RGBAAt(39, 20); got != white {
+		t.Errorf("Pixel 39, 20 is %v", got)
+	}
+
+	// Numbers which aren't are refused.
+	nan := &object.NumberObject{Value: math.NaN()}
+	if _, err := numbers([]object.Object{nan}); err == nil {
+		t.Errorf("Expected an error for NaN")
+	}
+}
+
+// TestMaxShapes tests that a program which draws forever is stopped once
+// it has drawn as many shapes as it may.
+func TestMaxShapes(t *testing.T) {
+	for _, src := range []string{
+		"10 PLOT 1, 1\n20 GOTO 10\n",
+		"10 TEXT 0, 0, \"HI\"\n20 GOTO 10\n",
+		"10 FORWARD 1\n20 RIGHT 1\n30 GOTO 10\n",
+	} {
+		var c *Canvas
+		_, err := evaltest.Run(t, src, func(e *eval.Interpreter) {
+			c = Register(e, Options{Width: 40, Height: 30, MaxShapes: 100, Turtle: true})
+		})
+		if err == nil || !strings.Contains(err.Error(), "at most 100 shapes may be drawn") {
+			t.Errorf("Unexpected error running %q: %v", src, err)
+		}
+		if len(c.shapes) < 100 || len(c.shapes) > 102 {
+			t.Errorf("%q drew %d shapes", src, len(c.shapes))
+		}
+	}
+}
+
+// TestFont tests that every printable character has a shape, other than
+// space.
+func TestFont(t *testing.T) {
+	for i, shape := range font {
+		empty := true
+		for _, row := range shape {
+			if row != 0 {
+				empty = false
+			}
+			if row >= 1<<5 {
+				t.Errorf("The shape of %q is too wide", rune(' '+i))
+			}
+		}
+		if empty != (i == 0) {
+			t.Errorf("Unexpected shape for %q", rune(' '+i))
+		}
+	}
+}
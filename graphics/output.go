@@ -0,0 +1,136 @@
+package graphics
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Frame records the current image as the next frame of an animation,
+// which is shown for the given time, in hundredths of a second, or for
+// the default time if that is zero.
+func (c *Canvas) Frame(delay int) error {
+	if c.opts.MaxFrames > 0 && len(c.frames) >= c.opts.MaxFrames {
+		return fmt.Errorf("an animation may have at most %d frames", c.opts.MaxFrames)
+	}
+	if delay < 0 {
+		return fmt.Errorf("the delay of a frame can't be negative")
+	}
+	if delay == 0 {
+		delay = c.opts.Delay
+	}
+
+	c.frames = append(c.frames, paletted(c.Image))
+	c.delays = append(c.delays, delay)
+	return nil
+}
+
+// Frames returns the number of frames which have been recorded.
+func (c *Canvas) Frames() int {
+	return len(c.frames)
+}
+
+// paletted converts an image into one with a palette, as GIF requires.
+//
+// Most of what BASIC draws has few colours, so we keep them exactly if we
+// can, and otherwise approximate them.
+func paletted(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+
+	var pal color.Palette
+	index := make(map[color.RGBA]uint8)
+	exact := true
+
+colours:
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			col := img.RGBAAt(x, y)
+			if _, ok := index[col]; ok {
+				continue
+			}
+			if len(pal) == 256 {
+				exact = false
+				break colours
+			}
+			index[col] = uint8(len(pal))
+			pal = append(pal, col)
+		}
+	}
+
+	if !exact {
+		out := image.NewPaletted(b, palette.Plan9)
+		draw.FloydSteinberg.Draw(out, b, img, b.Min)
+		return out
+	}
+
+	out := image.NewPaletted(b, pal)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetColorIndex(x, y, index[img.RGBAAt(x, y)])
+		}
+	}
+	return out
+}
+
+// WritePNG writes the image as a PNG.
+func (c *Canvas) WritePNG(w io.Writer) error {
+	return png.Encode(w, c.Image)
+}
+
+// WriteGIF writes the frames which have been recorded as an animated GIF,
+// which repeats forever, or the image if there are none.
+func (c *Canvas) WriteGIF(w io.Writer) error {
+	anim := &gif.GIF{Image: c.frames, Delay: c.delays}
+	if len(c.frames) == 0 {
+		anim = &gif.GIF{Image: []*image.Paletted{paletted(c.Image)}, Delay: []int{0}}
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// WriteSVG writes the image as an SVG, which draws the same shapes as
+// the image holds.
+func (c *Canvas) WriteSVG(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		c.opts.Width, c.opts.Height, c.opts.Width, c.opts.Height)
+	for _, s := range c.shapes {
+		fmt.Fprintf(out, "%s\n", s)
+	}
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+// Save writes the image to the given file, via the Create option.  The
+// format is chosen by the suffix of the name, which must be ".png",
+// ".svg", or ".gif".
+func (c *Canvas) Save(path string) error {
+	var write func(io.Writer) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		write = c.WritePNG
+	case ".svg":
+		write = c.WriteSVG
+	case ".gif":
+		write = c.WriteGIF
+	default:
+		return fmt.Errorf("can't save %q, the name must end in .png, .svg, or .gif", path)
+	}
+
+	f, err := c.opts.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
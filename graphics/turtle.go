@@ -0,0 +1,165 @@
+package graphics
+
+import (
+	"math"
+
+	"github.com/skx/gobasic/builtin"
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+)
+
+// turtle holds the state of the turtle, which draws as it moves.
+type turtle struct {
+
+	// x and y are the position of the turtle.  We keep fractions of
+	// a pixel, so that many short moves don't wander.
+	x float64
+	y float64
+
+	// heading is the direction the turtle faces, in degrees clockwise
+	// from straight up.
+	heading float64
+
+	// up is true if the pen is lifted, so the turtle doesn't draw.
+	up bool
+}
+
+// Home moves the turtle to the centre of the canvas, facing up, without
+// drawing.  The pen is left as it was.
+func (c *Canvas) Home() {
+	c.turtle.x = float64(c.opts.Width / 2)
+	c.turtle.y = float64(c.opts.Height / 2)
+	c.turtle.heading = 0
+}
+
+// Forward moves the turtle the given distance in the direction it faces,
+// drawing a line behind it unless the pen is up.
+func (c *Canvas) Forward(distance float64) {
+	rad := c.turtle.heading * math.Pi / 180
+	x := c.turtle.x + distance*math.Sin(rad)
+	y := c.turtle.y - distance*math.Cos(rad)
+
+	if !c.turtle.up {
+		c.Line(round(c.turtle.x), round(c.turtle.y), round(x), round(y))
+	}
+	c.turtle.x, c.turtle.y = x, y
+}
+
+// Right turns the turtle clockwise, by the given number of degrees.
+func (c *Canvas) Right(degrees float64) {
+	c.SetHeading(c.turtle.heading + degrees)
+}
+
+// SetHeading turns the turtle to face the given direction, in degrees
+// clockwise from straight up.
+func (c *Canvas) SetHeading(degrees float64) {
+	c.turtle.heading = math.Mod(degrees, 360)
+	if c.turtle.heading < 0 {
+		c.turtle.heading += 360
+	}
+}
+
+// PenUp lifts the pen, so the turtle moves without drawing, and PenDown
+// lowers it again.
+func (c *Canvas) PenUp() {
+	c.turtle.up = true
+}
+
+// PenDown lowers the pen, so the turtle draws as it moves.
+func (c *Canvas) PenDown() {
+	c.turtle.up = false
+}
+
+// round returns the pixel which holds the given coordinate.
+func round(v float64) int {
+	return int(math.Floor(v + 0.5))
+}
+
+// registerTurtle makes the turtle primitives available to the given
+// interpreter.
+func (c *Canvas) registerTurtle(e *eval.Interpreter) {
+	e.RegisterBuiltin("BACK", 1, c.backFunction)
+	e.RegisterBuiltin("FORWARD", 1, c.forwardFunction)
+	e.RegisterBuiltin("HOME", 0, c.homeFunction)
+	e.RegisterBuiltin("LEFT", 1, c.leftFunction)
+	e.RegisterBuiltin("PENCOLOR", -1, c.inkFunction)
+	e.RegisterBuiltin("PENCOLOUR", -1, c.inkFunction)
+	e.RegisterBuiltin("PENDOWN", 0, c.penDownFunction)
+	e.RegisterBuiltin("PENUP", 0, c.penUpFunction)
+	e.RegisterBuiltin("RIGHT", 1, c.rightFunction)
+	e.RegisterBuiltin("SETHEADING", 1, c.setHeadingFunction)
+}
+
+// forwardFunction implements FORWARD.
+func (c *Canvas) forwardFunction(env builtin.Environment, args []object.Object) object.Object {
+	if err := c.full(); err != nil {
+		return err
+	}
+	n, err := argument(args, 0)
+	if err != nil {
+		return err
+	}
+	c.Forward(n)
+	return done()
+}
+
+// backFunction implements BACK.
+func (c *Canvas) backFunction(env builtin.Environment, args []object.Object) object.Object {
+	if err := c.full(); err != nil {
+		return err
+	}
+	n, err := argument(args, 0)
+	if err != nil {
+		return err
+	}
+	c.Forward(-n)
+	return done()
+}
+
+// rightFunction implements RIGHT.
+func (c *Canvas) rightFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := argument(args, 0)
+	if err != nil {
+		return err
+	}
+	c.Right(n)
+	return done()
+}
+
+// leftFunction implements LEFT.
+func (c *Canvas) leftFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := argument(args, 0)
+	if err != nil {
+		return err
+	}
+	c.Right(-n)
+	return done()
+}
+
+// setHeadingFunction implements SETHEADING.
+func (c *Canvas) setHeadingFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := argument(args, 0)
+	if err != nil {
+		return err
+	}
+	c.SetHeading(n)
+	return done()
+}
+
+// homeFunction implements HOME.
+func (c *Canvas) homeFunction(env builtin.Environment, args []object.Object) object.Object {
+	c.Home()
+	return done()
+}
+
+// penUpFunction implements PENUP.
+func (c *Canvas) penUpFunction(env builtin.Environment, args []object.Object) object.Object {
+	c.PenUp()
+	return done()
+}
+
+// penDownFunction implements PENDOWN.
+func (c *Canvas) penDownFunction(env builtin.Environment, args []object.Object) object.Object {
+	c.PenDown()
+	return done()
+}
@@ -0,0 +1,52 @@
+// Package evaltest helps to test the primitives which other packages add
+// to a BASIC interpreter, by running programs which use them.
+//
+// Each package registers its primitives with the interpreter it is given:
+//
+//	e, err := evaltest.Run(t, "10 BEEP 1, 0\n", func(e *eval.Interpreter) {
+//		synth = sound.Register(e, sound.Options{})
+//	})
+package evaltest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// Run runs the given program, once register has added the primitives it
+// uses to its interpreter, returning the interpreter and the error the
+// program failed with, if any.  Its output is flushed.
+//
+// The test fails if the program can't be parsed.
+func Run(t *testing.T, src string, register func(e *eval.Interpreter)) (*eval.Interpreter, error) {
+	t.Helper()
+
+	e, err := eval.New(tokenizer.New(src))
+	if err != nil {
+		t.Fatalf("Error parsing %q: %s", src, err.Error())
+	}
+	register(e)
+	err = e.Run()
+	e.StdOutput().Flush()
+	return e, err
+}
+
+// Errors runs each of the given programs, as Run does, checking that each
+// fails with an error which contains the text it maps to.
+func Errors(t *testing.T, tests map[string]string, register func(e *eval.Interpreter)) {
+	t.Helper()
+
+	for src, expected := range tests {
+		_, err := Run(t, src, register)
+		if err == nil {
+			t.Errorf("Expected an error running %q", src)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Error %q running %q doesn't contain %q", err.Error(), src, expected)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// docs.go contains the documentation for our built-in functions, which
+// is shown when hovering over them.
+
+package lsp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// doc holds the documentation for a single built-in function.
+type doc struct {
+
+	// usage shows how the function is called.
+	usage string
+
+	// description describes what the function does.
+	description string
+}
+
+// docs holds the documentation for the standard built-in functions,
+// indexed by their upper-case names.
+var docs = map[string]doc{
+
+	// Maths
+	"ABS":  {"ABS n", "Returns the absolute value of `n`."},
+	"ACS":  {"ACS n", "Returns the arccosine of `n`, in radians."},
+	"ASN":  {"ASN n", "Returns the arcsine of `n`, in radians."},
+	"ATN":  {"ATN n", "Returns the arctangent of `n`, in radians."},
+	"BIN":  {"BIN n", "Interprets the digits of `n` as a binary number, so `BIN 101` is 5."},
+	"BIN$": {"BIN$ n", "Returns `n` as a string of binary digits, so `BIN$ 5` is \"101\"."},
+	"COS":  {"COS n", "Returns the cosine of `n`, which is in radians."},
+	"EXP":  {"EXP n", "Returns e raised to the power `n`."},
+	"HEX$": {"HEX$ n", "Returns `n` as a string of hexadecimal digits, so `HEX$ 255` is \"FF\"."},
+	"INT":  {"INT n", "Returns the integer part of `n`."},
+	"LN":   {"LN n", "Returns the natural logarithm of `n`."},
+	"LOG":  {"LOG n", "Returns the natural logarithm of `n`, the same as `LN`."},
+	"OCT$": {"OCT$ n", "Returns `n` as a string of octal digits, so `OCT$ 8` is \"10\"."},
+	"PI":   {"PI", "Returns the value of π."},
+	"π":    {"π", "Returns the value of π."},
+	"RND":  {"RND n", "Returns a random integer between 0 and `n`-1."},
+	"SGN":  {"SGN n", "Returns -1, 0, or 1, depending upon the sign of `n`."},
+	"SIN":  {"SIN n", "Returns the sine of `n`, which is in radians."},
+	"SQR":  {"SQR n", "Returns the square root of `n`."},
+	"TAN":  {"TAN n", "Returns the tangent of `n`, which is in radians."},
+	"VAL":  {"VAL s$", "Converts the string `s$` to a number."},
+	"CODE": {"CODE s$", "Returns the character-code of the first character of `s$`."},
+	"LEN":  {"LEN s$", "Returns the length of the string `s$`."},
+
+	// Strings
+	"CHR$":   {"CHR$ n", "Returns the character with the code `n`."},
+	"LEFT$":  {"LEFT$ s$, n", "Returns the left-most `n` characters of `s$`."},
+	"MID$":   {"MID$ s$, offset, n", "Returns `n` characters of `s$`, starting from `offset`."},
+	"RIGHT$": {"RIGHT$ s$, n", "Returns the right-most `n` characters of `s$`."},
+	"SPC":    {"SPC n", "Returns a string containing `n` spaces."},
+	"STR$":   {"STR$ n", "Converts the number `n` to a string."},
+	"TL$":    {"TL$ s$", "Returns `s$` without its first character."},
+
+	// Time
+	"DATE$": {"DATE$", "Returns the current date, as `MM-DD-YYYY`."},
+	"PAUSE": {"PAUSE n", "Pauses for `n` fiftieths of a second."},
+	"SLEEP": {"SLEEP n", "Pauses for `n` seconds."},
+	"TIME$": {"TIME$", "Returns the current time, as `HH:MM:SS`."},
+	"TIMER": {"TIMER", "Returns the number of seconds since midnight."},
+
+	// Errors
+	"ERL":  {"ERL", "Returns the line-number upon which the most recent error occurred."},
+	"ERR":  {"ERR", "Returns the code of the most recent error."},
+	"ERR$": {"ERR$", "Returns the message of the most recent error."},
+
+	// Misc
+	"DUMP":  {"DUMP x", "Shows the type and value of `x`, for debugging."},
+	"PRINT": {"PRINT x, y, ..", "Outputs each of the given values."},
+}
+
+// documentation returns the documentation of the given built-in, as
+// markdown.
+//
+// Built-ins which have been registered by embedders won't have any
+// documentation, so we describe them as best we can.
+func documentation(name string, nArgs int) string {
+	if d, ok := docs[strings.ToUpper(name)]; ok {
+		return fmt.Sprintf("```basic\n%s\n```\n%s", d.usage, d.description)
+	}
+	if d, ok := docs[strings.ToLower(name)]; ok {
+		return fmt.Sprintf("```basic\n%s\n```\n%s", d.usage, d.description)
+	}
+
+	args := "a variable number of arguments"
+	switch {
+	case nArgs == 1:
+		args = "one argument"
+	case nArgs >= 0:
+		args = fmt.Sprintf("%d arguments", nArgs)
+	}
+	return fmt.Sprintf("```basic\n%s\n```\nA built-in function, taking %s.", strings.ToUpper(name), args)
+}
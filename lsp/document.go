@@ -0,0 +1,250 @@
+// document.go contains the code which indexes an open document, so that
+// we can find the tokens at given positions, and the lines they refer to.
+
+package lsp
+
+import (
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/token"
+	"github.com/skx/gobasic/tokenizer"
+	"github.com/skx/gobasic/vet"
+)
+
+// item is a token, along with its position in the document.
+type item struct {
+	tok token.Token
+
+	// line is the line of the token, counting from zero.
+	line int
+
+	// start is the column at which the token starts, counting from
+	// zero.
+	start int
+
+	// endLine and end are the position at which the token ends,
+	// which is exclusive.  A string might contain newlines, so a
+	// token can end upon a later line.
+	endLine int
+	end     int
+}
+
+// document holds the text of an open document, along with its tokens.
+type document struct {
+
+	// text is the content of the document.
+	text string
+
+	// items holds the tokens of the document.
+	items []item
+
+	// lines maps line-numbers, and labels, to the index of the token
+	// which defines them.
+	lines map[string]int
+}
+
+// newDocument tokenizes the given text, and indexes it.
+func newDocument(text string, builtins map[string]int) *document {
+	d := &document{text: text, lines: make(map[string]int)}
+
+	chars := []rune(text)
+
+	t := tokenizer.New(text)
+	for {
+		tok := t.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+
+		// The tokenizer may step past the end of the input, when a
+		// string is unterminated.
+		end := t.Offset()
+		if end > len(chars) {
+			end = len(chars)
+		}
+
+		it := item{tok: tok, line: tok.Line - 1, start: tok.Column - 1}
+		it.endLine, it.end = it.line, it.start
+		for _, ch := range chars[tok.Offset:end] {
+			if ch == '\n' {
+				it.endLine++
+				it.end = 0
+			} else {
+				it.end++
+			}
+		}
+
+		if tok.Type == token.IDENT {
+			if _, ok := builtins[tok.Literal]; ok {
+				it.tok.Type = token.BUILTIN
+			}
+		}
+
+		// A name followed by a colon, at the start of a line, is
+		// a label.
+		if tok.Type == token.COLON && len(d.items) > 0 && d.items[len(d.items)-1].tok.Type == token.IDENT && d.startsLine(len(d.items)-1) {
+			d.items[len(d.items)-1].tok.Type = token.LABEL
+		}
+
+		d.items = append(d.items, it)
+	}
+
+	for i, it := range d.items {
+		if it.tok.Type == token.LINENO || it.tok.Type == token.LABEL {
+			if _, ok := d.lines[it.tok.Literal]; !ok {
+				d.lines[it.tok.Literal] = i
+			}
+		}
+	}
+	return d
+}
+
+// startsLine returns true if the given token is the first on its line,
+// ignoring any line-number.
+func (d *document) startsLine(i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := d.items[i-1].tok.Type
+	return prev == token.NEWLINE || prev == token.LINENO
+}
+
+// find returns the index of the token at the given position, or -1 if
+// there is none.
+func (d *document) find(pos Position) int {
+	for i, it := range d.items {
+		if it.line == pos.Line && pos.Character >= it.start && (it.endLine > it.line || pos.Character <= it.end) && it.tok.Type != token.NEWLINE {
+			// Prefer the token starting here, over one ending here.
+			if pos.Character == it.end && i+1 < len(d.items) && d.items[i+1].line == pos.Line && d.items[i+1].start == pos.Character {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// rangeOf returns the range of the given token.
+func (d *document) rangeOf(i int) Range {
+	it := d.items[i]
+	return Range{
+		Start: Position{Line: it.line, Character: it.start},
+		End:   Position{Line: it.endLine, Character: it.end},
+	}
+}
+
+// isTarget returns true if the given token is the target of a GOTO,
+// GOSUB, THEN, ELSE, RESUME, or ON statement.
+func (d *document) isTarget(i int) bool {
+	it := d.items[i]
+	if it.tok.Type != token.INT && it.tok.Type != token.IDENT {
+		return false
+	}
+	if it.tok.Type == token.IDENT {
+		if _, ok := d.lines[it.tok.Literal]; !ok {
+			return false
+		}
+	}
+
+	// Walk backwards over any list of targets.
+	for j := i - 1; j >= 0; j-- {
+		switch d.items[j].tok.Type {
+		case token.GOTO, token.GOSUB, token.THEN, token.ELSE, token.RESUME:
+			return true
+		case token.COMMA:
+			continue
+		case token.INT, token.IDENT:
+			if j == i-1 {
+				return false
+			}
+			continue
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// definition returns the index of the token which defines the line
+// referred to by the given token, or -1 if it isn't a reference.
+func (d *document) definition(i int) int {
+	if !d.isTarget(i) {
+		return -1
+	}
+	if def, ok := d.lines[d.items[i].tok.Literal]; ok {
+		return def
+	}
+	return -1
+}
+
+// references returns the indexes of the tokens which refer to the same
+// line, or variable, as the given token.
+func (d *document) references(i int, declaration bool) []int {
+	var out []int
+
+	it := d.items[i]
+	switch {
+	case it.tok.Type == token.LINENO || it.tok.Type == token.LABEL || d.isTarget(i):
+		name := it.tok.Literal
+		for j, other := range d.items {
+			if other.tok.Literal != name {
+				continue
+			}
+			if d.isTarget(j) {
+				out = append(out, j)
+			}
+			if declaration && (other.tok.Type == token.LINENO || other.tok.Type == token.LABEL) {
+				out = append(out, j)
+			}
+		}
+
+	case it.tok.Type == token.IDENT:
+		for j, other := range d.items {
+			if other.tok.Type == token.IDENT && other.tok.Literal == it.tok.Literal {
+				out = append(out, j)
+			}
+		}
+	}
+	return out
+}
+
+// diagnostics returns the problems found in the document.
+//
+// Anything the tokenizer couldn't understand is an error, and if there
+// are none we report errors found by attempting to load the program into
+// the interpreter, and warnings from vet.
+func (d *document) diagnostics(builtins map[string]int) []Diagnostic {
+	out := []Diagnostic{}
+
+	for i, it := range d.items {
+		if it.tok.Type == token.ILLEGAL {
+			out = append(out, Diagnostic{
+				Range:    d.rangeOf(i),
+				Severity: SeverityError,
+				Source:   "gobasic",
+				Message:  it.tok.Problem(),
+			})
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	if _, err := eval.New(tokenizer.New(d.text)); err != nil {
+		out = append(out, Diagnostic{
+			Severity: SeverityError,
+			Source:   "gobasic",
+			Message:  err.Error(),
+		})
+	}
+
+	for _, v := range vet.New(builtins).Check(d.text) {
+		pos := Position{Line: v.Line - 1, Character: v.Column - 1}
+		out = append(out, Diagnostic{
+			Range:    Range{Start: pos, End: pos},
+			Severity: SeverityWarning,
+			Source:   "vet",
+			Message:  v.Message,
+		})
+	}
+	return out
+}
@@ -0,0 +1,310 @@
+// Package lsp contains a Language Server Protocol server for BASIC
+// programs, which communicates via JSON-RPC over a pair of streams -
+// usually STDIN and STDOUT.
+//
+// The server provides:
+//
+//   - Diagnostics, from the tokenizer, from loading the program into
+//     the interpreter, and from the vet package.
+//   - Hover documentation for built-in functions.
+//   - Go-to-definition, from a GOTO/GOSUB target to the line.
+//   - Find-references, for line-numbers, labels, and variables.
+//   - Completion of keywords and built-in function names.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/token"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// Server holds our state.
+type Server struct {
+
+	// in is the stream we read requests from.
+	in *bufio.Reader
+
+	// out is the stream we write responses to.
+	out io.Writer
+
+	// builtins holds the names of the built-in functions, and the
+	// number of arguments each requires.
+	builtins map[string]int
+
+	// documents holds the documents which are open, by URI.
+	documents map[string]*document
+
+	// shutdown is true once the client has asked us to shutdown.
+	shutdown bool
+}
+
+// New creates a new server, which reads requests from the given reader
+// and writes responses to the given writer.
+//
+// The built-in functions are those which the standard interpreter
+// provides.
+func New(in io.Reader, out io.Writer) (*Server, error) {
+	e, err := eval.New(tokenizer.New(""))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		builtins:  e.Builtins(),
+		documents: make(map[string]*document),
+	}, nil
+}
+
+// Run processes requests until the client asks us to exit, or the input
+// is closed.
+func (s *Server) Run() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.fail(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		// After a shutdown request only "exit" is valid.
+		if s.shutdown && req.ID != nil {
+			if err := s.fail(req.ID, codeInvalidRequest, "the server has been shutdown"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads a single message, which is preceded by headers.
+func (s *Server) read() ([]byte, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %s", err.Error())
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// write writes a single message, preceded by its length.
+func (s *Server) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// reply sends a successful response to a request.
+func (s *Server) reply(id *json.RawMessage, result interface{}) error {
+	return s.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// fail sends a failed response to a request.
+func (s *Server) fail(id *json.RawMessage, code int, message string) error {
+	return s.write(errorResponse{JSONRPC: "2.0", ID: id, Error: rpcError{Code: code, Message: message}})
+}
+
+// handle dispatches a single request, or notification.
+func (s *Server) handle(req request) error {
+
+	switch req.Method {
+	case "initialize":
+		return s.reply(req.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1,
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"referencesProvider": true,
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "gobasic"},
+		})
+
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(req.ID, nil)
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.update(params.TextDocument.URI, text)
+
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.write(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+			Params: publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}}})
+
+	case "textDocument/hover", "textDocument/definition", "textDocument/references":
+		var params positionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return s.fail(req.ID, codeInvalidParams, err.Error())
+		}
+		return s.reply(req.ID, s.query(req.Method, params))
+
+	case "textDocument/completion":
+		return s.reply(req.ID, s.completions())
+	}
+
+	// Notifications we don't understand are ignored, requests get
+	// an error.
+	if req.ID == nil {
+		return nil
+	}
+	return s.fail(req.ID, codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
+}
+
+// update records the new content of a document, and publishes its
+// diagnostics.
+func (s *Server) update(uri string, text string) error {
+	doc := newDocument(text, s.builtins)
+	s.documents[uri] = doc
+
+	return s.write(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+		Params: publishDiagnosticsParams{URI: uri, Diagnostics: doc.diagnostics(s.builtins)}})
+}
+
+// query handles the requests which concern a position in a document.
+func (s *Server) query(method string, params positionParams) interface{} {
+	uri := params.TextDocument.URI
+
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil
+	}
+	i := doc.find(params.Position)
+	if i < 0 {
+		return nil
+	}
+
+	switch method {
+	case "textDocument/hover":
+		tok := doc.items[i].tok
+		if tok.Type != token.BUILTIN {
+			return nil
+		}
+		r := doc.rangeOf(i)
+		return Hover{
+			Contents: MarkupContent{Kind: "markdown", Value: documentation(tok.Literal, s.builtins[tok.Literal])},
+			Range:    &r,
+		}
+
+	case "textDocument/definition":
+		def := doc.definition(i)
+		if def < 0 {
+			return nil
+		}
+		return Location{URI: uri, Range: doc.rangeOf(def)}
+
+	case "textDocument/references":
+		out := []Location{}
+		for _, j := range doc.references(i, params.Context.IncludeDeclaration) {
+			out = append(out, Location{URI: uri, Range: doc.rangeOf(j)})
+		}
+		return out
+	}
+	return nil
+}
+
+// completions returns the keywords, and built-in functions, which may
+// be completed.
+func (s *Server) completions() []CompletionItem {
+	out := []CompletionItem{}
+
+	for _, kw := range token.Keywords() {
+		out = append(out, CompletionItem{Label: strings.ToUpper(kw), Kind: kindKeyword, Detail: "keyword"})
+	}
+
+	// Each built-in is registered in both upper- and lower-case,
+	// we prefer upper-case unless that would be surprising; "π"
+	// is nicer than "Π".
+	seen := make(map[string]bool)
+	for name, n := range s.builtins {
+		label := strings.ToLower(name)
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		if isASCII(label) {
+			label = strings.ToUpper(label)
+		}
+		out = append(out, CompletionItem{
+			Label:         label,
+			Kind:          kindFunction,
+			Detail:        "built-in function",
+			Documentation: &MarkupContent{Kind: "markdown", Value: documentation(label, n)},
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Label != out[j].Label {
+			return out[i].Label < out[j].Label
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
+
+// isASCII returns true if the string contains only ASCII characters.
+func isASCII(s string) bool {
+	for _, c := range s {
+		if c > 127 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+// server_test.go - Test-cases for our language server.
+//
+// Each file beneath testdata/ is a recorded session; lines prefixed with
+// "-> " are sent to the server, and lines prefixed with "<- " are the
+// messages we expect it to send in reply, in order.
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// frame wraps a message with its Content-Length header.
+func frame(msg string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(msg), msg)
+}
+
+// messages splits the output of the server into its messages.
+func messages(t *testing.T, out string) []string {
+	var res []string
+	for out != "" {
+		var n int
+		if _, err := fmt.Sscanf(out, "Content-Length: %d\r\n\r\n", &n); err != nil {
+			t.Fatalf("bad header in %q: %s", out, err.Error())
+		}
+		body := out[strings.Index(out, "\r\n\r\n")+4:]
+		res = append(res, body[:n])
+		out = body[n:]
+	}
+	return res
+}
+
+// same returns true if the two messages contain the same JSON.
+func same(t *testing.T, a string, b string) bool {
+	var x, y interface{}
+	if err := json.Unmarshal([]byte(a), &x); err != nil {
+		t.Fatalf("invalid JSON %q: %s", a, err.Error())
+	}
+	if err := json.Unmarshal([]byte(b), &y); err != nil {
+		t.Fatalf("invalid JSON %q: %s", b, err.Error())
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// run sends the given messages to a new server, and returns its output.
+func run(t *testing.T, input ...string) []string {
+	var in, out bytes.Buffer
+	for _, msg := range input {
+		in.WriteString(frame(msg))
+	}
+
+	s, err := New(&in, &out)
+	if err != nil {
+		t.Fatalf("failed to create server: %s", err.Error())
+	}
+	if err := s.Run(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return messages(t, out.String())
+}
+
+// TestTranscripts replays each of our recorded sessions.
+func TestTranscripts(t *testing.T) {
+
+	files, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatalf("failed to find transcripts: %s", err.Error())
+	}
+	if len(files) == 0 {
+		t.Fatalf("no transcripts found")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", file, err.Error())
+		}
+
+		var input, expected []string
+		for _, line := range strings.Split(string(data), "\n") {
+			switch {
+			case strings.HasPrefix(line, "-> "):
+				input = append(input, line[3:])
+			case strings.HasPrefix(line, "<- "):
+				expected = append(expected, line[3:])
+			}
+		}
+
+		output := run(t, input...)
+		if len(output) != len(expected) {
+			t.Errorf("%s: expected %d messages, got %d:\n%s", file, len(expected), len(output), strings.Join(output, "\n"))
+			continue
+		}
+		for i := range output {
+			if !same(t, output[i], expected[i]) {
+				t.Errorf("%s: message %d\nexpected: %s\n     got: %s", file, i+1, expected[i], output[i])
+			}
+		}
+	}
+}
+
+// TestCompletion ensures that keywords and built-ins are completed.
+func TestCompletion(t *testing.T) {
+
+	out := run(t, `{"jsonrpc":"2.0","id":1,"method":"textDocument/completion","params":{}}`)
+	if len(out) != 1 {
+		t.Fatalf("expected one message, got %d", len(out))
+	}
+
+	var res struct {
+		Result []CompletionItem `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(out[0]), &res); err != nil {
+		t.Fatalf("invalid response: %s", err.Error())
+	}
+
+	found := make(map[string]int)
+	for _, item := range res.Result {
+		found[item.Label]++
+	}
+
+	for _, name := range []string{"GOTO", "FOR", "NEXT", "PRINT", "LEFT$", "π"} {
+		if found[name] != 1 {
+			t.Errorf("expected one completion for %s, got %d", name, found[name])
+		}
+	}
+	if found["goto"] != 0 || found["Π"] != 0 {
+		t.Errorf("found unexpected completions")
+	}
+}
+
+// TestBadInput ensures that broken input is handled.
+func TestBadInput(t *testing.T) {
+
+	// Invalid JSON results in an error, but the server continues.
+	out := run(t, `{"jsonrpc":`, `{"jsonrpc":"2.0","id":1,"method":"shutdown"}`)
+	if len(out) != 2 {
+		t.Fatalf("expected two messages, got %d", len(out))
+	}
+	if !strings.Contains(out[0], "-32700") {
+		t.Errorf("expected a parse error, got %s", out[0])
+	}
+
+	// A missing header is an error.
+	s, err := New(strings.NewReader("Content-Length: foo\r\n\r\n"), io.Discard)
+	if err != nil {
+		t.Fatalf("failed to create server: %s", err.Error())
+	}
+	if s.Run() == nil {
+		t.Errorf("expected an error with a bad header")
+	}
+}
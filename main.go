@@ -3,9 +3,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/prg"
+	"github.com/skx/gobasic/sound"
+	"github.com/skx/gobasic/terminal"
 	"github.com/skx/gobasic/token"
 	"github.com/skx/gobasic/tokenizer"
 )
@@ -13,14 +20,55 @@ import (
 // This version-string will be updated via travis for generated binaries.
 var version = "master/unreleased"
 
+// maxSound is the longest sound a program may make, which limits the
+// memory it is kept in.
+const maxSound = 10 * time.Minute
+
+// subcommands holds the commands which may be given as the first
+// argument, instead of the name of a program to run.
+//
+// Each is given the remaining arguments, and returns the exit-code.
+var subcommands = map[string]func(args []string) int{
+	"bas2prg": bas2prgCommand,
+	"cover":   coverCommand,
+	"fmt":     fmtCommand,
+	"lsp":     lspCommand,
+	"prg2bas": prg2basCommand,
+	"renum":   renumCommand,
+	"tap2bas": tap2basCommand,
+	"test":    testCommand,
+	"vet":     vetCommand,
+}
+
 func main() {
 
+	//
+	// Is this a sub-command?
+	//
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
+	//
+	// "gobasic run file" is the same as "gobasic file".
+	//
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	//
 	// Setup some command-line flags
 	//
+	dialect := flag.String("dialect", eval.Default.Name, "The dialect of BASIC to run, one of: "+strings.Join(eval.DialectNames(), ", ")+".")
+	coverage := flag.String("coverage", "", "Record the lines which are executed, merging them into the given lcov tracefile.")
 	lex := flag.Bool("lex", false, "Show the output of the lexer.")
+	profile := flag.String("profile", "", "Write a pprof profile of the program to the given file, and an annotated listing to STDERR.")
 	trace := flag.Bool("trace", false, "Trace execution.")
 	vers := flag.Bool("version", false, "Show our version and exit.")
+	tty := flag.Bool("terminal", false, "Allow the program to control the terminal, with CLS, LOCATE, PRINT AT, INK, PAPER, BRIGHT, CURSOR, and INKEY$.")
+	wav := flag.String("wav", "", "Write the sound made by BEEP, PLAY, and SOUND to the given WAV file.")
 
 	//
 	// Parse the flags
@@ -39,23 +87,43 @@ func main() {
 	// Test we have a file to interpret
 	//
 	if len(flag.Args()) != 1 {
-		fmt.Printf("Usage: gobasic /path/to/input/script.bas\n")
+		fmt.Printf("Usage: gobasic [run] [-dialect name] [-terminal] [-wav out.wav] /path/to/input/script.bas\n")
+		fmt.Printf("       gobasic [run] [-dialect name] /path/to/input/program.prg\n")
+		fmt.Printf("       gobasic bas2prg /path/to/input/script.bas\n")
+		fmt.Printf("       gobasic cover [-html report.html] [-o merged.info] coverage.info ..\n")
+		fmt.Printf("       gobasic fmt [-d] [-w] /path/to/input/script.bas ..\n")
+		fmt.Printf("       gobasic lsp\n")
+		fmt.Printf("       gobasic prg2bas /path/to/input/program.prg\n")
+		fmt.Printf("       gobasic renum [-start N] [-step N] [-from N] [-to N] [-w] /path/to/input/script.bas\n")
+		fmt.Printf("       gobasic tap2bas [-l] [-n name] /path/to/input/tape.tap\n")
+		fmt.Printf("       gobasic test [-update] [-v] [-timeout 10s] [/path/to/tests ..]\n")
+		fmt.Printf("       gobasic vet /path/to/input/script.bas ..\n")
+		os.Exit(2)
+	}
+
+	//
+	// Find the dialect the program is written in.
+	//
+	d, ok := eval.Dialects()[*dialect]
+	if !ok {
+		fmt.Printf("Unknown dialect %s, expected one of: %s\n", *dialect, strings.Join(eval.DialectNames(), ", "))
 		os.Exit(2)
 	}
 
 	//
 	// Load the file.
 	//
-	data, err := os.ReadFile(flag.Args()[0])
+	path := flag.Args()[0]
+	src, err := readSource(path)
 	if err != nil {
-		fmt.Printf("Error reading %s - %s\n", flag.Args()[0], err.Error())
+		fmt.Printf("Error reading %s - %s\n", path, err.Error())
 		os.Exit(3)
 	}
 
 	//
 	// Tokenize
 	//
-	t := tokenizer.New(string(data))
+	t := tokenizer.New(src)
 
 	//
 	// Are we dumping tokens?
@@ -74,7 +142,7 @@ func main() {
 	//
 	// Create a new evaluator, to run the BASIC program.
 	//
-	e, err := eval.New(t)
+	e, err := eval.NewWithDialect(t, d)
 	if err != nil {
 		fmt.Printf("Error constructing interpreter:\n\t%s\n", err.Error())
 		os.Exit(0)
@@ -86,10 +154,131 @@ func main() {
 	e.SetTrace(*trace)
 
 	//
-	// Run the code, and report on any error.
+	// Enable profiling if we should.
+	//
+	e.SetProfile(*profile != "")
+
+	//
+	// Measure coverage if we should.
+	//
+	e.SetCoverage(*coverage != "")
+
+	//
+	// Add the primitives which were asked for.
+	//
+	var keyboard io.Reader
+	if *tty {
+		keyboard = os.Stdin
+	}
+	synth, term := register(e, *wav != "", keyboard)
+
+	//
+	// Run the code, and report on any error, once the terminal is
+	// back to normal.
 	//
 	err = e.Run()
+	if term != nil {
+		term.Close()
+	}
 	if err != nil {
 		fmt.Printf("Error running program:\n\t%s\n", err.Error())
 	}
+
+	//
+	// Write the profile, even if the program failed.
+	//
+	if *profile != "" {
+		e.StdOutput().Flush()
+		if err := writeProfile(e.Profile(), *profile, path, src); err != nil {
+			fmt.Printf("Error writing profile:\n\t%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	//
+	// Write the sound, even if the program failed.
+	//
+	if *wav != "" {
+		e.StdOutput().Flush()
+		if err := writeSound(synth, *wav); err != nil {
+			fmt.Printf("Error writing sound:\n\t%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	//
+	// Record the coverage, even if the program failed.
+	//
+	if *coverage != "" {
+		if err := writeCoverage(e.Coverage(), *coverage, path); err != nil {
+			fmt.Printf("Error writing coverage:\n\t%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+}
+
+// register makes the primitives which must be asked for available to the
+// given interpreter, as their names become keywords, which programs can't
+// use for variables.
+//
+// If we're to write a WAV file the program may make sounds, which we keep
+// in memory, as there's nothing to hear them with, and if it's given a
+// keyboard it may control the terminal, and read keys as they're pressed.
+// What it was given is returned.
+func register(e *eval.Interpreter, wav bool, keyboard io.Reader) (*sound.Synth, *terminal.Terminal) {
+	var synth *sound.Synth
+	if wav {
+		synth = sound.Register(e, sound.Options{MaxDuration: maxSound})
+	}
+
+	var t *terminal.Terminal
+	if keyboard != nil {
+		t = terminal.Register(e, terminal.Options{Keyboard: keyboard})
+	}
+	return synth, t
+}
+
+// readSource returns the source of the program at the given path.
+//
+// Commodore programs are tokenised, so they're converted to source.
+func readSource(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".prg") {
+		return prg.Detokenize(data)
+	}
+	return string(data), nil
+}
+
+// writeSound writes the sound a program made to the given WAV file.
+func writeSound(synth *sound.Synth, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := synth.WriteWAV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeProfile writes the given profile, of the program at the given path,
+// to a file in the pprof format, and writes an annotated listing of its
+// source to STDERR.
+func writeProfile(p *eval.Profile, out string, path string, src string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err = p.WritePprof(f, path); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return p.WriteListing(os.Stderr, src)
 }
@@ -0,0 +1,46 @@
+// main_test.go - Test-cases for running programs.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/internal/evaltest"
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/sound"
+	"github.com/skx/gobasic/terminal"
+)
+
+// TestRegister tests that the primitives which must be asked for don't
+// take names programs would otherwise use.
+func TestRegister(t *testing.T) {
+	src := "10 LET at = 3\n20 LET beep = at + 1\n30 LET cls = beep * 2\n"
+
+	var synth *sound.Synth
+	var term *terminal.Terminal
+	e, err := evaltest.Run(t, src, func(e *eval.Interpreter) {
+		synth, term = register(e, false, nil)
+	})
+	if synth != nil || term != nil {
+		t.Errorf("Unexpected primitives %v %v", synth, term)
+	}
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+	if got := e.GetVariable("cls").(*object.NumberObject).Value; got != 8 {
+		t.Errorf("cls was %v, not 8", got)
+	}
+
+	// Once they're asked for they are keywords.
+	_, err = evaltest.Run(t, src, func(e *eval.Interpreter) {
+		synth, term = register(e, true, strings.NewReader(""))
+	})
+	if synth == nil || term == nil {
+		t.Fatalf("Missing primitives %v %v", synth, term)
+	}
+	if err == nil || !strings.Contains(err.Error(), "expected IDENT after LET") {
+		t.Errorf("Expected an error assigning to AT, got %v", err)
+	}
+}
@@ -82,38 +82,46 @@ func Array(x int, y int) *ArrayObject {
 
 // Get the value at the given X,Y coordinate
 func (a *ArrayObject) Get(x int, y int) Object {
-	offset := int(x*a.X + y)
+	// Each row of a two-dimensional array holds Y entries.
+	offset := y
+	if a.X != 0 {
+		offset = x*a.Y + y
+	}
 
 	if a.X == 0 && offset >= a.Y {
-		return &ErrorObject{Value: "Get-Array access out of bounds (Y)"}
+		return &ErrorObject{Value: "Get-Array access out of bounds (Y)", Code: ErrSubscript}
 	}
-	if (a.X != 0) && (offset > a.X*a.Y) {
-		return &ErrorObject{Value: "Get-Array access out of bounds (X,Y)"}
+	if (a.X != 0) && (x >= a.X || y >= a.Y) {
+		return &ErrorObject{Value: "Get-Array access out of bounds (X,Y)", Code: ErrSubscript}
 	}
-	if offset < 0 {
-		return &ErrorObject{Value: "Get-Array access out of bounds (negative index)"}
+	if y < 0 || (a.X != 0 && x < 0) {
+		return &ErrorObject{Value: "Get-Array access out of bounds (negative index)", Code: ErrSubscript}
 	}
 	if offset > len(a.Contents) {
-		return &ErrorObject{Value: "Get-Array access out of bounds (LEN)"}
+		return &ErrorObject{Value: "Get-Array access out of bounds (LEN)", Code: ErrSubscript}
 	}
 	return (a.Contents[offset])
 }
 
 // Set the value at the given X,Y coordinate
 func (a *ArrayObject) Set(x int, y int, obj Object) Object {
-	offset := int(x*a.X + y)
+	// Each row of a two-dimensional array holds Y entries.
+	offset := y
+	if a.X != 0 {
+		offset = x*a.Y + y
+	}
 
 	if a.X == 0 && offset >= a.Y {
-		return &ErrorObject{Value: "Set-Array access out of bounds (Y)"}
+		return &ErrorObject{Value: "Set-Array access out of bounds (Y)", Code: ErrSubscript}
 	}
-	if (a.X != 0) && (offset > a.X*a.Y) {
-		return &ErrorObject{Value: "Set-Array access out of bounds (X,Y)"}
+	if (a.X != 0) && (x >= a.X || y >= a.Y) {
+		return &ErrorObject{Value: "Set-Array access out of bounds (X,Y)", Code: ErrSubscript}
 	}
-	if offset < 0 {
-		return &ErrorObject{Value: "Set-Array access out of bounds (negative index)"}
+	if y < 0 || (a.X != 0 && x < 0) {
+		return &ErrorObject{Value: "Set-Array access out of bounds (negative index)", Code: ErrSubscript}
 	}
 	if offset > len(a.Contents) {
-		return &ErrorObject{Value: "Set-Array access out of bounds (LEN)"}
+		return &ErrorObject{Value: "Set-Array access out of bounds (LEN)", Code: ErrSubscript}
 	}
 
 	a.Contents[offset] = obj
@@ -167,11 +175,33 @@ func (no *NumberObject) Type() Type {
 	return NUMBER
 }
 
+// Error codes.
+//
+// Each error carries a numeric code, which BASIC programs can inspect via
+// ERR when they trap errors with ON ERROR GOTO.  The values are stable, and
+// follow those used by Microsoft BASIC where there is an equivalent.
+const (
+	ErrNextWithoutFor     = 1
+	ErrSyntax             = 2
+	ErrReturnWithoutGosub = 3
+	ErrOutOfData          = 4
+	ErrIllegalFunction    = 5
+	ErrUndefinedLine      = 8
+	ErrSubscript          = 9
+	ErrDivisionByZero     = 11
+	ErrTypeMismatch       = 13
+	ErrUndefinedFunction  = 18
+	ErrResumeWithoutError = 20
+)
+
 // ErrorObject holds a string, which describes an error
 type ErrorObject struct {
 
 	// Value is the message our object wraps.
 	Value string
+
+	// Code is the numeric error-code, one of the Err* constants.
+	Code int
 }
 
 // String returns a string representation of this object.
@@ -189,9 +219,17 @@ func (eo *ErrorObject) Type() Type {
 //
 
 // Error is a helper for creating a new error-object with the given message.
+//
+// The error will have the generic code ErrIllegalFunction.
 func Error(format string, args ...interface{}) *ErrorObject {
+	return CodedError(ErrIllegalFunction, format, args...)
+}
+
+// CodedError is a helper for creating a new error-object with the given
+// error-code and message.
+func CodedError(code int, format string, args ...interface{}) *ErrorObject {
 	msg := fmt.Sprintf(format, args...)
-	return &ErrorObject{Value: msg}
+	return &ErrorObject{Value: msg, Code: code}
 }
 
 // Number is a helper for creating a new number-object with the given value.
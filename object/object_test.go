@@ -101,6 +101,8 @@ func Test2DArray(t *testing.T) {
 	err := a.Get(6, 4)
 	if err.Type() != ERROR {
 		t.Errorf("Expected error - got none!")
+	} else if err.(*ErrorObject).Code != ErrSubscript {
+		t.Errorf("Wrong code for out of bounds error")
 	}
 
 	// Set an out of bounds entry
@@ -134,6 +136,39 @@ func Test2DArray(t *testing.T) {
 
 }
 
+// TestRectangularArray ensures that arrays which aren't square keep each
+// entry apart, and refuse indexes beyond either dimension.
+func TestRectangularArray(t *testing.T) {
+
+	// Three rows, of two columns.
+	a := Array(2, 1)
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 2; j++ {
+			if e := a.Set(i, j, Number(float64(i*10+j))); e.Type() == ERROR {
+				t.Fatalf("Unexpected error setting %d,%d: %s", i, j, e.String())
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 2; j++ {
+			actual := a.Get(i, j)
+			if actual.Type() != NUMBER || actual.(*NumberObject).Value != float64(i*10+j) {
+				t.Errorf("Wrong value for %d,%d: %s", i, j, actual.String())
+			}
+		}
+	}
+
+	for _, index := range [][2]int{{3, 0}, {0, 2}, {1, -1}, {-1, 1}} {
+		if e := a.Get(index[0], index[1]); e.Type() != ERROR {
+			t.Errorf("Expected an error getting %v, got %s", index, e.String())
+		}
+		if e := a.Set(index[0], index[1], Number(1)); e.Type() != ERROR {
+			t.Errorf("Expected an error setting %v, got %s", index, e.String())
+		}
+	}
+}
+
 func TestError(t *testing.T) {
 
 	a := Error("Test")
@@ -161,6 +196,15 @@ func TestError(t *testing.T) {
 	if c.Value != "Test me" {
 		t.Errorf("Wrong value for error-message")
 	}
+
+	// Test codes
+	if a.Code != ErrIllegalFunction {
+		t.Errorf("Wrong default code for error")
+	}
+	d := CodedError(ErrDivisionByZero, "Division by %s", "zero")
+	if d.Code != ErrDivisionByZero || d.Value != "Division by zero" {
+		t.Errorf("Wrong code/value for coded error: %v", d)
+	}
 }
 
 func TestNumber(t *testing.T) {
@@ -0,0 +1,395 @@
+// Package prg converts programs to, and from, the tokenised ".PRG" format
+// used by Commodore BASIC v2, as found upon the C64.
+//
+// A PRG file begins with a two-byte little-endian load address, which is
+// normally 0x0801 for BASIC programs.  Each line follows as a two-byte
+// link to the address of the next line, a two-byte line-number, the text
+// of the line, and a zero byte.  A link of zero marks the end of the
+// program.
+//
+// Within a line keywords, and operators, are stored as single bytes from
+// 0x80 upwards, while everything else is stored in PETSCII.  We use the
+// same convention as most conversion tools: unshifted letters become
+// lower-case, and shifted letters upper-case.  Outside strings shifted
+// letters would be read as keywords, so names are always lower-case.
+// Characters which have no equivalent are written, within strings and
+// comments, as "\uXXXX" escapes, except for RETURN which is "\n".
+package prg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BasicStart is the address at which BASIC programs are loaded.
+const BasicStart = 0x0801
+
+// maxLine is the largest line-number which BASIC v2 allows.
+const maxLine = 63999
+
+// keywords holds the text of the tokens, starting from 0x80.
+var keywords = [...]string{
+	"END", "FOR", "NEXT", "DATA", "INPUT#", "INPUT", "DIM", "READ", "LET",
+	"GOTO", "RUN", "IF", "RESTORE", "GOSUB", "RETURN", "REM", "STOP", "ON",
+	"WAIT", "LOAD", "SAVE", "VERIFY", "DEF", "POKE", "PRINT#", "PRINT",
+	"CONT", "LIST", "CLR", "CMD", "SYS", "OPEN", "CLOSE", "GET", "NEW",
+	"TAB(", "TO", "FN", "SPC(", "THEN", "NOT", "STEP", "+", "-", "*", "/",
+	"^", "AND", "OR", ">", "=", "<", "SGN", "INT", "ABS", "USR", "FRE",
+	"POS", "SQR", "RND", "LOG", "EXP", "COS", "SIN", "TAN", "ATN", "PEEK",
+	"LEN", "STR$", "VAL", "ASC", "CHR$", "LEFT$", "RIGHT$", "MID$", "GO",
+}
+
+// The tokens which change how the rest of a line is stored.
+const (
+	firstKeyword = 0x80
+	dataToken    = 0x83
+	remToken     = 0x8F
+	pi           = 0xFF
+)
+
+// Detokenize converts the contents of a PRG file into BASIC source.
+func Detokenize(data []byte) (string, error) {
+	if len(data) < 2 {
+		return "", fmt.Errorf("the load address is missing")
+	}
+
+	var out strings.Builder
+
+	offset := 2
+	for offset < len(data) {
+		if offset+2 > len(data) {
+			return "", fmt.Errorf("offset %d: truncated link", offset)
+		}
+		if word(data[offset:]) == 0 {
+			break
+		}
+		if offset+4 > len(data) {
+			return "", fmt.Errorf("offset %d: truncated line-number", offset)
+		}
+		n := word(data[offset+2:])
+		offset += 4
+
+		end := offset
+		for end < len(data) && data[end] != 0 {
+			end++
+		}
+		if end >= len(data) {
+			return "", fmt.Errorf("line %d: missing the end of the line", n)
+		}
+
+		text, err := detokenizeLine(data[offset:end])
+		if err != nil {
+			return "", fmt.Errorf("line %d: %s", n, err.Error())
+		}
+		offset = end + 1
+
+		fmt.Fprintf(&out, "%d %s\n", n, text)
+	}
+
+	return out.String(), nil
+}
+
+// detokenizeLine converts the text of a single line.
+func detokenizeLine(data []byte) (string, error) {
+	var out []byte
+
+	// space is true if we've written a keyword which must be
+	// separated from any name, or number, which follows it.
+	space := false
+
+	quote := false
+	rem := false
+	inData := false
+
+	for i, c := range data {
+
+		// Strings and comments are never tokenised, so they may
+		// contain any character.
+		if quote || rem {
+			if c == '"' && !rem {
+				quote = false
+			}
+			text := literal(c)
+			if space && isWord(text[0]) {
+				out = append(out, ' ')
+			}
+			space = false
+			out = append(out, text...)
+			continue
+		}
+
+		if c == '"' {
+			quote = true
+		}
+		if inData && c == ':' {
+			inData = false
+		}
+
+		if c >= firstKeyword && c < firstKeyword+byte(len(keywords)) && !inData {
+			k := keywords[c-firstKeyword]
+			if isWord(k[0]) && len(out) > 0 && isWord(out[len(out)-1]) {
+				out = append(out, ' ')
+			}
+			out = append(out, k...)
+			space = isWord(k[len(k)-1])
+			rem = c == remToken
+			inData = c == dataToken
+			continue
+		}
+
+		r, ok := fromPETSCII(c)
+		if !ok {
+			if inData {
+				out = append(out, literal(c)...)
+				continue
+			}
+			return "", fmt.Errorf("unexpected byte 0x%02X at offset %d", c, i)
+		}
+		if space && (r == '"' || (r < 0x80 && isWord(byte(r)))) {
+			out = append(out, ' ')
+		}
+		space = false
+		out = append(out, string(r)...)
+	}
+
+	return string(out), nil
+}
+
+// literal returns the text of a character within a string, or comment,
+// using an escape if it has no equivalent.
+func literal(c byte) string {
+	if r, ok := fromPETSCII(c); ok {
+		return string(r)
+	}
+	if c == 0x0D {
+		return "\\n"
+	}
+	return fmt.Sprintf("\\u%04X", c)
+}
+
+// Tokenize converts BASIC source into the contents of a PRG file, which
+// will be loaded at BasicStart.
+//
+// Every line must have a line-number, and they must be in ascending
+// order.  Keywords are recognized regardless of case, but only when they
+// are whole words, so that a variable such as "total" isn't read as "TO"
+// followed by "tal".
+func Tokenize(src string) ([]byte, error) {
+	out := []byte{byte(BasicStart & 0xFF), byte(BasicStart >> 8)}
+
+	prev := -1
+	for i, text := range strings.Split(src, "\n") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		j := 0
+		for j < len(text) && isDigit(text[j]) {
+			j++
+		}
+		if j == 0 {
+			return nil, fmt.Errorf("line %d: there is no line-number", i+1)
+		}
+		n, err := strconv.Atoi(text[:j])
+		if err != nil || n > maxLine {
+			return nil, fmt.Errorf("line %d: line-number %s is larger than %d", i+1, text[:j], maxLine)
+		}
+		if n <= prev {
+			return nil, fmt.Errorf("line %d: line-number %d follows %d", i+1, n, prev)
+		}
+		prev = n
+
+		body, err := tokenizeLine(strings.TrimLeft(text[j:], " \t"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err.Error())
+		}
+
+		// The link is the address of the next line.
+		link := BasicStart + len(out) - 2 + 4 + len(body) + 1
+		if link > 0xFFFF {
+			return nil, fmt.Errorf("line %d: the program is too large", i+1)
+		}
+		out = append(out, byte(link), byte(link>>8), byte(n), byte(n>>8))
+		out = append(out, body...)
+		out = append(out, 0)
+	}
+
+	return append(out, 0, 0), nil
+}
+
+// tokenizeLine converts the text of a single line.
+func tokenizeLine(text string) ([]byte, error) {
+	var out []byte
+
+	quote := false
+	rem := false
+	inData := false
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if (quote || rem || inData) && r == '\\' {
+			c, n, err := escape(runes[i:])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, c)
+			i += n - 1
+			continue
+		}
+
+		if quote && r == '"' {
+			if i+1 < len(runes) && runes[i+1] == '"' {
+				return nil, fmt.Errorf("strings cannot contain quotes")
+			}
+			quote = false
+		} else if !rem && r == '"' {
+			quote = true
+		} else if inData && r == ':' {
+			inData = false
+		} else if !quote && !rem && !inData {
+			if t, n := keyword(runes, i); n > 0 {
+				out = append(out, t)
+				i += n - 1
+				rem = t == remToken
+				inData = t == dataToken
+				continue
+			}
+		}
+
+		// Outside strings, comments, and DATA, shifted letters would
+		// be read as keywords, so names are always unshifted.
+		if !quote && !rem && !inData && r >= 'A' && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+
+		c, ok := toPETSCII(r)
+		if !ok {
+			return nil, fmt.Errorf("the character %q has no PETSCII equivalent", r)
+		}
+		out = append(out, c)
+	}
+
+	return out, nil
+}
+
+// keyword returns the token for the keyword, if any, at the given
+// position, along with its length.
+func keyword(runes []rune, i int) (byte, int) {
+
+	// A keyword can't start in the middle of a name.
+	after := i > 0 && runes[i-1] < 0x80 && isWord(byte(runes[i-1]))
+
+	for t, k := range keywords {
+		if len(k) > len(runes)-i || (after && isWord(k[0])) {
+			continue
+		}
+		if !strings.EqualFold(string(runes[i:i+len(k)]), k) {
+			continue
+		}
+		end := i + len(k)
+		if isLetter(k[len(k)-1]) && end < len(runes) && runes[end] < 0x80 && isWord(byte(runes[end])) {
+			continue
+		}
+		return byte(firstKeyword + t), len(k)
+	}
+	if runes[i] == 'π' {
+		return pi, 1
+	}
+	return 0, 0
+}
+
+// escape decodes the escape at the start of the given runes, returning
+// the byte it represents and its length.
+func escape(runes []rune) (byte, int, error) {
+	if len(runes) >= 2 && runes[1] == 'n' {
+		return 0x0D, 2, nil
+	}
+	if len(runes) >= 6 && runes[1] == 'u' {
+		v, err := strconv.ParseUint(string(runes[2:6]), 16, 16)
+		if err == nil && v <= 0xFF {
+			return byte(v), 6, nil
+		}
+	}
+	if len(runes) > 6 {
+		runes = runes[:6]
+	}
+	return 0, 0, fmt.Errorf("unsupported escape %q", string(runes))
+}
+
+// fromPETSCII returns the character a PETSCII byte represents.
+func fromPETSCII(c byte) (rune, bool) {
+	switch {
+	case c >= 0x20 && c <= 0x40:
+		return rune(c), true
+	case c >= 0x41 && c <= 0x5A:
+		return rune(c) + 'a' - 'A', true
+	case c >= 0xC1 && c <= 0xDA:
+		return rune(c - 0x80), true
+	case c == pi:
+		return 'π', true
+	}
+	switch c {
+	case 0x5B:
+		return '[', true
+	case 0x5C:
+		return '£', true
+	case 0x5D:
+		return ']', true
+	case 0x5E:
+		return '^', true
+	case 0x5F:
+		return '←', true
+	}
+	return 0, false
+}
+
+// toPETSCII returns the PETSCII byte for a character.
+func toPETSCII(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r <= 0x40:
+		return byte(r), true
+	case r >= 'a' && r <= 'z':
+		return byte(r - 'a' + 'A'), true
+	case r >= 'A' && r <= 'Z':
+		return byte(r + 0x80), true
+	}
+	switch r {
+	case '[':
+		return 0x5B, true
+	case '£':
+		return 0x5C, true
+	case ']':
+		return 0x5D, true
+	case '^', '↑':
+		return 0x5E, true
+	case '←':
+		return 0x5F, true
+	case 'π':
+		return pi, true
+	}
+	return 0, false
+}
+
+// word returns the little-endian 16-bit value at the start of the slice.
+func word(b []byte) int {
+	return int(b[0]) | int(b[1])<<8
+}
+
+// isDigit returns true if the character is a digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isLetter returns true if the character is a letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isWord returns true if the character may be part of a name.
+func isWord(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '$'
+}
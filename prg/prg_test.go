@@ -0,0 +1,198 @@
+// prg_test.go - Test-cases for converting PRG files.
+
+package prg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// fixture returns a PRG file containing the given lines, each of which
+// holds a line-number followed by the bytes of its text.
+func fixture(lines ...[]byte) []byte {
+	out := []byte{0x01, 0x08}
+	for _, l := range lines {
+		link := BasicStart + len(out) - 2 + 2 + len(l) + 1
+		out = append(out, byte(link), byte(link>>8))
+		out = append(out, l...)
+		out = append(out, 0)
+	}
+	return append(out, 0, 0)
+}
+
+// TestDetokenize tests converting programs saved by a C64.
+func TestDetokenize(t *testing.T) {
+
+	type Test struct {
+		Input  []byte
+		Output string
+	}
+
+	tests := []Test{
+		// 10 PRINT "HELLO"
+		{Input: []byte{10, 0, 0x99, '"', 'H', 'E', 'L', 'L', 'O', '"'},
+			Output: `10 PRINT "hello"`},
+
+		// 20 FORI=1TO10:NEXT
+		{Input: []byte{20, 0, 0x81, 'I', 0xB2, '1', 0xA4, '1', '0', ':', 0x82},
+			Output: "20 FOR i=1 TO 10:NEXT"},
+
+		// 30 IFA<>BTHENGOTO10
+		{Input: []byte{30, 0, 0x8B, 'A', 0xB3, 0xB1, 'B', 0xA7, 0x89, '1', '0'},
+			Output: "30 IF a<>b THEN GOTO 10"},
+
+		// Comments and DATA aren't tokenised, shifted letters are
+		// upper-case, and anything else is escaped.
+		{Input: []byte{40, 0, 0x8F, 0xC8, 'I', ' ', 0x93},
+			Output: `40 REM Hi \u0093`},
+		{Input: []byte{50, 0, 0x83, 0xC1, 'B', ',', '"', 0x99, '"', ':', 0x99},
+			Output: `50 DATA Ab,"\u0099":PRINT`},
+		{Input: []byte{60, 0, 0x99, '"', 0x05, 0x0D, '"', ';', 0xFF},
+			Output: `60 PRINT "\u0005\n";π`},
+		{Input: []byte{0x39, 0x30, 0x99, 0xC7, '(', '6', '5', ')'},
+			Output: "12345 PRINT CHR$(65)"},
+	}
+
+	for _, test := range tests {
+		out, err := Detokenize(fixture(test.Input))
+		if err != nil {
+			t.Errorf("unexpected error converting % X: %s", test.Input, err.Error())
+			continue
+		}
+		if out != test.Output+"\n" {
+			t.Errorf("converting % X gave %q, expected %q", test.Input, out, test.Output)
+		}
+	}
+}
+
+// TestTokenize tests the bytes we produce.
+func TestTokenize(t *testing.T) {
+
+	out, err := Tokenize("10 PRINT \"HI\"\n\n20 goto 10\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := []byte{
+		0x01, 0x08,
+		0x0C, 0x08, 10, 0, 0x99, ' ', '"', 0xC8, 0xC9, '"', 0,
+		0x15, 0x08, 20, 0, 0x89, ' ', '1', '0', 0,
+		0, 0,
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("unexpected output\n% X\nexpected\n% X", out, expected)
+	}
+
+	// Outside strings letters are unshifted, whatever their case.
+	out, err = Tokenize("10 LET A = 1E3")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	src, err := Detokenize(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if src != "10 LET a = 1e3\n" {
+		t.Errorf("unexpected source %q", src)
+	}
+}
+
+// TestRoundTrip ensures that converting a program to a PRG file, and
+// back again, leaves it unchanged.
+func TestRoundTrip(t *testing.T) {
+
+	src := `10 REM Hello, World
+20 LET a$ = "Hi \u0093there\n"
+30 FOR i = 1 TO 10 STEP 2
+40 PRINT CHR$(65), LEFT$(a$, 2)
+50 NEXT i
+60 DATA 1, "two", Three
+70 IF a$ <> "" THEN GOTO 10
+80 total = π * 2 + 1e-5
+90 END
+`
+	data, err := Tokenize(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	out, err := Detokenize(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if out != src {
+		t.Errorf("round-trip gave\n%s\nexpected\n%s", out, src)
+	}
+}
+
+// TestErrors tests that problems are reported.
+func TestErrors(t *testing.T) {
+
+	programs := map[string]string{
+		"no line-number":       "PRINT 3\n",
+		"follows 20":           "20 END\n10 END\n",
+		"larger than 63999":    "64000 END\n",
+		"cannot contain quote": "10 PRINT \"a\"\"b\"\n",
+		"no PETSCII":           "10 PRINT {}\n",
+		"unsupported escape":   "10 PRINT \"\\t\"\n",
+	}
+	for expected, src := range programs {
+		_, err := Tokenize(src)
+		if err == nil {
+			t.Errorf("expected an error containing %q", expected)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q doesn't contain %q", err.Error(), expected)
+		}
+	}
+
+	files := map[string][]byte{
+		"load address":    {0x01},
+		"truncated link":  {0x01, 0x08, 0x0E},
+		"truncated line":  {0x01, 0x08, 0x0E, 0x08, 10},
+		"missing the end": {0x01, 0x08, 0x0E, 0x08, 10, 0, 0x99},
+		"byte 0x0D":       fixture([]byte{10, 0, 0x99, 0x0D}),
+		"byte 0xE0":       fixture([]byte{10, 0, 0xE0}),
+	}
+	for expected, data := range files {
+		_, err := Detokenize(data)
+		if err == nil {
+			t.Errorf("expected an error containing %q", expected)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q doesn't contain %q", err.Error(), expected)
+		}
+	}
+}
+
+// TestRun ensures a converted program may be run.
+func TestRun(t *testing.T) {
+
+	// 10 A=0:FORI=1TO4:A=A+I:NEXT
+	data := fixture([]byte{10, 0, 'A', 0xB2, '0', ':', 0x81, 'I', 0xB2, '1', 0xA4, '4', ':',
+		'A', 0xB2, 'A', 0xAA, 'I', ':', 0x82, 'I'})
+
+	src, err := Detokenize(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	e, err := eval.New(tokenizer.New(src))
+	if err != nil {
+		t.Fatalf("error parsing %q: %s", src, err.Error())
+	}
+	if err = e.Run(); err != nil {
+		t.Fatalf("error running %q: %s", src, err.Error())
+	}
+
+	a := e.GetVariable("a")
+	if a.Type() != object.NUMBER || a.(*object.NumberObject).Value != 10 {
+		t.Errorf("unexpected result %s", a.String())
+	}
+}
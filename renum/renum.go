@@ -0,0 +1,467 @@
+// Package renum contains the code to renumber a BASIC program.
+//
+// Renumbering is performed upon the source text, rather than upon a
+// stream of tokens, so that comments, spacing, and the case of keywords
+// are all preserved.  The only things which change are the line-numbers
+// themselves, and the references to them which follow GOTO, GOSUB, THEN,
+// ELSE, and RESUME.
+//
+// If the program refers to a line which doesn't exist, or contains a
+// computed jump whose target can't be determined, we refuse to renumber
+// it rather than produce a program which behaves differently.
+package renum
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options controls how a program is renumbered.
+type Options struct {
+
+	// Start is the first line-number to allocate.
+	Start int
+
+	// Step is the increment between successive line-numbers.
+	Step int
+
+	// From is the lowest existing line-number which will be renumbered.
+	From int
+
+	// To is the highest existing line-number which will be renumbered,
+	// zero means there is no upper limit.
+	To int
+}
+
+// DefaultOptions returns the options we use by default, which renumber
+// the whole program as 10, 20, 30, ..
+func DefaultOptions() Options {
+	return Options{Start: 10, Step: 10}
+}
+
+// Error describes a problem which prevents a program from being renumbered.
+type Error struct {
+
+	// Line is the line of the source, counting from one.
+	Line int
+
+	// Column is the column of the source, counting from one.
+	Column int
+
+	// Message describes the problem.
+	Message string
+}
+
+// Error returns the error, as a string.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// line holds the details of a single line of our source.
+type line struct {
+
+	// text is the text of the line, without any trailing newline.
+	text string
+
+	// number is the line-number, or -1 if the line has none.
+	number int
+
+	// start and end are the offsets of the line-number within text.
+	start int
+	end   int
+}
+
+// reference describes a line-number which is used as a jump-target.
+type reference struct {
+
+	// line is the index of the line containing the reference.
+	line int
+
+	// start and end are the offsets of the target within the line.
+	start int
+	end   int
+
+	// keyword is the statement which made the reference, for
+	// error-reporting.
+	keyword string
+
+	// target is the line-number which is referred to.
+	target int
+}
+
+// Renumber renumbers the given program, returning the updated source.
+func Renumber(src string, opts Options) (string, error) {
+
+	if opts.Start <= 0 {
+		return "", fmt.Errorf("the starting line-number must be positive")
+	}
+	if opts.Step <= 0 {
+		return "", fmt.Errorf("the line-number increment must be positive")
+	}
+	if opts.To != 0 && opts.To < opts.From {
+		return "", fmt.Errorf("the line-range %d-%d is empty", opts.From, opts.To)
+	}
+
+	//
+	// Split the program into lines, and find the line-numbers
+	// and labels they contain.
+	//
+	texts := strings.Split(src, "\n")
+	lines := make([]line, len(texts))
+	labels := make(map[string]bool)
+	seen := make(map[int]int)
+
+	for i, text := range texts {
+		lines[i] = parseLine(text)
+
+		if name := labelOf(text, lines[i].end); name != "" {
+			labels[strings.ToLower(name)] = true
+		}
+
+		n := lines[i].number
+		if n < 0 {
+			continue
+		}
+		if prev, ok := seen[n]; ok {
+			return "", &Error{Line: i + 1, Column: lines[i].start + 1,
+				Message: fmt.Sprintf("line %d is duplicated, it was first defined upon line %d", n, prev+1)}
+		}
+		seen[n] = i
+	}
+
+	//
+	// Now find all the references to line-numbers, ensuring that
+	// they all refer to lines which exist.
+	//
+	var refs []reference
+	for i, l := range lines {
+		found, err := references(l.text, l.end, labels)
+		if err != nil {
+			err.Line = i + 1
+			return "", err
+		}
+		for _, r := range found {
+			if _, ok := seen[r.target]; !ok {
+				return "", &Error{Line: i + 1, Column: r.start + 1,
+					Message: fmt.Sprintf("%s refers to line %d, which doesn't exist", r.keyword, r.target)}
+			}
+			r.line = i
+			refs = append(refs, r)
+		}
+	}
+
+	//
+	// Work out the new number of each line.
+	//
+	mapping := make(map[int]int)
+	ascending := true
+	prev := -1
+	next := opts.Start
+	for _, l := range lines {
+		if l.number < 0 {
+			continue
+		}
+		if l.number <= prev {
+			ascending = false
+		}
+		prev = l.number
+
+		if l.number >= opts.From && (opts.To == 0 || l.number <= opts.To) {
+			mapping[l.number] = next
+			next += opts.Step
+		} else {
+			mapping[l.number] = l.number
+		}
+	}
+
+	//
+	// The new numbers must be unique, and if the lines were in order
+	// before they must remain so.
+	//
+	used := make(map[int]int)
+	prev = -1
+	for i, l := range lines {
+		if l.number < 0 {
+			continue
+		}
+		n := mapping[l.number]
+		if other, ok := used[n]; ok {
+			return "", &Error{Line: i + 1, Column: l.start + 1,
+				Message: fmt.Sprintf("renumbering would give line %d the same number as line %d", l.number, lines[other].number)}
+		}
+		used[n] = i
+
+		if ascending && n <= prev {
+			return "", &Error{Line: i + 1, Column: l.start + 1,
+				Message: fmt.Sprintf("renumbering line %d as %d would move it out of order", l.number, n)}
+		}
+		prev = n
+	}
+
+	//
+	// Finally rewrite the lines, working backwards through each
+	// so that the offsets we've recorded remain valid.  Numbers
+	// which are unchanged are left alone, so "05" remains "05".
+	//
+	type edit struct {
+		start, end int
+		value      int
+	}
+	edits := make([][]edit, len(lines))
+	for i, l := range lines {
+		if l.number >= 0 && mapping[l.number] != l.number {
+			edits[i] = append(edits[i], edit{l.start, l.end, mapping[l.number]})
+		}
+	}
+	for _, r := range refs {
+		if mapping[r.target] != r.target {
+			edits[r.line] = append(edits[r.line], edit{r.start, r.end, mapping[r.target]})
+		}
+	}
+
+	for i := range lines {
+		sort.Slice(edits[i], func(a, b int) bool {
+			return edits[i][a].start > edits[i][b].start
+		})
+		text := lines[i].text
+		for _, e := range edits[i] {
+			text = text[:e.start] + strconv.Itoa(e.value) + text[e.end:]
+		}
+		texts[i] = text
+	}
+
+	return strings.Join(texts, "\n"), nil
+}
+
+// parseLine finds the line-number, if any, at the start of the given line.
+func parseLine(text string) line {
+	l := line{text: text, number: -1}
+
+	i := skipSpace(text, 0)
+	j := i
+	for j < len(text) && isDigit(text[j]) {
+		j++
+	}
+	if j == i {
+		return l
+	}
+
+	n, err := strconv.Atoi(text[i:j])
+	if err != nil {
+		return l
+	}
+	l.number = n
+	l.start = i
+	l.end = j
+	return l
+}
+
+// labelOf returns the name of the label defined upon the given line, if
+// any, which is either "name:" or "*name".
+func labelOf(text string, offset int) string {
+	i := skipSpace(text, offset)
+
+	star := false
+	if i < len(text) && text[i] == '*' {
+		star = true
+		i++
+	}
+	if i >= len(text) || !isLetter(text[i]) {
+		return ""
+	}
+	j := i
+	for j < len(text) && isWord(text[j]) {
+		j++
+	}
+	if star {
+		return text[i:j]
+	}
+	if j < len(text) && text[j] == ':' {
+		return text[i:j]
+	}
+	return ""
+}
+
+// references returns the jump-targets which are found in the given line,
+// starting from the given offset.
+func references(text string, offset int, labels map[string]bool) ([]reference, *Error) {
+	var refs []reference
+
+	previous := ""
+	i := offset
+	for i < len(text) {
+		c := text[i]
+
+		switch {
+		case c == '"':
+
+			// Skip strings, handling escaped characters.
+			i++
+			for i < len(text) && text[i] != '"' {
+				if text[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+
+		case c == '\'':
+
+			// The rest of the line is a comment.
+			return refs, nil
+
+		case isLetter(c):
+			j := i
+			for j < len(text) && isWord(text[j]) {
+				j++
+			}
+			word := strings.ToUpper(text[i:j])
+			i = j
+
+			switch word {
+			case "REM":
+
+				// The rest of the line is a comment.
+				return refs, nil
+
+			case "GOTO", "GOSUB", "THEN", "ELSE", "RESUME":
+				var found []reference
+				var err *Error
+				found, i, err = targets(text, i, word, previous, labels)
+				if err != nil {
+					return nil, err
+				}
+				refs = append(refs, found...)
+			}
+			previous = word
+
+		case isDigit(c):
+
+			// Skip numbers, so we don't see "1E" as a word.
+			for i < len(text) && (isDigit(text[i]) || text[i] == '.') {
+				i++
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return refs, nil
+}
+
+// targets parses the target(s) which follow a keyword.
+//
+// Following GOTO or GOSUB there may be a list of targets, as used by
+// "ON x GOTO 10, 20, 30".  THEN and ELSE are followed by a target only
+// if they're followed by a number, otherwise they're followed by a
+// statement.
+func targets(text string, offset int, keyword string, previous string, labels map[string]bool) ([]reference, int, *Error) {
+	var refs []reference
+
+	// Zero has a special meaning in both "ON ERROR GOTO 0",
+	// and "RESUME 0".
+	zero := keyword == "RESUME" || (keyword == "GOTO" && previous == "ERROR")
+
+	// The name to report in errors.
+	name := keyword
+	if previous == "ERROR" {
+		name = "ON ERROR " + keyword
+	}
+
+	i := offset
+	for {
+		start := skipSpace(text, i)
+		end := start
+
+		switch {
+		case start < len(text) && isDigit(text[start]):
+			for end < len(text) && isDigit(text[end]) {
+				end++
+			}
+
+		case start < len(text) && isLetter(text[start]):
+			for end < len(text) && isWord(text[end]) {
+				end++
+			}
+			word := strings.ToUpper(text[start:end])
+
+			// After THEN or ELSE we have a statement, and
+			// "RESUME NEXT" is fine too.
+			if keyword == "THEN" || keyword == "ELSE" || (keyword == "RESUME" && word == "NEXT") {
+				return refs, offset, nil
+			}
+			if !labels[strings.ToLower(word)] {
+				return nil, 0, &Error{Column: start + 1,
+					Message: fmt.Sprintf("%s target is computed, so it cannot be renumbered safely", name)}
+			}
+
+		default:
+			return refs, end, nil
+		}
+
+		//
+		// A target must be followed by the end of the statement,
+		// or by a comma within a list, anything else means that it
+		// is an expression which we can't renumber.
+		//
+		after := skipSpace(text, end)
+		list := after < len(text) && text[after] == ',' && (keyword == "GOTO" || keyword == "GOSUB")
+		if after < len(text) && text[after] != ':' && text[after] != '\'' && !list && !hasWord(text[after:], "ELSE") && !hasWord(text[after:], "REM") {
+			return nil, 0, &Error{Column: start + 1,
+				Message: fmt.Sprintf("%s target is computed, so it cannot be renumbered safely", name)}
+		}
+
+		if isDigit(text[start]) {
+			n, err := strconv.Atoi(text[start:end])
+			if err != nil {
+				return nil, 0, &Error{Column: start + 1, Message: err.Error()}
+			}
+			if !(zero && n == 0) {
+				refs = append(refs, reference{start: start, end: end, keyword: name, target: n})
+			}
+		}
+
+		if !list {
+			return refs, end, nil
+		}
+		i = after + 1
+	}
+}
+
+// hasWord returns true if the text begins with the given keyword.
+func hasWord(text string, word string) bool {
+	if len(text) < len(word) || !strings.EqualFold(text[:len(word)], word) {
+		return false
+	}
+	return len(text) == len(word) || !isWord(text[len(word)])
+}
+
+// skipSpace returns the offset of the first non-whitespace character
+// at, or after, the given offset.
+func skipSpace(text string, offset int) int {
+	for offset < len(text) && (text[offset] == ' ' || text[offset] == '\t' || text[offset] == '\r') {
+		offset++
+	}
+	return offset
+}
+
+// isDigit returns true if the character is a digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isLetter returns true if the character may begin an identifier.
+//
+// Bytes beyond the ASCII range are regarded as letters, so that
+// identifiers containing UTF-8 are skipped as a whole.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// isWord returns true if the character may be part of an identifier.
+func isWord(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '$' || c == '_' || c == '.'
+}
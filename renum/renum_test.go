@@ -0,0 +1,140 @@
+// renum_test.go - Test-cases for our renumbering code.
+
+package renum
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRenumber tests that programs are renumbered as expected.
+func TestRenumber(t *testing.T) {
+
+	type Test struct {
+		Input  string
+		Output string
+		Opts   Options
+	}
+
+	tests := []Test{
+
+		// Simple renumbering, with the defaults.
+		{Input: "1 PRINT \"Hello\"\n2 GOTO 1\n",
+			Output: "10 PRINT \"Hello\"\n20 GOTO 10\n",
+			Opts:   DefaultOptions()},
+
+		// Formatting, case, and comments are preserved.
+		{Input: "  5 rem GOTO 5 is a comment\n  7   goto   5\n",
+			Output: "  100 rem GOTO 5 is a comment\n  105   goto   100\n",
+			Opts:   Options{Start: 100, Step: 5}},
+
+		// As are apostrophe comments.
+		{Input: "1 GOTO 2 ' GOTO 1\n2 GOSUB 1' GOTO 2\n",
+			Output: "10 GOTO 20 ' GOTO 1\n20 GOSUB 10' GOTO 2\n",
+			Opts:   DefaultOptions()},
+
+		// Strings are left alone.
+		{Input: "1 PRINT \"GOTO 1\" : GOTO 1\n",
+			Output: "10 PRINT \"GOTO 1\" : GOTO 10\n",
+			Opts:   DefaultOptions()},
+
+		// GOSUB, THEN, ELSE, and ON.
+		{Input: "1 GOSUB 3\n2 IF a THEN 1 ELSE 3\n3 ON a GOTO 1, 2,3\n4 ON a GOSUB 1\n",
+			Output: "10 GOSUB 30\n20 IF a THEN 10 ELSE 30\n30 ON a GOTO 10, 20,30\n40 ON a GOSUB 10\n",
+			Opts:   DefaultOptions()},
+
+		// THEN may be followed by a statement.
+		{Input: "1 IF a THEN PRINT \"1\" ELSE GOTO 1\n",
+			Output: "10 IF a THEN PRINT \"1\" ELSE GOTO 10\n",
+			Opts:   DefaultOptions()},
+
+		// Error-handling, where zero is special.
+		{Input: "1 ON ERROR GOTO 3\n2 ON ERROR GOTO 0\n3 RESUME 0\n4 RESUME NEXT\n5 RESUME 1\n",
+			Output: "10 ON ERROR GOTO 30\n20 ON ERROR GOTO 0\n30 RESUME 0\n40 RESUME NEXT\n50 RESUME 10\n",
+			Opts:   DefaultOptions()},
+
+		// Labels are left alone, as are unnumbered lines.
+		{Input: "1 GOTO loop\nloop:\nPRINT 3\n*sub\n2 GOSUB sub : GOTO 1\n",
+			Output: "10 GOTO loop\nloop:\nPRINT 3\n*sub\n20 GOSUB sub : GOTO 10\n",
+			Opts:   DefaultOptions()},
+
+		// A range of lines.
+		{Input: "05 GOTO 30\n10 GOTO 20\n20 GOTO 10\n30 GOTO 05\n",
+			Output: "05 GOTO 30\n11 GOTO 12\n12 GOTO 11\n30 GOTO 05\n",
+			Opts:   Options{Start: 11, Step: 1, From: 10, To: 20}},
+
+		// A range with no upper limit.
+		{Input: "1 GOTO 2\n2 GOTO 3\n3 GOTO 1",
+			Output: "1 GOTO 200\n200 GOTO 300\n300 GOTO 1",
+			Opts:   Options{Start: 200, Step: 100, From: 2}},
+	}
+
+	for _, test := range tests {
+
+		out, err := Renumber(test.Input, test.Opts)
+		if err != nil {
+			t.Errorf("unexpected error renumbering %q: %s", test.Input, err.Error())
+			continue
+		}
+		if out != test.Output {
+			t.Errorf("renumbering %q gave %q, expected %q", test.Input, out, test.Output)
+		}
+	}
+}
+
+// TestRenumberErrors tests the programs we refuse to renumber.
+func TestRenumberErrors(t *testing.T) {
+
+	type Test struct {
+		Input string
+		Error string
+		Opts  Options
+	}
+
+	tests := []Test{
+		{Input: "10 GOTO 20\n",
+			Error: "1:9: GOTO refers to line 20, which doesn't exist"},
+		{Input: "10 PRINT 1\n20 ON a GOSUB 10, 30\n",
+			Error: "2:19: GOSUB refers to line 30"},
+		{Input: "10 ON ERROR GOTO 40\n",
+			Error: "ON ERROR GOTO refers to line 40"},
+		{Input: "10 PRINT 1\n10 PRINT 2\n",
+			Error: "2:1: line 10 is duplicated"},
+		{Input: "10 GOTO 10 + 1\n",
+			Error: "GOTO target is computed"},
+		{Input: "10 GOSUB x * 100\n",
+			Error: "GOSUB target is computed"},
+		{Input: "10 GOTO 30\n20 GOTO 10\n30 END\n",
+			Opts:  Options{Start: 10, Step: 20, From: 10, To: 20},
+			Error: "renumbering would give line 30 the same number as line 20"},
+		{Input: "10 GOTO 30\n20 GOTO 10\n30 END\n",
+			Opts:  Options{Start: 1, Step: 1, From: 30},
+			Error: "renumbering line 30 as 1 would move it out of order"},
+		{Input: "10 END\n",
+			Opts:  Options{Start: 0, Step: 1},
+			Error: "must be positive"},
+		{Input: "10 END\n",
+			Opts:  Options{Start: 1, Step: 0},
+			Error: "must be positive"},
+		{Input: "10 END\n",
+			Opts:  Options{Start: 1, Step: 1, From: 20, To: 10},
+			Error: "is empty"},
+	}
+
+	for _, test := range tests {
+
+		opts := test.Opts
+		if opts == (Options{}) {
+			opts = DefaultOptions()
+		}
+
+		_, err := Renumber(test.Input, opts)
+		if err == nil {
+			t.Errorf("expected an error renumbering %q", test.Input)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.Error) {
+			t.Errorf("error renumbering %q was %q, expected %q", test.Input, err.Error(), test.Error)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sound
+
+import (
+	"github.com/skx/gobasic/builtin"
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+)
+
+// Register creates a synthesiser with the given options, and makes the
+// primitives which make sounds available to the given interpreter.
+func Register(e *eval.Interpreter, opts Options) *Synth {
+	s := New(opts)
+	s.Register(e)
+	return s
+}
+
+// Register makes the primitives which make sounds available to the given
+// interpreter:
+//
+//	BEEP duration, pitch    A tone, as the ZX Spectrum made; the duration
+//	                        is in seconds, and the pitch in semitones
+//	                        above middle C.
+//	SOUND freq, duration    A tone, as QBasic made; the frequency is in Hz,
+//	                        and the duration in ticks of the clock, of
+//	                        which there are 18.2 a second.
+//	PLAY s$                 A tune, written in the Music Macro Language.
+func (s *Synth) Register(e *eval.Interpreter) {
+	e.RegisterBuiltin("BEEP", 2, s.beepFunction)
+	e.RegisterBuiltin("PLAY", 1, s.playFunction)
+	e.RegisterBuiltin("SOUND", 2, s.soundFunction)
+}
+
+// ticks is the number of clock ticks in a second, which SOUND counts in.
+const ticks = 18.2
+
+// numbers returns the arguments given to a primitive.
+func numbers(args []object.Object) ([]float64, object.Object) {
+	var out []float64
+	for i, arg := range args {
+		n, ok := arg.(*object.NumberObject)
+		if !ok {
+			return nil, object.CodedError(object.ErrTypeMismatch, "Wrong type for argument %d", i+1)
+		}
+		out = append(out, n.Value)
+	}
+	return out, nil
+}
+
+// done returns the value returned by the primitives, or the error they
+// failed with.
+func done(err error) object.Object {
+	if err != nil {
+		return object.CodedError(object.ErrIllegalFunction, "%s", err.Error())
+	}
+	return &object.NumberObject{Value: 0}
+}
+
+// beepFunction implements BEEP.
+func (s *Synth) beepFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers(args)
+	if err != nil {
+		return err
+	}
+	return done(s.Beep(n[0], n[1]))
+}
+
+// soundFunction implements SOUND.
+func (s *Synth) soundFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers(args)
+	if err != nil {
+		return err
+	}
+	if n[0] < 37 || n[0] > 32767 {
+		return object.CodedError(object.ErrIllegalFunction, "SOUND frequency must be between 37 and 32767")
+	}
+	if n[1] < 0 || n[1] > 65535 {
+		return object.CodedError(object.ErrIllegalFunction, "SOUND duration must be between 0 and 65535")
+	}
+	return done(s.Tone(n[0], n[1]/ticks, 0))
+}
+
+// playFunction implements PLAY.
+func (s *Synth) playFunction(env builtin.Environment, args []object.Object) object.Object {
+	str, ok := args[0].(*object.StringObject)
+	if !ok {
+		return object.CodedError(object.ErrTypeMismatch, "PLAY expects a string")
+	}
+	return done(s.Play(str.Value))
+}
@@ -0,0 +1,223 @@
+package sound
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// middleC is the frequency of middle C, in Hz, which is "O4 C" to PLAY,
+// and a pitch of zero to BEEP.
+const middleC = 261.6256
+
+// semitones holds the position of each note within an octave.
+var semitones = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// player holds the settings of PLAY, which last from one call to the
+// next, as they did in GW-BASIC.
+type player struct {
+
+	// octave is the octave of the notes, from 0 to 6.
+	octave int
+
+	// length is the length of the notes, where 4 is a quarter-note.
+	length int
+
+	// tempo is the number of quarter-notes in a minute.
+	tempo int
+
+	// style is the part of each note which sounds, the remainder
+	// being silent.
+	style float64
+}
+
+// newPlayer returns the settings PLAY starts with.
+func newPlayer() player {
+	return player{octave: 4, length: 4, tempo: 120, style: 7.0 / 8}
+}
+
+// Play records the notes described by the given string, in the Music
+// Macro Language of GW-BASIC, and QBasic:
+//
+//	A to G     A note, which may be followed by "#" or "+" to sharpen
+//	           it, "-" to flatten it, a length, and dots.
+//	N n        The note n semitones above the C of octave 0, or a
+//	           rest if n is zero.
+//	O n        Choose the octave, from 0 to 6; middle C is in 4.
+//	< and >    Move down, or up, an octave.
+//	L n        Choose the length of notes, from 1 to 64; 4 is a
+//	           quarter-note.
+//	P n, R n   Rest for the given length.
+//	T n        Choose the number of quarter-notes in a minute, from
+//	           32 to 255.
+//	MN, ML, MS Play notes normally, legato, or staccato.
+//	MF, MB     Accepted, and ignored.
+//
+// A dot after a note, or rest, makes it half as long again.  Spaces are
+// ignored, and case doesn't matter.
+func (s *Synth) Play(mml string) error {
+	p := &s.play
+	str := []byte(mml)
+	for i, c := range str {
+		if c >= 'a' && c <= 'z' {
+			str[i] = c - 'a' + 'A'
+		}
+	}
+	i := 0
+
+	// number reads the number at the current position, if any.
+	number := func() (int, bool) {
+		start := i
+		n := 0
+		for i < len(str) && str[i] >= '0' && str[i] <= '9' {
+			if n < 1000000 {
+				n = n*10 + int(str[i]-'0')
+			}
+			i++
+		}
+		return n, i > start
+	}
+
+	// spaces skips the spaces which may precede a number.
+	spaces := func() {
+		for i < len(str) && str[i] == ' ' {
+			i++
+		}
+	}
+
+	// argument reads the number which must follow a command.
+	argument := func(cmd byte, min, max int) (int, error) {
+		spaces()
+		n, ok := number()
+		if !ok || n < min || n > max {
+			return 0, fmt.Errorf("PLAY: %c expects a number between %d and %d", cmd, min, max)
+		}
+		return n, nil
+	}
+
+	// duration returns the length of a note, in seconds, reading an
+	// optional length, and any dots, which follow it.
+	duration := func() (float64, error) {
+		length := p.length
+		if n, ok := number(); ok {
+			if n < 1 || n > 64 {
+				return 0, fmt.Errorf("PLAY: the length of a note must be between 1 and 64")
+			}
+			length = n
+		}
+		d := 4 / float64(length) * 60 / float64(p.tempo)
+		for i < len(str) && str[i] == '.' {
+			d *= 1.5
+			i++
+		}
+		return d, nil
+	}
+
+	// tone records a note, n semitones above the C of octave 0.
+	tone := func(n int) error {
+		d, err := duration()
+		if err != nil {
+			return err
+		}
+		freq := middleC * math.Pow(2, float64(n-4*12)/12)
+		return s.Tone(freq, d*p.style, d*(1-p.style))
+	}
+
+	// rest records a silence, whose length may follow spaces, as the
+	// arguments of other commands may.
+	rest := func() error {
+		spaces()
+		d, err := duration()
+		if err != nil {
+			return err
+		}
+		return s.Tone(0, 0, d)
+	}
+
+	for i < len(str) {
+		cmd := str[i]
+		i++
+
+		switch {
+		case cmd == ' ':
+
+		case cmd >= 'A' && cmd <= 'G':
+			n := p.octave*12 + semitones[cmd]
+			if i < len(str) && (str[i] == '#' || str[i] == '+') {
+				n++
+				i++
+			} else if i < len(str) && str[i] == '-' {
+				n--
+				i++
+			}
+			if err := tone(n); err != nil {
+				return err
+			}
+
+		case cmd == 'N':
+			n, err := argument(cmd, 0, 84)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				err = rest()
+			} else {
+				err = tone(n - 1)
+			}
+			if err != nil {
+				return err
+			}
+
+		case cmd == 'P' || cmd == 'R':
+			if err := rest(); err != nil {
+				return err
+			}
+
+		case cmd == 'O':
+			n, err := argument(cmd, 0, 6)
+			if err != nil {
+				return err
+			}
+			p.octave = n
+
+		case cmd == '<':
+			if p.octave > 0 {
+				p.octave--
+			}
+
+		case cmd == '>':
+			if p.octave < 6 {
+				p.octave++
+			}
+
+		case cmd == 'L':
+			n, err := argument(cmd, 1, 64)
+			if err != nil {
+				return err
+			}
+			p.length = n
+
+		case cmd == 'T':
+			n, err := argument(cmd, 32, 255)
+			if err != nil {
+				return err
+			}
+			p.tempo = n
+
+		case cmd == 'M' && i < len(str) && strings.IndexByte("NLSFB", str[i]) >= 0:
+			switch str[i] {
+			case 'N':
+				p.style = 7.0 / 8
+			case 'L':
+				p.style = 1
+			case 'S':
+				p.style = 3.0 / 4
+			}
+			i++
+
+		default:
+			return fmt.Errorf("PLAY: unexpected %q in %q", mml[i-1], mml)
+		}
+	}
+	return nil
+}
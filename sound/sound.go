@@ -0,0 +1,167 @@
+// Package sound provides primitives which make sounds, and may be added
+// to any BASIC interpreter, along with the synthesiser which renders them.
+//
+// Each interpreter is given a synthesiser of its own:
+//
+//	e, _ := eval.New(tokenizer.New(src))
+//	synth := sound.Register(e, sound.Options{})
+//
+// The program may then make some noise:
+//
+//	10 BEEP 0.5, 0
+//	20 SOUND 440, 18.2
+//	30 PLAY "T120 O4 L8 CDEFGAB > C"
+//
+// No audio hardware is involved; the sound is rendered as a square wave,
+// as the ZX Spectrum's beeper made, which may be written as a WAV file.
+package sound
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Options control the synthesiser created by New.
+type Options struct {
+
+	// Rate is the number of samples in each second of sound, which
+	// defaults to 22050.
+	Rate int
+
+	// MaxDuration is the longest the sound may last, or zero for no
+	// limit.
+	MaxDuration time.Duration
+}
+
+// note is a single tone, or a silence.
+type note struct {
+
+	// freq is the frequency of the tone, in Hz, or zero for silence.
+	freq float64
+
+	// on is the time the tone sounds for, and off the silence after
+	// it, in seconds.
+	on  float64
+	off float64
+}
+
+// Synth records the sounds made by a program, and renders them.
+type Synth struct {
+
+	// opts holds our options.
+	opts Options
+
+	// notes holds the sounds which have been made.
+	notes []note
+
+	// duration is the length of the notes, in seconds.
+	duration float64
+
+	// play holds the state of PLAY, which persists between calls.
+	play player
+}
+
+// New creates a synthesiser, which has made no sound.
+func New(opts Options) *Synth {
+	if opts.Rate <= 0 {
+		opts.Rate = 22050
+	}
+	return &Synth{opts: opts, play: newPlayer()}
+}
+
+// Tone records a tone of the given frequency, which sounds for the first
+// of the given times, in seconds, and is followed by silence for the
+// second.  A frequency of zero is silent.
+func (s *Synth) Tone(freq, on, off float64) error {
+	if freq < 0 || on < 0 || off < 0 || math.IsNaN(freq+on+off) || math.IsInf(freq+on+off, 0) {
+		return fmt.Errorf("invalid tone %g Hz for %gs", freq, on)
+	}
+
+	// Notes which take no time are inaudible, so needn't be kept.
+	if on+off == 0 {
+		return nil
+	}
+
+	total := s.duration + on + off
+	if s.opts.MaxDuration > 0 && total > s.opts.MaxDuration.Seconds() {
+		return fmt.Errorf("the sound may last at most %s", s.opts.MaxDuration)
+	}
+
+	s.notes = append(s.notes, note{freq: freq, on: on, off: off})
+	s.duration = total
+	return nil
+}
+
+// Beep records a tone as the ZX Spectrum's BEEP makes it; the duration is
+// in seconds, and the pitch in semitones above middle C.
+func (s *Synth) Beep(duration, pitch float64) error {
+	if duration < 0 || duration > 10 {
+		return fmt.Errorf("the duration of BEEP must be between 0 and 10 seconds")
+	}
+	if pitch < -60 || pitch > 69 {
+		return fmt.Errorf("the pitch of BEEP must be between -60 and 69")
+	}
+	return s.Tone(middleC*math.Pow(2, pitch/12), duration, 0)
+}
+
+// Duration returns the length of the sound which has been recorded.
+func (s *Synth) Duration() time.Duration {
+	return time.Duration(s.duration * float64(time.Second))
+}
+
+// Rate returns the number of samples in each second of sound.
+func (s *Synth) Rate() int {
+	return s.opts.Rate
+}
+
+// amplitude is the volume of the tones, leaving some room, and fade is
+// the time taken to start, and stop, each, which avoids a click.
+const (
+	amplitude = 0.3 * math.MaxInt16
+	fade      = 0.002
+)
+
+// Samples renders the sound which has been recorded, as signed 16-bit
+// samples.
+func (s *Synth) Samples() []int16 {
+	rate := float64(s.opts.Rate)
+	out := make([]int16, 0, int(s.duration*rate)+len(s.notes))
+
+	//
+	// We count time in samples from the start, so that the rounding
+	// of each note doesn't add up, and keep the phase of the wave
+	// between notes, as the beeper would.
+	//
+	phase := 0.0
+	elapsed := 0.0
+	for _, n := range s.notes {
+		start := len(out)
+		on := int(math.Round((elapsed+n.on)*rate)) - start
+		elapsed += n.on + n.off
+		end := int(math.Round(elapsed * rate))
+
+		edge := fade * rate
+		for i := 0; i < end-start; i++ {
+			if i >= on || n.freq == 0 {
+				out = append(out, 0)
+				continue
+			}
+
+			v := amplitude
+			if phase >= 0.5 {
+				v = -v
+			}
+			if f := float64(i) + 0.5; f < edge {
+				v *= f / edge
+			} else if f = float64(on-i) - 0.5; f < edge {
+				v *= f / edge
+			}
+			out = append(out, int16(v))
+
+			phase += n.freq / rate
+			phase -= math.Floor(phase)
+		}
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+// sound_test.go - Test-cases for our synthesiser.
+
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/internal/evaltest"
+)
+
+// options are those of the synthesiser our programs play upon.
+var options = Options{Rate: 8000, MaxDuration: time.Minute}
+
+// run runs the given program, returning the synthesiser it played upon.
+func run(t *testing.T, src string) (*Synth, error) {
+	var s *Synth
+	_, err := evaltest.Run(t, src, func(e *eval.Interpreter) {
+		s = Register(e, options)
+	})
+	return s, err
+}
+
+// frequency returns the frequency of the square wave in the given
+// samples, by counting the times it rises.
+func frequency(samples []int16, rate int) float64 {
+	rises := 0
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1] <= 0 && samples[i] > 0 {
+			rises++
+		}
+	}
+	return float64(rises) * float64(rate) / float64(len(samples))
+}
+
+// TestTones tests the tones made by each primitive.
+func TestTones(t *testing.T) {
+
+	type Test struct {
+		Source   string
+		Duration float64
+		Freq     float64
+	}
+
+	tests := []Test{
+		{Source: "10 BEEP 1, 0\n", Duration: 1, Freq: 261.6},
+		{Source: "10 BEEP 0.5, 12\n", Duration: 0.5, Freq: 523.3},
+		{Source: "10 SOUND 440, 18.2\n", Duration: 1, Freq: 440},
+
+		// At 60 quarter-notes a minute each lasts a second.
+		{Source: "10 PLAY \"T60 ML A\"\n", Duration: 1, Freq: 440},
+		{Source: "10 PLAY \"t60 ml o3 a2\"\n", Duration: 2, Freq: 220},
+		{Source: "10 PLAY \"T60 ML N59 L2\"\n", Duration: 1, Freq: 466.2},
+		{Source: "10 PLAY \"T60 ML > G#4.\"\n", Duration: 1.5, Freq: 830.6},
+
+		// Rests may be given a length after a space; the tone lasts
+		// for a quarter of the time, so seems a quarter as high.
+		{Source: "10 PLAY \"T60 ML P 2 A R 4\"\n", Duration: 4, Freq: 110},
+	}
+
+	for _, test := range tests {
+		s, err := run(t, test.Source)
+		if err != nil {
+			t.Errorf("Error running %q: %s", test.Source, err.Error())
+			continue
+		}
+		if d := s.Duration().Seconds(); math.Abs(d-test.Duration) > 0.001 {
+			t.Errorf("%q lasted %fs, not %fs", test.Source, d, test.Duration)
+		}
+		samples := s.Samples()
+		if len(samples) != int(test.Duration*8000) {
+			t.Errorf("%q gave %d samples", test.Source, len(samples))
+		}
+		if f := frequency(samples, 8000); math.Abs(f-test.Freq) > 2 {
+			t.Errorf("%q gave %fHz, not %fHz", test.Source, f, test.Freq)
+		}
+	}
+}
+
+// TestPlay tests the settings of PLAY, which persist between calls.
+func TestPlay(t *testing.T) {
+	s, err := run(t, `10 PLAY "T240 L8 MS C D E P4"
+20 PLAY "MN CR2 MF O5 > C < < C > > > C MB"
+`)
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+
+	// An eighth-note at 240 is an eighth of a second.
+	expected := []note{
+		{261.63, 0.09375, 0.03125},
+		{293.66, 0.09375, 0.03125},
+		{329.63, 0.09375, 0.03125},
+		{0, 0, 0.25},
+		{261.63, 0.109375, 0.015625},
+		{0, 0, 0.5},
+		{1046.5, 0.109375, 0.015625},
+		{261.63, 0.109375, 0.015625},
+		{1046.5, 0.109375, 0.015625},
+	}
+	if len(s.notes) != len(expected) {
+		t.Fatalf("Expected %d notes, got %v", len(expected), s.notes)
+	}
+	for i, n := range expected {
+		got := s.notes[i]
+		if math.Abs(got.freq-n.freq) > 0.01 || got.on != n.on || got.off != n.off {
+			t.Errorf("Note %d was %v, not %v", i, got, n)
+		}
+	}
+
+	// The silences are silent.
+	samples := s.Samples()
+	for _, i := range []int{8000 * 3 / 8, 8000 * 7 / 8} {
+		if samples[i] != 0 {
+			t.Errorf("Sample %d is %d", i, samples[i])
+		}
+	}
+
+	// Notes which take no time aren't kept.
+	s, err = run(t, "10 BEEP 0, 0\n20 SOUND 100, 0\n")
+	if err != nil || len(s.notes) != 0 {
+		t.Errorf("Unexpected notes %v %v", s.notes, err)
+	}
+}
+
+// TestErrors tests that mistakes are reported.
+func TestErrors(t *testing.T) {
+	tests := map[string]string{
+		"10 BEEP \"a\", 1\n":    "Wrong type for argument 1",
+		"10 BEEP 11, 1\n":       "between 0 and 10 seconds",
+		"10 BEEP 1, 70\n":       "between -60 and 69",
+		"10 SOUND 20, 1\n":      "between 37 and 32767",
+		"10 SOUND 100, 0 - 1\n": "between 0 and 65535",
+		"10 PLAY 3\n":           "PLAY expects a string",
+		"10 PLAY \"H\"\n":       "unexpected 'H'",
+		"10 PLAY \"O7\"\n":      "O expects a number between 0 and 6",
+		"10 PLAY \"T\"\n":       "T expects a number",
+		"10 PLAY \"C65\"\n":     "between 1 and 64",
+		"10 PLAY \"MX\"\n":      "unexpected 'M'",
+		"10 BEEP 10, 0\n20 BEEP 10, 0\n30 BEEP 10, 0\n40 BEEP 10, 0\n50 BEEP 10, 0\n60 BEEP 10, 0\n70 BEEP 1, 0\n": "at most 1m0s",
+	}
+
+	evaltest.Errors(t, tests, func(e *eval.Interpreter) {
+		Register(e, options)
+	})
+}
+
+// TestWAV tests the WAV file we write.
+func TestWAV(t *testing.T) {
+	s := New(Options{})
+	if err := s.Beep(0.5, 0); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteWAV(&buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	wav := buf.Bytes()
+
+	if len(wav) != 44+22050 {
+		t.Fatalf("Unexpected size %d", len(wav))
+	}
+	if string(wav[0:4]) != "RIFF" || string(wav[8:16]) != "WAVEfmt " || string(wav[36:40]) != "data" {
+		t.Errorf("Unexpected header %q", wav[:44])
+	}
+	le := binary.LittleEndian
+	if le.Uint32(wav[4:]) != uint32(len(wav)-8) || le.Uint32(wav[40:]) != 22050 {
+		t.Errorf("Unexpected sizes %q", wav[:44])
+	}
+	if le.Uint16(wav[22:]) != 1 || le.Uint32(wav[24:]) != 22050 || le.Uint16(wav[34:]) != 16 {
+		t.Errorf("Unexpected format %q", wav[:44])
+	}
+
+	// The tone fades in, rather than clicking.
+	first := int16(le.Uint16(wav[44:]))
+	loud := int16(le.Uint16(wav[44+200:]))
+	if first <= 0 || first >= loud/10 {
+		t.Errorf("Unexpected samples %d %d", first, loud)
+	}
+}
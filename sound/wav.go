@@ -0,0 +1,40 @@
+package sound
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// WriteWAV writes the sound as a WAV file, holding mono, 16-bit, PCM
+// samples.
+func (s *Synth) WriteWAV(w io.Writer) error {
+	samples := s.Samples()
+	size := uint32(len(samples) * 2)
+	rate := uint32(s.opts.Rate)
+
+	out := bufio.NewWriter(w)
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'}, 36 + size, [4]byte{'W', 'A', 'V', 'E'},
+
+		// The format of the samples.
+		[4]byte{'f', 'm', 't', ' '}, uint32(16),
+		uint16(1),  // PCM
+		uint16(1),  // channels
+		rate,       // samples per second
+		rate * 2,   // bytes per second
+		uint16(2),  // bytes per sample
+		uint16(16), // bits per sample
+
+		[4]byte{'d', 'a', 't', 'a'}, size,
+	}
+	for _, v := range header {
+		if err := binary.Write(out, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(out, binary.LittleEndian, samples); err != nil {
+		return err
+	}
+	return out.Flush()
+}
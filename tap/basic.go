@@ -0,0 +1,313 @@
+package tap
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// keywords holds the text of the single-byte tokens, starting from 0xA5,
+// which the Spectrum uses to store its keywords.
+//
+// "GO TO" and "GO SUB" are written as single words, since that is the
+// spelling our tokenizer understands.
+var keywords = [...]string{
+	"RND", "INKEY$", "PI", "FN", "POINT", "SCREEN$", "ATTR", "AT", "TAB",
+	"VAL$", "CODE", "VAL", "LEN", "SIN", "COS", "TAN", "ASN", "ACS", "ATN",
+	"LN", "EXP", "INT", "SQR", "SGN", "ABS", "PEEK", "IN", "USR", "STR$",
+	"CHR$", "NOT", "BIN", "OR", "AND", "<=", ">=", "<>", "LINE", "THEN",
+	"TO", "STEP", "DEF FN", "CAT", "FORMAT", "MOVE", "ERASE", "OPEN #",
+	"CLOSE #", "MERGE", "VERIFY", "BEEP", "CIRCLE", "INK", "PAPER", "FLASH",
+	"BRIGHT", "INVERSE", "OVER", "OUT", "LPRINT", "LLIST", "STOP", "READ",
+	"DATA", "RESTORE", "NEW", "BORDER", "CONTINUE", "DIM", "REM", "FOR",
+	"GOTO", "GOSUB", "INPUT", "LOAD", "LIST", "LET", "PAUSE", "NEXT", "POKE",
+	"PRINT", "PLOT", "RUN", "SAVE", "RANDOMIZE", "IF", "CLS", "DRAW",
+	"CLEAR", "RETURN", "COPY",
+}
+
+// firstKeyword is the value of the first token in our keywords table.
+const firstKeyword = 0xA5
+
+// blocks holds the characters used for the Spectrum's block-graphics,
+// 0x80 to 0x8F, where the bits of the code select the quarters which are
+// set: 1 for the top-right, 2 for the top-left, 4 for the bottom-right,
+// and 8 for the bottom-left.
+var blocks = []rune(" ▝▘▀▗▐▚▜▖▞▌▛▄▟▙█")
+
+// The special bytes which may appear within a line of a program.
+const (
+	number = 0x0E // Followed by the five-byte form of a number.
+	ink    = 0x10 // INK to OVER are followed by a one-byte parameter.
+	over   = 0x15
+	at     = 0x16 // AT and TAB are followed by two bytes.
+	tab    = 0x17
+	enter  = 0x0D // The end of a line.
+)
+
+// Detokenize converts a Spectrum BASIC program, as stored in memory or
+// upon a tape, into source which our tokenizer can understand.
+//
+// Each line is stored as a two-byte big-endian line-number, a two-byte
+// little-endian length, and the text of the line.  Keywords are stored
+// as single bytes, and each number is followed by a hidden five-byte
+// copy of its value, which is what the Spectrum actually uses when the
+// program runs.  Where the hidden value and the visible digits disagree
+// we use the hidden value, so the program behaves as it did originally.
+func Detokenize(program []byte) (string, error) {
+	var out strings.Builder
+
+	offset := 0
+	for offset < len(program) {
+		if offset+4 > len(program) {
+			return "", fmt.Errorf("offset %d: truncated line header", offset)
+		}
+		n := int(program[offset])<<8 | int(program[offset+1])
+		size := word(program[offset+2:])
+		offset += 4
+
+		// The variables area may follow the program, and
+		// begins with a byte which can't start a line-number.
+		if n >= 16384 {
+			break
+		}
+
+		if offset+size > len(program) {
+			return "", fmt.Errorf("line %d: expected %d bytes, only %d remain", n, size, len(program)-offset)
+		}
+		text, err := detokenizeLine(program[offset : offset+size])
+		if err != nil {
+			return "", fmt.Errorf("line %d: %s", n, err.Error())
+		}
+		offset += size
+
+		fmt.Fprintf(&out, "%d %s\n", n, text)
+	}
+
+	return out.String(), nil
+}
+
+// line holds our state as we convert a single line of a program.
+type line struct {
+
+	// buf holds the text we've produced.
+	buf []byte
+
+	// space is true if a space should be written before the next
+	// character, because we've just written a keyword.
+	space bool
+
+	// start is the offset within buf of the number we're currently
+	// writing, or -1 if we're not within a number.
+	start int
+
+	// literal is true if the next number must be kept as written,
+	// because it follows BIN and so is in binary.
+	literal bool
+}
+
+// detokenizeLine converts the text of a single line.
+func detokenizeLine(data []byte) (string, error) {
+	l := &line{start: -1}
+
+	inString := false
+	inRem := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		// Within a comment only the keywords are expanded, since it
+		// may contain anything - including machine-code.
+		if inRem {
+			if c >= firstKeyword {
+				l.keyword(keywords[c-firstKeyword])
+			} else if c >= ' ' {
+				l.write(decodeChar(c))
+			}
+			continue
+		}
+
+		switch {
+		case c == enter:
+			return string(l.buf), nil
+
+		case c == number:
+			if i+5 >= len(data) {
+				return "", fmt.Errorf("truncated number at offset %d", i)
+			}
+			l.number(decodeNumber(data[i+1 : i+6]))
+			i += 5
+
+		case c >= ink && c <= over:
+			i++
+
+		case c == at || c == tab:
+			i += 2
+
+		case c >= firstKeyword:
+			k := keywords[c-firstKeyword]
+			l.keyword(k)
+			if k == "REM" && !inString {
+				inRem = true
+			}
+			l.literal = k == "BIN"
+
+		case c == '"':
+			inString = !inString
+			l.write("\"")
+
+		case c == '\\' && inString:
+			l.write("\\\\")
+
+		case c < ' ':
+			// Other control-codes have no visible form.
+
+		default:
+			l.write(decodeChar(c))
+		}
+	}
+
+	return string(l.buf), nil
+}
+
+// write appends text to the line, keeping track of any number it forms
+// part of.
+func (l *line) write(s string) {
+	if l.space && !strings.Contains(" ),;:=<>+-*/^", s) {
+		l.buf = append(l.buf, ' ')
+	}
+	l.space = false
+
+	c := s[0]
+	switch {
+	case l.start >= 0 && (isDigit(c) || c == '.' || c == 'e' || c == 'E'):
+	case l.start >= 0 && (c == '+' || c == '-') && (l.last() == 'e' || l.last() == 'E'):
+	case (isDigit(c) || c == '.') && !isWord(l.last()):
+		l.start = len(l.buf)
+	default:
+		l.start = -1
+	}
+
+	l.buf = append(l.buf, s...)
+}
+
+// keyword appends a keyword to the line, with spaces to separate it from
+// any names, numbers, or strings which surround it.
+func (l *line) keyword(k string) {
+	l.start = -1
+	l.space = false
+
+	if !isWord(k[0]) {
+		l.buf = append(l.buf, k...)
+		return
+	}
+	if c := l.last(); isWord(c) || c == ')' || c == '"' {
+		l.buf = append(l.buf, ' ')
+	}
+	l.buf = append(l.buf, k...)
+	l.space = true
+}
+
+// number handles the hidden value of the number we've just written.
+//
+// The parameters of a DEF FN statement are also followed by (empty)
+// hidden numbers, so there may be no visible digits at all.
+func (l *line) number(value float64) {
+	start := l.start
+	l.start = -1
+
+	if start < 0 {
+		return
+	}
+	if l.literal {
+		l.literal = false
+		return
+	}
+
+	shown, err := strconv.ParseFloat(string(l.buf[start:]), 64)
+	if err == nil && math.Abs(shown-value) <= 1e-9*math.Max(1, math.Abs(value)) {
+		return
+	}
+	l.buf = append(l.buf[:start], formatNumber(value)...)
+}
+
+// last returns the last character of the line, or zero if it is empty.
+func (l *line) last() byte {
+	if len(l.buf) == 0 {
+		return 0
+	}
+	return l.buf[len(l.buf)-1]
+}
+
+// decodeNumber converts the five-byte form of a number into its value.
+//
+// Integers between -65535 and 65535 are stored as a zero byte, a sign
+// byte, and a little-endian 16-bit value.  Anything else is stored as an
+// exponent, biased by 128, followed by a 32-bit big-endian mantissa whose
+// top bit is replaced by the sign.
+func decodeNumber(b []byte) float64 {
+	if b[0] == 0 {
+		n := word(b[2:])
+		if b[1] == 0xFF {
+			n -= 65536
+		}
+		return float64(n)
+	}
+
+	mantissa := uint32(b[1]|0x80)<<24 | uint32(b[2])<<16 | uint32(b[3])<<8 | uint32(b[4])
+	value := math.Ldexp(float64(mantissa), int(b[0])-128-32)
+	if b[1]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// formatNumber returns the text of a number, to the precision the
+// Spectrum itself uses.
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatInt(int64(value), 10)
+	}
+	return strconv.FormatFloat(value, 'g', 10, 64)
+}
+
+// decodeChar returns the text of a character from the Spectrum's
+// character-set, which is mostly, but not entirely, ASCII.
+func decodeChar(c byte) string {
+	switch {
+	case c == 0x60:
+		return "£"
+	case c == 0x7F:
+		return "©"
+	case c >= 0x80 && c <= 0x8F:
+		return string(blocks[c-0x80])
+	case c >= 0x90 && c < firstKeyword:
+		// The user-defined graphics look like the letters A-U,
+		// until they're redefined.
+		return string(rune('A' + c - 0x90))
+	case c >= firstKeyword:
+		return keywords[c-firstKeyword]
+	case c < ' ':
+		return ""
+	}
+	return string(rune(c))
+}
+
+// decodeText converts a string from the Spectrum's character-set.
+func decodeText(b []byte) string {
+	var out strings.Builder
+	for _, c := range b {
+		out.WriteString(decodeChar(c))
+	}
+	return out.String()
+}
+
+// isDigit returns true if the character is a digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWord returns true if the character may be part of an identifier.
+func isWord(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || isDigit(c) || c == '$'
+}
@@ -0,0 +1,213 @@
+// Package tap reads ZX Spectrum tape images, in the ".tap" format, and
+// converts the BASIC programs they contain into source we can run.
+//
+// A tape image is a series of blocks, each of which is stored as a
+// two-byte little-endian length, followed by that many bytes.  The first
+// of those bytes is a flag, which is zero for a header and 255 for data,
+// and the last is a checksum: the XOR of all the bytes which precede it.
+//
+// A header is normally followed by the data it describes, and tells us
+// the type and name of the file which follows.  A single tape may hold
+// many files, of which any number may be BASIC programs.
+package tap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The values of the flag byte at the start of each block.
+const (
+	headerFlag = 0x00
+	dataFlag   = 0xFF
+)
+
+// Kind is the type of a file stored upon a tape.
+type Kind int
+
+// The kinds of files a header may describe.
+const (
+	Program     Kind = 0
+	NumberArray Kind = 1
+	CharArray   Kind = 2
+	Code        Kind = 3
+
+	// Headerless is used for data blocks which aren't preceded by a
+	// header, as is common for loading-screens and game-data.
+	Headerless Kind = -1
+)
+
+// String returns the name of the kind of file.
+func (k Kind) String() string {
+	switch k {
+	case Program:
+		return "Program"
+	case NumberArray:
+		return "Number array"
+	case CharArray:
+		return "Character array"
+	case Code:
+		return "Bytes"
+	case Headerless:
+		return "Headerless"
+	}
+	return fmt.Sprintf("Unknown(%d)", int(k))
+}
+
+// Block is a single block read from a tape.
+type Block struct {
+
+	// Flag is the first byte of the block, zero for a header.
+	Flag byte
+
+	// Data holds the contents of the block, without the flag
+	// or the checksum.
+	Data []byte
+}
+
+// File is a header and the data which follows it.
+type File struct {
+
+	// Kind is the type of the file.
+	Kind Kind
+
+	// Name is the name of the file, with any trailing spaces removed.
+	Name string
+
+	// Autostart is the line a program runs from once it has been
+	// loaded, or -1 if it doesn't run automatically.
+	Autostart int
+
+	// Length is the length of the BASIC program, which may be followed
+	// by the values of its variables.  It is only used for programs.
+	Length int
+
+	// Data holds the contents of the file.
+	Data []byte
+}
+
+// Blocks splits a tape image into its blocks, validating their checksums.
+func Blocks(tape []byte) ([]Block, error) {
+	var blocks []Block
+
+	offset := 0
+	for offset < len(tape) {
+		if offset+2 > len(tape) {
+			return nil, fmt.Errorf("block %d at offset %d: truncated length", len(blocks), offset)
+		}
+		n := int(tape[offset]) | int(tape[offset+1])<<8
+		offset += 2
+
+		if n < 2 {
+			return nil, fmt.Errorf("block %d at offset %d: length %d is too short", len(blocks), offset-2, n)
+		}
+		if offset+n > len(tape) {
+			return nil, fmt.Errorf("block %d at offset %d: expected %d bytes, only %d remain", len(blocks), offset-2, n, len(tape)-offset)
+		}
+		raw := tape[offset : offset+n]
+		offset += n
+
+		sum := byte(0)
+		for _, b := range raw[:n-1] {
+			sum ^= b
+		}
+		if sum != raw[n-1] {
+			return nil, fmt.Errorf("block %d at offset %d: checksum is %02X, expected %02X", len(blocks), offset-n-2, raw[n-1], sum)
+		}
+
+		blocks = append(blocks, Block{Flag: raw[0], Data: raw[1 : n-1]})
+	}
+
+	return blocks, nil
+}
+
+// Files reads the files stored upon a tape image.
+//
+// Each header is paired with the data block which follows it, data
+// which has no header is returned as a Headerless file, and a header
+// whose data is missing is an error.
+func Files(tape []byte) ([]File, error) {
+	blocks, err := Blocks(tape)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+
+		if b.Flag != headerFlag {
+			files = append(files, File{Kind: Headerless, Autostart: -1, Data: b.Data})
+			continue
+		}
+
+		if len(b.Data) != 17 {
+			return nil, fmt.Errorf("block %d: header has %d bytes, expected 17", i, len(b.Data))
+		}
+		f := File{
+			Kind:      Kind(b.Data[0]),
+			Name:      strings.TrimRight(decodeText(b.Data[1:11]), " "),
+			Autostart: -1,
+		}
+		size := word(b.Data[11:])
+		param1 := word(b.Data[13:])
+		param2 := word(b.Data[15:])
+
+		if f.Kind == Program {
+			// Line-numbers are below 10000, so anything larger
+			// means the program doesn't run itself.
+			if param1 < 10000 {
+				f.Autostart = param1
+			}
+			f.Length = param2
+		}
+
+		if i+1 >= len(blocks) || blocks[i+1].Flag == headerFlag {
+			return nil, fmt.Errorf("block %d: header for %q is not followed by any data", i, f.Name)
+		}
+		i++
+		f.Data = blocks[i].Data
+		if len(f.Data) != size {
+			return nil, fmt.Errorf("block %d: %q should contain %d bytes, but has %d", i, f.Name, size, len(f.Data))
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// Programs returns only the BASIC programs stored upon a tape image.
+func Programs(tape []byte) ([]File, error) {
+	files, err := Files(tape)
+	if err != nil {
+		return nil, err
+	}
+
+	var programs []File
+	for _, f := range files {
+		if f.Kind == Program {
+			programs = append(programs, f)
+		}
+	}
+	return programs, nil
+}
+
+// Source converts a program into BASIC source, as Detokenize does.
+func (f File) Source() (string, error) {
+	if f.Kind != Program {
+		return "", fmt.Errorf("%q is not a program", f.Name)
+	}
+
+	// Any variables which were saved with the program follow it.
+	data := f.Data
+	if f.Length < len(data) {
+		data = data[:f.Length]
+	}
+	return Detokenize(data)
+}
+
+// word returns the little-endian 16-bit value at the start of the slice.
+func word(b []byte) int {
+	return int(b[0]) | int(b[1])<<8
+}
@@ -0,0 +1,298 @@
+// tap_test.go - Test-cases for reading tape images.
+
+package tap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// block returns a block, as stored in a tape image, with its length
+// and checksum.
+func block(flag byte, data []byte) []byte {
+	raw := append([]byte{flag}, data...)
+	sum := byte(0)
+	for _, b := range raw {
+		sum ^= b
+	}
+	raw = append(raw, sum)
+	return append([]byte{byte(len(raw)), byte(len(raw) >> 8)}, raw...)
+}
+
+// header returns a header block.
+func header(kind Kind, name string, size int, param1 int, param2 int) []byte {
+	data := []byte{byte(kind)}
+	data = append(data, []byte((name + "          ")[:10])...)
+	for _, n := range []int{size, param1, param2} {
+		data = append(data, byte(n), byte(n>>8))
+	}
+	return block(headerFlag, data)
+}
+
+// program returns a program stored in a header and a data block.
+func program(name string, autostart int, data []byte) []byte {
+	return append(header(Program, name, len(data), autostart, len(data)), block(dataFlag, data)...)
+}
+
+// basic returns a line of a program, terminated by ENTER.
+func basic(n int, text ...interface{}) []byte {
+	var body []byte
+	for _, t := range text {
+		switch v := t.(type) {
+		case string:
+			body = append(body, v...)
+		case int:
+			body = append(body, byte(v))
+		case []byte:
+			body = append(body, v...)
+		}
+	}
+	body = append(body, enter)
+	size := len(body)
+	return append([]byte{byte(n >> 8), byte(n), byte(size), byte(size >> 8)}, body...)
+}
+
+// integer returns the hidden form of a small integer.
+func integer(n int) []byte {
+	return []byte{number, 0, 0, byte(n), byte(n >> 8), 0}
+}
+
+// TestDetokenize tests the conversion of single lines.
+func TestDetokenize(t *testing.T) {
+
+	type Test struct {
+		Input  []byte
+		Output string
+	}
+
+	tests := []Test{
+		{Input: basic(10, 0xF5, `"Hello"`),
+			Output: `10 PRINT "Hello"`},
+
+		// Numbers are shown as written.
+		{Input: basic(20, 0xF1, "a=10", integer(10)),
+			Output: "20 LET a=10"},
+		{Input: basic(20, 0xF1, "a=0.5", number, 0x80, 0, 0, 0, 0),
+			Output: "20 LET a=0.5"},
+		{Input: basic(20, 0xF1, "a=0.1", number, 0x7D, 0x4C, 0xCC, 0xCC, 0xCD),
+			Output: "20 LET a=0.1"},
+
+		// Unless the hidden value differs.
+		{Input: basic(30, 0xF1, "a=0", integer(1)),
+			Output: "30 LET a=1"},
+		{Input: basic(30, 0xF1, "a=1", number, 0x82, 0x49, 0x0F, 0xDA, 0xA2),
+			Output: "30 LET a=3.141592653"},
+
+		// Numbers following BIN are in binary.
+		{Input: basic(40, 0xF1, "b=", 0xC4, "101", integer(5)),
+			Output: "40 LET b=BIN 101"},
+
+		// Keywords are spaced, and GO TO is a single word.
+		{Input: basic(50, 0xFA, "a", 0xC7, "1", integer(1), 0xCB, 0xEC, "100", integer(100)),
+			Output: "50 IF a<=1 THEN GOTO 100"},
+		{Input: basic(60, 0xF5, "(", 0xA5, "*6)"),
+			Output: "60 PRINT (RND*6)"},
+
+		// DEF FN parameters are followed by empty numbers.
+		{Input: basic(70, 0xCE, "f(x", integer(0), ")=x*2", integer(2)),
+			Output: "70 DEF FN f(x)=x*2"},
+
+		// Colour-codes are hidden.
+		{Input: basic(80, 0xF5, 0x10, 2, 0x16, 1, 2, `"x"`),
+			Output: `80 PRINT "x"`},
+
+		// Backslashes in strings are escaped.
+		{Input: basic(90, 0xF5, `"a\b"`),
+			Output: `90 PRINT "a\\b"`},
+
+		// Comments may contain anything.
+		{Input: basic(100, 0xEA, "hi", 0x0E, 0xF5, 0x60, 0x81),
+			Output: "100 REM hi PRINT £▝"},
+
+		// User-defined graphics look like letters.
+		{Input: basic(110, 0xF5, "\"", 0x90, 0xA4, "\""),
+			Output: `110 PRINT "AU"`},
+	}
+
+	for _, test := range tests {
+		out, err := Detokenize(test.Input)
+		if err != nil {
+			t.Errorf("unexpected error converting % X: %s", test.Input, err.Error())
+			continue
+		}
+		if out != test.Output+"\n" {
+			t.Errorf("converting % X gave %q, expected %q", test.Input, out, test.Output)
+		}
+	}
+}
+
+// TestDecodeNumber tests the five-byte form of numbers.
+func TestDecodeNumber(t *testing.T) {
+
+	type Test struct {
+		Input  []byte
+		Output float64
+	}
+
+	tests := []Test{
+		{Input: []byte{0, 0, 0, 0, 0}, Output: 0},
+		{Input: []byte{0, 0, 0xFF, 0xFF, 0}, Output: 65535},
+		{Input: []byte{0, 0xFF, 0xFB, 0xFF, 0}, Output: -5},
+		{Input: []byte{0x80, 0, 0, 0, 0}, Output: 0.5},
+		{Input: []byte{0x80, 0x80, 0, 0, 0}, Output: -0.5},
+		{Input: []byte{0x91, 0x00, 0, 0, 0}, Output: 65536},
+	}
+
+	for _, test := range tests {
+		out := decodeNumber(test.Input)
+		if out != test.Output {
+			t.Errorf("decoding % X gave %v, expected %v", test.Input, out, test.Output)
+		}
+	}
+}
+
+// TestFiles tests reading a tape with several files upon it.
+func TestFiles(t *testing.T) {
+
+	one := basic(10, 0xF5, `"one"`)
+	two := basic(20, 0xF5, `"two"`)
+
+	var tape []byte
+	tape = append(tape, program("first", 10, one)...)
+	tape = append(tape, header(Code, "screen", 3, 16384, 32768)...)
+	tape = append(tape, block(dataFlag, []byte{1, 2, 3})...)
+	tape = append(tape, block(dataFlag, []byte{4, 5})...)
+	tape = append(tape, program("second", 32768, two)...)
+
+	files, err := Files(tape)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, found %d", len(files))
+	}
+
+	expected := []struct {
+		kind      Kind
+		name      string
+		autostart int
+		size      int
+	}{
+		{Program, "first", 10, len(one)},
+		{Code, "screen", -1, 3},
+		{Headerless, "", -1, 2},
+		{Program, "second", -1, len(two)},
+	}
+	for i, e := range expected {
+		f := files[i]
+		if f.Kind != e.kind || f.Name != e.name || f.Autostart != e.autostart || len(f.Data) != e.size {
+			t.Errorf("file %d was %v %q %d %d, expected %v", i, f.Kind, f.Name, f.Autostart, len(f.Data), e)
+		}
+	}
+
+	programs, err := Programs(tape)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(programs) != 2 {
+		t.Fatalf("expected 2 programs, found %d", len(programs))
+	}
+	src, err := programs[1].Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if src != "20 PRINT \"two\"\n" {
+		t.Errorf("unexpected source %q", src)
+	}
+
+	if _, err := files[1].Source(); err == nil {
+		t.Errorf("expected an error converting code")
+	}
+}
+
+// TestVariables ensures that saved variables aren't treated as code.
+func TestVariables(t *testing.T) {
+
+	code := basic(10, 0xF5, "1", integer(1))
+	vars := []byte{0x61, 0, 0, 3, 0, 0}
+
+	data := append(append([]byte{}, code...), vars...)
+	tape := append(header(Program, "vars", len(data), 10, len(code)), block(dataFlag, data)...)
+
+	programs, err := Programs(tape)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	src, err := programs[0].Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if src != "10 PRINT 1\n" {
+		t.Errorf("unexpected source %q", src)
+	}
+}
+
+// TestErrors tests that broken tapes are reported.
+func TestErrors(t *testing.T) {
+
+	good := program("test", 10, basic(10, 0xF5, "1", integer(1)))
+
+	bad := append([]byte{}, good...)
+	bad[len(bad)-1] ^= 0xFF
+
+	tests := map[string][]byte{
+		"checksum":              bad,
+		"truncated length":      append(append([]byte{}, good...), 1),
+		"only 2 remain":         append(append([]byte{}, good...), 5, 0, 0xFF, 0),
+		"not followed by":       header(Program, "test", 3, 10, 3),
+		"header has 2 bytes":    block(headerFlag, []byte{1, 2}),
+		"should contain 9":      append(header(Program, "x", 9, 0, 9), block(dataFlag, []byte{1})...),
+		"truncated line header": program("x", 0, []byte{0, 10}),
+		"truncated number":      program("x", 0, basic(10, "1", number, 0, 0)),
+	}
+
+	for expected, tape := range tests {
+		programs, err := Programs(tape)
+		if err == nil {
+			_, err = programs[0].Source()
+		}
+		if err == nil {
+			t.Errorf("expected an error containing %q", expected)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q doesn't contain %q", err.Error(), expected)
+		}
+	}
+}
+
+// TestRun ensures a converted program may be run.
+func TestRun(t *testing.T) {
+
+	data := append(basic(10, 0xF1, "a=0", integer(3)),
+		basic(20, 0xFA, "a=3", integer(3), 0xCB, 0xEC, "50", integer(50))...)
+	data = append(data, basic(30, 0xF1, "a=0", integer(0))...)
+	data = append(data, basic(50, 0xF1, "b=a*2", integer(2))...)
+
+	src, err := Detokenize(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	e, err := eval.New(tokenizer.New(src))
+	if err != nil {
+		t.Fatalf("error parsing %q: %s", src, err.Error())
+	}
+	if err = e.Run(); err != nil {
+		t.Fatalf("error running %q: %s", src, err.Error())
+	}
+
+	b := e.GetVariable("b")
+	if b.Type() != object.NUMBER || b.(*object.NumberObject).Value != 6 {
+		t.Errorf("unexpected result %s", b.String())
+	}
+}
@@ -0,0 +1,12 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package terminal
+
+import "syscall"
+
+// The requests which read, and change, the mode of a terminal.
+const (
+	getMode = syscall.TIOCGETA
+	setMode = syscall.TIOCSETA
+)
@@ -0,0 +1,12 @@
+//go:build linux
+// +build linux
+
+package terminal
+
+import "syscall"
+
+// The requests which read, and change, the mode of a terminal.
+const (
+	getMode = syscall.TCGETS
+	setMode = syscall.TCSETS
+)
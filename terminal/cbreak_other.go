@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package terminal
+
+import (
+	"errors"
+	"os"
+)
+
+// cbreak would return functions which put the given terminal into cbreak
+// mode, and back, but we don't know how to upon this system, so keys
+// arrive a line at a time.
+func cbreak(f *os.File) (func() error, func() error, error) {
+	return nil, nil, errors.New("cbreak mode isn't supported")
+}
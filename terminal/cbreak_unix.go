@@ -0,0 +1,45 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package terminal
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl reads, or changes, the mode of the terminal.
+func ioctl(f *os.File, request uintptr, mode *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(mode)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// cbreak returns functions which put the given terminal into cbreak mode,
+// where each key is read as it is pressed, and isn't echoed, and return it
+// to the mode it was in.  Output is unchanged, and Ctrl-C still
+// interrupts us.
+//
+// An error is returned if it isn't a terminal.
+func cbreak(f *os.File) (func() error, func() error, error) {
+	var old syscall.Termios
+	if err := ioctl(f, getMode, &old); err != nil {
+		return nil, nil, err
+	}
+
+	mode := old
+	mode.Lflag &^= syscall.ICANON | syscall.ECHO
+	mode.Cc[syscall.VMIN] = 1
+	mode.Cc[syscall.VTIME] = 0
+
+	on := func() error {
+		return ioctl(f, setMode, &mode)
+	}
+	off := func() error {
+		return ioctl(f, setMode, &old)
+	}
+	return on, off, nil
+}
@@ -0,0 +1,179 @@
+package terminal
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/skx/gobasic/builtin"
+	"github.com/skx/gobasic/object"
+)
+
+// cursorKeys holds the codes INKEY$ returns for the cursor keys, which
+// are those the ZX Spectrum used, indexed by the letter which ends the
+// escape sequence a terminal sends for each.
+var cursorKeys = map[byte]string{
+	'A': "\x0b", // up
+	'B': "\x0a", // down
+	'C': "\x09", // right
+	'D': "\x08", // left
+}
+
+// typedLimit is the most input we keep, which hasn't been read.
+const typedLimit = 4096
+
+// Press records the given input as having been typed, so that INKEY$
+// returns it a key at a time, and INPUT a line at a time.
+//
+// Input is dropped if too much is waiting to be read.
+func (t *Terminal) Press(input string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if room := typedLimit - len(t.typed); len(input) > room {
+		input = input[:room]
+	}
+	t.typed = append(t.typed, input...)
+	t.notify()
+}
+
+// notify wakes anything waiting for input, which the caller must hold
+// the lock to change.
+func (t *Terminal) notify() {
+	select {
+	case t.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// keys splits the given input into keys, which are single characters,
+// other than the escape sequences sent by keys such as those which move
+// the cursor.
+func keys(input string) []string {
+	var out []string
+	for len(input) > 0 {
+		key, n := nextKey(input)
+		out = append(out, key)
+		input = input[n:]
+	}
+	return out
+}
+
+// nextKey returns the first key of the given input, and the number of
+// bytes it was sent as.
+func nextKey(input string) (string, int) {
+	switch c := input[0]; {
+	case c == '\n' || c == '\r':
+		// ENTER is CHR$ 13, whichever the keyboard sends.
+		if c == '\r' && len(input) > 1 && input[1] == '\n' {
+			return "\r", 2
+		}
+		return "\r", 1
+
+	case c == 0x1b && len(input) > 2 && (input[1] == '[' || input[1] == 'O'):
+		// An escape sequence runs until a letter, or "~".
+		end := 2
+		for end < len(input)-1 && !(input[end] >= '@' && input[end] <= '~') {
+			end++
+		}
+		if key, ok := cursorKeys[input[end]]; ok && end == 2 {
+			return key, end + 1
+		}
+		return input[:end+1], end + 1
+	}
+	return input[:1], 1
+}
+
+// read starts reading the keyboard, recording the input as it arrives.
+// If it is a terminal we learn how to change its mode, which INKEY$ and
+// INPUT each choose as they need.
+func (t *Terminal) read() {
+	if t.opts.Keyboard == nil {
+		t.lock.Lock()
+		t.closed = true
+		t.lock.Unlock()
+		return
+	}
+	if f, ok := t.opts.Keyboard.(*os.File); ok {
+		if on, off, err := cbreak(f); err == nil {
+			t.cbreak, t.cooked = on, off
+		}
+	}
+
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := t.opts.Keyboard.Read(buf)
+			if n > 0 {
+				t.Press(string(buf[:n]))
+			}
+			if err != nil {
+				t.lock.Lock()
+				t.closed = true
+				t.notify()
+				t.lock.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+// mode puts the keyboard into cbreak mode, if raw is true, so that keys
+// arrive as they're pressed, or returns it to the mode it was in, so that
+// lines may be typed, and edited, as usual.
+func (t *Terminal) mode(raw bool) {
+	if t.cbreak == nil || t.raw == raw {
+		return
+	}
+	if raw {
+		t.cbreak()
+	} else {
+		t.cooked()
+	}
+	t.raw = raw
+}
+
+// Read reads the next line which was typed, or as much of it as fits, so
+// that INPUT shares the keyboard with INKEY$.  It waits until there is
+// some input, returning io.EOF once the keyboard has none left.
+func (t *Terminal) Read(p []byte) (int, error) {
+	t.listen.Do(t.read)
+	t.mode(false)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for len(t.typed) == 0 && !t.closed {
+		t.lock.Unlock()
+		<-t.arrived
+		t.lock.Lock()
+	}
+	if len(t.typed) == 0 {
+		return 0, io.EOF
+	}
+
+	line := t.typed
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i+1]
+	}
+	n := copy(p, line)
+	t.typed = t.typed[n:]
+	return n, nil
+}
+
+// inkeyFunction implements INKEY$, which returns the next key which was
+// pressed, without waiting for one.
+func (t *Terminal) inkeyFunction(env builtin.Environment, args []object.Object) object.Object {
+	t.listen.Do(t.read)
+	t.mode(true)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.typed) == 0 {
+		return &object.StringObject{Value: ""}
+	}
+	key, n := nextKey(string(t.typed))
+	t.typed = t.typed[n:]
+	return &object.StringObject{Value: key}
+}
@@ -0,0 +1,246 @@
+package terminal
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cell is a single character upon a Screen.
+type Cell struct {
+
+	// Char is the character shown, which is a space if nothing has
+	// been written.
+	Char rune
+
+	// Ink and Paper are the colours of the character, and behind it,
+	// from 0 to 7, or -1 for the terminal's own, and Bright is true
+	// if they're bright.
+	Ink    int
+	Paper  int
+	Bright bool
+}
+
+// Screen is a virtual terminal, which shows what the escape sequences
+// written by our primitives would do to a real one.  It allows programs
+// which use them to be tested.
+//
+// Newlines move to the start of the next line, and the text scrolls up
+// when it reaches the bottom.
+type Screen struct {
+
+	// cells holds the contents of the screen, a row at a time.
+	cells [][]Cell
+
+	// row and col are the position of the cursor, counting from
+	// zero, and hidden is true if it has been hidden.
+	row    int
+	col    int
+	hidden bool
+
+	// pen holds the colours of the text which is written.
+	pen Cell
+
+	// pending holds the start of an escape sequence, or character,
+	// which the next write will finish.
+	pending []byte
+}
+
+// spectrum holds the colour of the ZX Spectrum with each ANSI number.
+var spectrum = [8]int{0, 2, 4, 6, 1, 3, 5, 7}
+
+// NewScreen creates a blank screen of the given size.
+func NewScreen(rows, cols int) *Screen {
+	s := &Screen{pen: Cell{Char: ' ', Ink: -1, Paper: -1}}
+	s.cells = make([][]Cell, rows)
+	for r := range s.cells {
+		s.cells[r] = s.blank(cols)
+	}
+	return s
+}
+
+// blank returns an empty row of the given width, in the current colours.
+func (s *Screen) blank(cols int) []Cell {
+	row := make([]Cell, cols)
+	for c := range row {
+		row[c] = s.pen
+		row[c].Char = ' '
+	}
+	return row
+}
+
+// Write updates the screen with the given output.
+func (s *Screen) Write(p []byte) (int, error) {
+	buf := append(s.pending, p...)
+	s.pending = nil
+
+	for i := 0; i < len(buf); {
+		switch buf[i] {
+		case '\n':
+			s.newline()
+			i++
+		case '\r':
+			s.col = 0
+			i++
+		case 0x1b:
+			n := s.escape(buf[i:])
+			if n == 0 {
+				s.pending = append([]byte{}, buf[i:]...)
+				return len(p), nil
+			}
+			i += n
+		default:
+			if !utf8.FullRune(buf[i:]) {
+				s.pending = append([]byte{}, buf[i:]...)
+				return len(p), nil
+			}
+			r, n := utf8.DecodeRune(buf[i:])
+			s.put(r)
+			i += n
+		}
+	}
+	return len(p), nil
+}
+
+// put writes a character at the cursor, and moves it along, to the next
+// line if it reaches the edge of the screen.
+func (s *Screen) put(r rune) {
+	if len(s.cells) == 0 || len(s.cells[0]) == 0 {
+		return
+	}
+	if s.col >= len(s.cells[0]) {
+		s.newline()
+	}
+	s.cells[s.row][s.col] = s.pen
+	s.cells[s.row][s.col].Char = r
+	s.col++
+}
+
+// newline moves the cursor to the start of the next line, scrolling the
+// screen up if it is at the bottom.
+func (s *Screen) newline() {
+	s.col = 0
+	if s.row < len(s.cells)-1 {
+		s.row++
+		return
+	}
+	if len(s.cells) > 0 {
+		s.cells = append(s.cells[1:], s.blank(len(s.cells[0])))
+	}
+}
+
+// escape handles the escape sequence at the start of the given output,
+// returning its length, or zero if it is incomplete.
+func (s *Screen) escape(buf []byte) int {
+	if len(buf) < 2 {
+		return 0
+	}
+	if buf[1] != '[' {
+		return 2
+	}
+
+	end := 2
+	for end < len(buf) && !(buf[end] >= '@' && buf[end] <= '~') {
+		end++
+	}
+	if end == len(buf) {
+		return 0
+	}
+
+	params := string(buf[2:end])
+	private := strings.HasPrefix(params, "?")
+	var n []int
+	for _, p := range strings.Split(strings.TrimPrefix(params, "?"), ";") {
+		v, _ := strconv.Atoi(p)
+		n = append(n, v)
+	}
+
+	switch {
+	case buf[end] == 'H':
+		row, col := 1, 1
+		if len(n) > 0 && n[0] > 0 {
+			row = n[0]
+		}
+		if len(n) > 1 && n[1] > 0 {
+			col = n[1]
+		}
+		s.row, s.col = clamp(row-1, len(s.cells)-1), clamp(col-1, len(s.cells[0])-1)
+
+	case buf[end] == 'J' && n[0] == 2:
+		for r := range s.cells {
+			s.cells[r] = s.blank(len(s.cells[r]))
+		}
+
+	case buf[end] == 'm':
+		for _, v := range n {
+			s.attribute(v)
+		}
+
+	case private && buf[end] == 'l' && n[0] == 25:
+		s.hidden = true
+
+	case private && buf[end] == 'h' && n[0] == 25:
+		s.hidden = false
+	}
+	return end + 1
+}
+
+// attribute changes the colours of the text to be written.
+func (s *Screen) attribute(v int) {
+	switch {
+	case v == 0:
+		s.pen = Cell{Char: ' ', Ink: -1, Paper: -1}
+	case v == 1:
+		s.pen.Bright = true
+	case v == 22:
+		s.pen.Bright = false
+	case v >= 30 && v <= 37:
+		s.pen.Ink = spectrum[v-30]
+	case v == 39:
+		s.pen.Ink = -1
+	case v >= 40 && v <= 47:
+		s.pen.Paper = spectrum[v-40]
+	case v == 49:
+		s.pen.Paper = -1
+	case v >= 90 && v <= 97:
+		s.pen.Ink, s.pen.Bright = spectrum[v-90], true
+	case v >= 100 && v <= 107:
+		s.pen.Paper, s.pen.Bright = spectrum[v-100], true
+	}
+}
+
+// clamp limits the given position to the screen.
+func clamp(v, max int) int {
+	if v > max {
+		v = max
+	}
+	if v < 0 {
+		v = 0
+	}
+	return v
+}
+
+// At returns the cell at the given position, counting from zero.
+func (s *Screen) At(row, col int) Cell {
+	return s.cells[row][col]
+}
+
+// Cursor returns the position of the cursor, counting from zero, and
+// whether it is shown.
+func (s *Screen) Cursor() (int, int, bool) {
+	return s.row, s.col, !s.hidden
+}
+
+// String returns the text upon the screen, a line at a time, without the
+// spaces at the end of each.
+func (s *Screen) String() string {
+	lines := make([]string, len(s.cells))
+	for r, row := range s.cells {
+		var b strings.Builder
+		for _, c := range row {
+			b.WriteRune(c.Char)
+		}
+		lines[r] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
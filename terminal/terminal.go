@@ -0,0 +1,270 @@
+// Package terminal provides primitives which control the screen of a
+// terminal, and read its keyboard, which may be added to any BASIC
+// interpreter.
+//
+// Each interpreter is given a terminal of its own:
+//
+//	e, _ := eval.New(tokenizer.New(src))
+//	term := terminal.Register(e, terminal.Options{Keyboard: os.Stdin})
+//	defer term.Close()
+//
+// The program may then write where it likes, in colour, and read keys as
+// they're pressed, which is enough for a simple game:
+//
+//	10 CLS : CURSOR OFF
+//	20 INK 2 : PRINT AT 10, 15 "HELLO"
+//	30 LET k$ = INKEY$
+//	40 IF k$ = "" THEN GOTO 30
+//
+// The screen is controlled by writing ANSI escape sequences to the output
+// of the interpreter, so a Screen may be used to see what it would show.
+//
+// The names of the primitives become keywords, which programs can't use
+// for variables, so it's best to only register a terminal when asked to.
+// The graphics package also provides INK and PAPER, so an interpreter
+// should be given one or the other.
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/skx/gobasic/builtin"
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/object"
+)
+
+// Options control the terminal created by New.
+type Options struct {
+
+	// Keyboard is read by INKEY$, and INPUT, from the first time
+	// either is used, so that neither misses what was typed for the
+	// other.  If it is a terminal it is put into cbreak mode while
+	// INKEY$ is used, so that keys arrive as they're pressed, without
+	// being echoed, and back while INPUT is, until Close is called.
+	// If it is nil only the input given to Press is seen.
+	Keyboard io.Reader
+}
+
+// Terminal holds the state of the screen, and the keyboard.
+type Terminal struct {
+
+	// opts holds our options.
+	opts Options
+
+	// ink and paper are the colours of the text, from 0 to 7, or -1
+	// for the terminal's own, and bright is true if they're bright.
+	ink    int
+	paper  int
+	bright bool
+
+	// hidden is true if the cursor has been hidden.
+	hidden bool
+
+	// out is the writer we last wrote to, which Close resets.
+	out *bufio.Writer
+
+	// typed holds the input which hasn't been read, arrived is sent
+	// to when there's more, and closed is true once the keyboard has
+	// none left.  lock protects them all.
+	lock    sync.Mutex
+	typed   []byte
+	arrived chan struct{}
+	closed  bool
+
+	// listen starts reading the keyboard, cbreak and cooked change its
+	// mode, if it is a terminal, and raw is true if it is in cbreak
+	// mode.
+	listen sync.Once
+	cbreak func() error
+	cooked func() error
+	raw    bool
+}
+
+// ansi holds the number ANSI gives each of our colours, which are those
+// of the ZX Spectrum.
+var ansi = [8]int{0, 4, 1, 5, 2, 6, 3, 7}
+
+// New creates a terminal, which keeps the colours of the screen until the
+// program chooses others.
+func New(opts Options) *Terminal {
+	return &Terminal{opts: opts, ink: -1, paper: -1, arrived: make(chan struct{}, 1)}
+}
+
+// Register creates a terminal with the given options, and makes the
+// primitives which use it available to the given interpreter.  If there
+// is a keyboard INPUT reads it through the terminal too.
+func Register(e *eval.Interpreter, opts Options) *Terminal {
+	t := New(opts)
+	t.Register(e)
+	if opts.Keyboard != nil {
+		e.STDIN = bufio.NewReader(t)
+	}
+	return t
+}
+
+// Register makes the primitives which use the terminal available to the
+// given interpreter:
+//
+//	CLS                     Clear the screen.
+//	LOCATE row, col         Move the cursor, counting from 1, 1 at the
+//	                        top-left, as QBasic did.
+//	PRINT AT row, col "s"   Print at a position, counting from 0, 0, as
+//	                        the ZX Spectrum did.
+//	INK n, PAPER n          Choose the colour of the text, and behind it,
+//	                        from the eight colours of the ZX Spectrum.
+//	BRIGHT ON|OFF           Make both colours brighter, or not.
+//	CURSOR ON|OFF           Show, or hide, the cursor.
+//	INKEY$                  The key which was pressed, or "" if there
+//	                        was none.
+func (t *Terminal) Register(e *eval.Interpreter) {
+	e.RegisterBuiltin("AT", 2, t.atFunction)
+	e.RegisterBuiltin("BRIGHT", 1, t.brightFunction)
+	e.RegisterBuiltin("CLS", 0, t.clsFunction)
+	e.RegisterBuiltin("CURSOR", 1, t.cursorFunction)
+	e.RegisterBuiltin("INK", 1, t.inkFunction)
+	e.RegisterBuiltin("INKEY$", 0, t.inkeyFunction)
+	e.RegisterBuiltin("LOCATE", 2, t.locateFunction)
+	e.RegisterBuiltin("PAPER", 1, t.paperFunction)
+}
+
+// Close returns the keyboard to the mode it was in, and the screen to
+// its own colours, with the cursor shown, if the program changed them.
+func (t *Terminal) Close() error {
+	if t.out != nil && (t.ink >= 0 || t.paper >= 0 || t.bright || t.hidden) {
+		t.out.WriteString("\x1b[0m\x1b[?25h")
+		t.out.Flush()
+	}
+	if t.raw {
+		t.raw = false
+		return t.cooked()
+	}
+	return nil
+}
+
+// write sends the given escape sequence to the output of the interpreter.
+func (t *Terminal) write(env builtin.Environment, s string) object.Object {
+	t.out = env.StdOutput()
+	t.out.WriteString(s)
+	t.out.Flush()
+	return &object.NumberObject{Value: 0}
+}
+
+// attributes returns the escape sequence which selects our colours.
+func (t *Terminal) attributes() string {
+	codes := []string{"0"}
+	switch {
+	case t.ink >= 0 && t.bright:
+		codes = append(codes, fmt.Sprintf("%d", 90+ansi[t.ink]))
+	case t.ink >= 0:
+		codes = append(codes, fmt.Sprintf("%d", 30+ansi[t.ink]))
+	case t.bright:
+		codes = append(codes, "1")
+	}
+	switch {
+	case t.paper >= 0 && t.bright:
+		codes = append(codes, fmt.Sprintf("%d", 100+ansi[t.paper]))
+	case t.paper >= 0:
+		codes = append(codes, fmt.Sprintf("%d", 40+ansi[t.paper]))
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// numbers returns the arguments given to a primitive as integers, checking
+// each is at least the given value.
+func numbers(name string, args []object.Object, min int) ([]int, object.Object) {
+	var out []int
+	for i, arg := range args {
+		n, ok := arg.(*object.NumberObject)
+		if !ok {
+			return nil, object.CodedError(object.ErrTypeMismatch, "Wrong type for argument %d", i+1)
+		}
+		if int(n.Value) < min {
+			return nil, object.CodedError(object.ErrIllegalFunction, "%s expects numbers of at least %d", name, min)
+		}
+		out = append(out, int(n.Value))
+	}
+	return out, nil
+}
+
+// colour returns the colour given to INK, or PAPER.
+func colour(name string, args []object.Object) (int, object.Object) {
+	n, err := numbers(name, args, 0)
+	if err != nil {
+		return 0, err
+	}
+	if n[0] >= len(ansi) {
+		return 0, object.CodedError(object.ErrIllegalFunction, "%s colour must be between 0 and %d", name, len(ansi)-1)
+	}
+	return n[0], nil
+}
+
+// clsFunction implements CLS.
+func (t *Terminal) clsFunction(env builtin.Environment, args []object.Object) object.Object {
+	return t.write(env, "\x1b[2J\x1b[H")
+}
+
+// locateFunction implements LOCATE.
+func (t *Terminal) locateFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers("LOCATE", args, 1)
+	if err != nil {
+		return err
+	}
+	return t.write(env, fmt.Sprintf("\x1b[%d;%dH", n[0], n[1]))
+}
+
+// atFunction implements AT, which returns the escape sequence that PRINT
+// writes to move the cursor.
+func (t *Terminal) atFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers("AT", args, 0)
+	if err != nil {
+		return err
+	}
+	return &object.StringObject{Value: fmt.Sprintf("\x1b[%d;%dH", n[0]+1, n[1]+1)}
+}
+
+// inkFunction implements INK.
+func (t *Terminal) inkFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := colour("INK", args)
+	if err != nil {
+		return err
+	}
+	t.ink = n
+	return t.write(env, t.attributes())
+}
+
+// paperFunction implements PAPER.
+func (t *Terminal) paperFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := colour("PAPER", args)
+	if err != nil {
+		return err
+	}
+	t.paper = n
+	return t.write(env, t.attributes())
+}
+
+// brightFunction implements BRIGHT.
+func (t *Terminal) brightFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers("BRIGHT", args, 0)
+	if err != nil {
+		return err
+	}
+	t.bright = n[0] != 0
+	return t.write(env, t.attributes())
+}
+
+// cursorFunction implements CURSOR.
+func (t *Terminal) cursorFunction(env builtin.Environment, args []object.Object) object.Object {
+	n, err := numbers("CURSOR", args, 0)
+	if err != nil {
+		return err
+	}
+	t.hidden = n[0] == 0
+	if t.hidden {
+		return t.write(env, "\x1b[?25l")
+	}
+	return t.write(env, "\x1b[?25h")
+}
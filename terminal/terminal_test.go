@@ -0,0 +1,203 @@
+// terminal_test.go - Test-cases for our terminal, and its screen.
+
+package terminal
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/internal/evaltest"
+	"github.com/skx/gobasic/object"
+)
+
+// run runs the given program upon a screen of 5 rows, by 20 columns,
+// returning the terminal and the screen.
+func run(t *testing.T, src string) (*Terminal, *Screen, error) {
+	var term *Terminal
+	screen := NewScreen(5, 20)
+	_, err := evaltest.Run(t, src, func(e *eval.Interpreter) {
+		e.STDOUT = bufio.NewWriter(screen)
+		term = Register(e, Options{})
+	})
+	return term, screen, err
+}
+
+// TestPosition tests the primitives which move the cursor.
+func TestPosition(t *testing.T) {
+	tests := map[string]string{
+		"10 PRINT AT 1, 2 \"HI\"\n":                   "\n  HI",
+		"10 LOCATE 2, 3 : PRINT \"HI\"\n":             "\n  HI",
+		"10 PRINT \"A\\n\"\n20 CLS\n30 PRINT \"B\"\n": "B",
+		"10 PRINT AT 4, 18 \"ABC\"\n":                 "\n\n\n                  AB\nC",
+		"10 PRINT AT 0, 0 \"X\" : LOCATE 99, 99\n":    "X",
+		"10 PRINT AT 3, 0 \"A\\n\"\n20 PRINT \"B\"\n": "\n\n\nA\nB",
+	}
+
+	for src, expected := range tests {
+		_, screen, err := run(t, src)
+		if err != nil {
+			t.Errorf("Error running %q: %s", src, err.Error())
+			continue
+		}
+		if got := strings.TrimRight(screen.String(), "\n"); got != expected {
+			t.Errorf("%q showed %q, not %q", src, got, expected)
+		}
+	}
+}
+
+// TestColours tests the colours of the text, and the cursor.
+func TestColours(t *testing.T) {
+	term, screen, err := run(t, `10 INK 2 : PAPER 6 : PRINT "A\n"
+20 BRIGHT ON : PRINT "B\n"
+30 BRIGHT OFF : INK 0 : PRINT "C\n"
+40 CURSOR OFF
+`)
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+
+	expected := []Cell{
+		{Char: 'A', Ink: 2, Paper: 6},
+		{Char: 'B', Ink: 2, Paper: 6, Bright: true},
+		{Char: 'C', Ink: 0, Paper: 6},
+	}
+	for row, cell := range expected {
+		if got := screen.At(row, 0); got != cell {
+			t.Errorf("Row %d showed %v, not %v", row, got, cell)
+		}
+	}
+	if _, _, visible := screen.Cursor(); visible {
+		t.Errorf("The cursor wasn't hidden")
+	}
+
+	// Closing the terminal restores its colours, and the cursor.
+	if err = term.Close(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	screen.Write([]byte("D"))
+	if got := screen.At(3, 0); got != (Cell{Char: 'D', Ink: -1, Paper: -1}) {
+		t.Errorf("After closing the terminal it showed %v", got)
+	}
+	if _, _, visible := screen.Cursor(); !visible {
+		t.Errorf("The cursor wasn't shown")
+	}
+
+	// Brightness alone makes the text bold.
+	_, screen, err = run(t, "10 BRIGHT ON : PRINT \"E\"\n")
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+	if got := screen.At(0, 0); got != (Cell{Char: 'E', Ink: -1, Paper: -1, Bright: true}) {
+		t.Errorf("Bright text was %v", got)
+	}
+}
+
+// TestInkey tests reading the keyboard.
+func TestInkey(t *testing.T) {
+	e, err := evaltest.Run(t, `10 LET a$ = INKEY$
+20 LET b$ = INKEY$
+30 LET c$ = INKEY$
+40 LET d$ = INKEY$
+`, func(e *eval.Interpreter) {
+		Register(e, Options{}).Press("q\x1b[A\r\n")
+	})
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+
+	expected := map[string]string{"a$": "q", "b$": "\x0b", "c$": "\r", "d$": ""}
+	for name, value := range expected {
+		if got := e.GetVariable(name).(*object.StringObject).Value; got != value {
+			t.Errorf("%s was %q, not %q", name, got, value)
+		}
+	}
+
+	// Keys are also read from the keyboard we're given.
+	e, err = evaltest.Run(t, "10 LET k$ = INKEY$\n20 IF k$ = \"\" THEN GOTO 10\n", func(e *eval.Interpreter) {
+		Register(e, Options{Keyboard: strings.NewReader("z")})
+	})
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+	if got := e.GetVariable("k$").(*object.StringObject).Value; got != "z" {
+		t.Errorf("k$ was %q, not \"z\"", got)
+	}
+
+	// INPUT shares the keyboard, so neither misses what was typed.
+	e, err = evaltest.Run(t, `10 LET a$ = INKEY$
+20 IF a$ = "" THEN GOTO 10
+30 INPUT "", b$
+40 LET c$ = INKEY$
+50 IF c$ = "" THEN GOTO 40
+60 INPUT "", d
+70 LET e$ = INKEY$
+`, func(e *eval.Interpreter) {
+		Register(e, Options{Keyboard: strings.NewReader("yhello world\nn42\n")})
+	})
+	if err != nil {
+		t.Fatalf("Error running program: %s", err.Error())
+	}
+	expected = map[string]string{"a$": "y", "b$": "hello world", "c$": "n", "e$": ""}
+	for name, value := range expected {
+		if got := e.GetVariable(name).(*object.StringObject).Value; got != value {
+			t.Errorf("%s was %q, not %q", name, got, value)
+		}
+	}
+	if got := e.GetVariable("d").(*object.NumberObject).Value; got != 42 {
+		t.Errorf("d was %v, not 42", got)
+	}
+}
+
+// TestKeys tests splitting input into keys.
+func TestKeys(t *testing.T) {
+	tests := map[string][]string{
+		"ab":             {"a", "b"},
+		"\n\r\r\n":       {"\r", "\r", "\r"},
+		"\x1b[D\x1bOC":   {"\x08", "\x09"},
+		"\x1b[3~x":       {"\x1b[3~", "x"},
+		"\x1b":           {"\x1b"},
+		"\x1b[1;5Ay\x1b": {"\x1b[1;5A", "y", "\x1b"},
+	}
+
+	for input, expected := range tests {
+		if got := keys(input); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q gave %q, not %q", input, got, expected)
+		}
+	}
+}
+
+// TestErrors tests that mistakes are reported.
+func TestErrors(t *testing.T) {
+	tests := map[string]string{
+		"10 INK 8\n":           "INK colour must be between 0 and 7",
+		"10 PAPER \"a\"\n":     "Wrong type for argument 1",
+		"10 LOCATE 0, 1\n":     "LOCATE expects numbers of at least 1",
+		"10 PRINT AT 0 - 1, 1": "AT expects numbers of at least 0",
+		"10 CURSOR \"on\"\n":   "Wrong type for argument 1",
+	}
+
+	evaltest.Errors(t, tests, func(e *eval.Interpreter) {
+		Register(e, Options{})
+	})
+}
+
+// TestScreen tests the screen directly, with output split in awkward
+// places.
+func TestScreen(t *testing.T) {
+	s := NewScreen(2, 4)
+	for _, p := range []string{"a\x1b", "[3", "1mé"[:3], "é"[1:], "\r\nxyz", "wv"} {
+		s.Write([]byte(p))
+	}
+	if got := s.String(); got != "xyzw\nv" {
+		t.Errorf("Screen showed %q", got)
+	}
+	if got := s.At(0, 1); got != (Cell{Char: 'y', Ink: 2, Paper: -1}) {
+		t.Errorf("Unexpected cell %v", got)
+	}
+	if row, col, _ := s.Cursor(); row != 1 || col != 1 {
+		t.Errorf("Cursor at %d, %d", row, col)
+	}
+}
@@ -0,0 +1,72 @@
+// render.go contains the code which turns a stream of tokens back into
+// source-code.
+
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Render returns the source-code of the given tokens.
+//
+// The result isn't formatted in any way, tokens are separated by single
+// spaces, but tokenizing it again will produce the same tokens.
+func Render(tokens []Token) string {
+	var out strings.Builder
+
+	start := true
+	for _, tok := range tokens {
+		switch tok.Type {
+		case EOF:
+			continue
+		case NEWLINE:
+			out.WriteString("\n")
+			start = true
+			continue
+		}
+
+		if !start {
+			out.WriteString(" ")
+		}
+		start = false
+
+		switch tok.Type {
+		case STRING:
+			out.WriteString(quote(tok.Literal))
+		case LABEL:
+			out.WriteString("*" + tok.Literal)
+		default:
+			out.WriteString(tok.Literal)
+		}
+	}
+	return out.String()
+}
+
+// quote returns the given string as a string-literal, escaping anything
+// which needs it.
+func quote(s string) string {
+	var out strings.Builder
+
+	out.WriteString("\"")
+	for _, c := range s {
+		switch {
+		case c == '"':
+			out.WriteString("\\\"")
+		case c == '\\':
+			out.WriteString("\\\\")
+		case c == '\n':
+			out.WriteString("\\n")
+		case c == '\r':
+			out.WriteString("\\r")
+		case c == '\t':
+			out.WriteString("\\t")
+		case c < ' ' || c == 0x7f:
+			out.WriteString(fmt.Sprintf("\\u%04X", c))
+		default:
+			out.WriteRune(c)
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
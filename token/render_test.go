@@ -0,0 +1,32 @@
+// render_test.go: Tests of turning tokens back into source-code.
+
+package token
+
+import "testing"
+
+// TestRender tests rendering some simple token streams.
+func TestRender(t *testing.T) {
+
+	type Test struct {
+		Tokens []Token
+		Output string
+	}
+
+	tests := []Test{
+		{Tokens: []Token{{Type: LINENO, Literal: "10"}, {Type: IDENT, Literal: "PRINT"}, {Type: STRING, Literal: "Hi\n"}, {Type: NEWLINE, Literal: "\\n"}, {Type: EOF}},
+			Output: "10 PRINT \"Hi\\n\"\n"},
+		{Tokens: []Token{{Type: STRING, Literal: "Say \"Hi\"\\\r\t\x00é"}},
+			Output: "\"Say \\\"Hi\\\"\\\\\\r\\t\\u0000é\""},
+		{Tokens: []Token{{Type: LABEL, Literal: "loop"}, {Type: NEWLINE, Literal: "\\n"}, {Type: GOTO, Literal: "goto"}, {Type: IDENT, Literal: "loop"}},
+			Output: "*loop\ngoto loop"},
+		{Tokens: []Token{{Type: INT, Literal: "5"}, {Type: MINUS, Literal: "-"}, {Type: INT, Literal: "-3"}, {Type: REM, Literal: "' done"}},
+			Output: "5 - -3 ' done"},
+	}
+
+	for _, test := range tests {
+		out := Render(test.Tokens)
+		if out != test.Output {
+			t.Errorf("Rendering gave %q, expected %q", out, test.Output)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package token
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -17,6 +18,15 @@ type Token struct {
 
 	// Literal holds the literal value.
 	Literal string
+
+	// Offset is the position of the token within the program,
+	// counting characters from zero.
+	Offset int
+
+	// Line and Column are the position of the token within the
+	// program, counting from one.
+	Line   int
+	Column int
 }
 
 // pre-defined token-types
@@ -25,6 +35,8 @@ const (
 	EOF     = "EOF"     // End of file
 	NEWLINE = "NEWLINE" // Newlines are kept in our lexer-stream
 	LINENO  = "LINENO"  // Line-number of each input.
+	LABEL   = "LABEL"   // Symbolic name of a line, "loop:" or "*loop".
+	ILLEGAL = "ILLEGAL" // Malformed input, such as "1.2.3" or a stray "?".
 
 	// Types
 	IDENT   = "IDENT"   // Identifier (i.e. variable name)
@@ -34,11 +46,14 @@ const (
 
 	// Implemented keywords.
 	END    = "END"
+	ERROR  = "ERROR"
 	GOSUB  = "GOSUB"
 	GOTO   = "GOTO"
 	INPUT  = "INPUT"
 	LET    = "LET"
+	ON     = "ON"
 	REM    = "REM"
+	RESUME = "RESUME"
 	RETURN = "RETURN"
 
 	// Did I mention that for-loops work?  :D
@@ -58,12 +73,13 @@ const (
 	XOR = "XOR"
 
 	// Misc
-	DEF  = "DEF"
-	DIM  = "DIM"
-	FN   = "FN"
-	READ = "READ"
-	SWAP = "SWAP"
-	DATA = "DATA"
+	ASSERT = "ASSERT"
+	DEF    = "DEF"
+	DIM    = "DIM"
+	FN     = "FN"
+	READ   = "READ"
+	SWAP   = "SWAP"
+	DATA   = "DATA"
 
 	// Woo-operators
 	ASSIGN   = "=" // LET x = 3
@@ -93,11 +109,13 @@ const (
 // reversed keywords
 var keywords = map[string]Type{
 	"and":    AND,
+	"assert": ASSERT,
 	"data":   DATA,
 	"dim":    DIM,
 	"def":    DEF,
 	"else":   ELSE,
 	"end":    END,
+	"error":  ERROR,
 	"fn":     FN,
 	"for":    FOR,
 	"gosub":  GOSUB,
@@ -106,9 +124,11 @@ var keywords = map[string]Type{
 	"input":  INPUT,
 	"let":    LET,
 	"next":   NEXT,
+	"on":     ON,
 	"or":     OR,
 	"read":   READ,
 	"rem":    REM,
+	"resume": RESUME,
 	"return": RETURN,
 	"step":   STEP,
 	"swap":   SWAP,
@@ -128,6 +148,17 @@ func LookupIdentifier(identifier string) Type {
 	return IDENT
 }
 
+// Keywords returns the names of all the reserved keywords, in lower-case
+// and sorted alphabetically.
+func Keywords() []string {
+	var out []string
+	for kw := range keywords {
+		out = append(out, kw)
+	}
+	sort.Strings(out)
+	return out
+}
+
 // String creates a string-representation of a token
 func (t Token) String() string {
 
@@ -140,3 +171,20 @@ func (t Token) String() string {
 	}
 	return (fmt.Sprintf("Token{Type:%s Value:%s}", t.Type, lit))
 }
+
+// Problem describes what is wrong with an ILLEGAL token, for use in
+// error-messages.
+func (t Token) Problem() string {
+	if t.Type != ILLEGAL {
+		return ""
+	}
+
+	lit := t.Literal
+	switch {
+	case strings.HasPrefix(lit, "\""):
+		return "unterminated string"
+	case len(lit) > 1 && (lit[0] == '&' || lit[0] == '-' || (lit[0] >= '0' && lit[0] <= '9')):
+		return fmt.Sprintf("malformed number %s", lit)
+	}
+	return fmt.Sprintf("unexpected character %q", lit)
+}
@@ -52,3 +52,45 @@ func TestString(t *testing.T) {
 		t.Errorf("Stringification failed!")
 	}
 }
+
+// Test that all keywords are returned, in order.
+func TestKeywords(t *testing.T) {
+
+	names := Keywords()
+	if len(names) != len(keywords) {
+		t.Fatalf("expected %d keywords, got %d", len(keywords), len(names))
+	}
+	for i, name := range names {
+		if _, ok := keywords[name]; !ok {
+			t.Errorf("%s is not a keyword", name)
+		}
+		if i > 0 && names[i-1] >= name {
+			t.Errorf("keywords are not sorted: %s >= %s", names[i-1], name)
+		}
+	}
+}
+
+// Test the problems described for illegal tokens.
+func TestProblem(t *testing.T) {
+
+	tests := map[string]string{
+		"\"Steve": "unterminated string",
+		"1.2.3":   "malformed number 1.2.3",
+		"&HFG":    "malformed number &HFG",
+		"-1.2.3":  "malformed number -1.2.3",
+		"?":       "unexpected character \"?\"",
+		"&":       "unexpected character \"&\"",
+	}
+
+	for lit, expected := range tests {
+		tok := Token{Type: ILLEGAL, Literal: lit}
+		if tok.Problem() != expected {
+			t.Errorf("Problem of %s was %s, expected %s", lit, tok.Problem(), expected)
+		}
+	}
+
+	tok := Token{Type: INT, Literal: "1"}
+	if tok.Problem() != "" {
+		t.Errorf("A valid token has no problem")
+	}
+}
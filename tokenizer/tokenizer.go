@@ -8,6 +8,9 @@
 package tokenizer
 
 import (
+	"strconv"
+	"unicode"
+
 	"github.com/skx/gobasic/token"
 )
 
@@ -27,6 +30,12 @@ type Tokenizer struct {
 
 	// The previous token.
 	prevToken token.Token
+
+	// current line, counting from one.
+	line int
+
+	// position at which the current line starts.
+	lineStart int
 }
 
 // New returns a Tokenizer instance from the specified string input.
@@ -42,7 +51,7 @@ func New(input string) *Tokenizer {
 	// we also setup a fake "previous" character of a newline.  This
 	// means we don't actually need to prefix our input with such a thing.
 	//
-	l := &Tokenizer{characters: []rune(input)}
+	l := &Tokenizer{characters: []rune(input), line: 1}
 	l.prevToken.Type = token.NEWLINE
 	l.readChar()
 	return l
@@ -50,6 +59,10 @@ func New(input string) *Tokenizer {
 
 // readChar reads forward one character.
 func (l *Tokenizer) readChar() {
+	if l.ch == rune('\n') {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.characters) {
 		l.ch = rune(0)
 	} else {
@@ -65,6 +78,11 @@ func (l *Tokenizer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhitespace()
 
+	// Record where the token starts.
+	offset := l.position
+	line := l.line
+	column := l.position - l.lineStart + 1
+
 	switch l.ch {
 	case rune('='):
 		tok = newToken(token.ASSIGN, l.ch)
@@ -93,9 +111,7 @@ func (l *Tokenizer) NextToken() token.Token {
 			l.readChar()
 
 			// read the number
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
-
+			tok = l.readNumber()
 			tok.Literal = "-" + tok.Literal
 
 		} else {
@@ -108,7 +124,21 @@ func (l *Tokenizer) NextToken() token.Token {
 	case rune('%'):
 		tok = newToken(token.MOD, l.ch)
 	case rune('*'):
-		tok = newToken(token.ASTERISK, l.ch)
+		//
+		// A "*" at the start of a line, immediately followed by
+		// a name, is a label:
+		//
+		//     *loop
+		//     PRINT "Hello"
+		//     GOTO loop
+		//
+		if (l.prevToken.Type == token.NEWLINE || l.prevToken.Type == token.LINENO) && isLetter(l.peekChar()) {
+			l.readChar()
+			tok.Literal = l.readIdentifier()
+			tok.Type = token.LABEL
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case rune('('):
 		tok = newToken(token.LBRACKET, l.ch)
 	case rune(')'):
@@ -137,9 +167,30 @@ func (l *Tokenizer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
+	case rune('&'):
+		//
+		// "&HFF", "&O17", and "&B1010" are numbers in
+		// hexadecimal, octal, and binary respectively.
+		//
+		if base := basePrefix(l.peekChar()); base != 0 {
+			l.readChar()
+			tok = l.readBased(base, "&")
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case rune('\''):
+		// A comment, which runs to the end of the line.
+		tok.Type = token.REM
+		tok.Literal = string(l.ch)
+		tok.Literal += l.readComment()
 	case rune('"'):
+		var ok bool
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal, ok = l.readString()
+		if !ok {
+			tok.Type = token.ILLEGAL
+			tok.Literal = "\"" + tok.Literal
+		}
 	case rune('\n'):
 		tok.Type = token.NEWLINE
 		tok.Literal = "\\n"
@@ -148,13 +199,23 @@ func (l *Tokenizer) NextToken() token.Token {
 		tok.Type = token.EOF
 	default:
 		if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
-		} else {
+			tok = l.readNumber()
+		} else if isLetter(l.ch) || l.ch == rune('_') {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdentifier(tok.Literal)
+
+			// The text of a comment is never tokenized, so that
+			// it may contain anything.
+			if tok.Type == token.REM {
+				tok.Literal += l.readComment()
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
+	tok.Offset = offset
+	tok.Line = line
+	tok.Column = column
 	l.readChar()
 
 	//
@@ -174,6 +235,15 @@ func (l *Tokenizer) NextToken() token.Token {
 	return tok
 }
 
+// Offset returns the offset, in characters, of the first character which
+// follows the most recently returned token.
+//
+// This allows callers to find the source of each token, for example to
+// preserve the text of comments exactly.
+func (l *Tokenizer) Offset() int {
+	return l.position
+}
+
 // newToken is a simple helper for returning a new token.
 func newToken(tokenType token.Type, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
@@ -193,6 +263,17 @@ func (l *Tokenizer) readIdentifier() string {
 	return id
 }
 
+// readComment reads the remainder of the line, which is a comment, not
+// including the newline.
+func (l *Tokenizer) readComment() string {
+	out := ""
+	for l.readPosition < len(l.characters) && l.peekChar() != rune('\n') {
+		l.readChar()
+		out += string(l.ch)
+	}
+	return out
+}
+
 // skip white space
 func (l *Tokenizer) skipWhitespace() {
 	for isWhitespace(l.ch) {
@@ -200,57 +281,192 @@ func (l *Tokenizer) skipWhitespace() {
 	}
 }
 
-// read a number, note that this only handles integers.
-func (l *Tokenizer) readNumber() string {
-	str := ""
+// readNumber reads a number, which might be in scientific notation
+// ("1.5e-3"), or hexadecimal, octal, or binary ("0x1F", "0o17", "0b101").
+//
+// Numbers in bases other than ten are converted to decimal, so that the
+// rest of the interpreter need not care how they were written.  Malformed
+// numbers, such as "1.2.3", result in an ILLEGAL token.
+func (l *Tokenizer) readNumber() token.Token {
+
+	//
+	// The prefix must be followed by a digit, otherwise "0OR 1" and
+	// "0XOR 1" would be broken.
+	//
+	if l.ch == rune('0') {
+		if base := basePrefix(l.peekChar()); base != 0 && isBaseDigit(l.peekCharAt(1), base) {
+			l.readChar()
+			return l.readBased(base, "0")
+		}
+	}
 
-	for isDigit(l.peekChar()) || l.peekChar() == rune('.') {
+	str := l.readDigits()
+
+	if l.peekChar() == rune('.') {
+		l.readChar()
 		str += string(l.ch)
+		if isDigit(l.peekChar()) {
+			l.readChar()
+			str += l.readDigits()
+		}
+	}
+
+	//
+	// An exponent is only present if the "e" is followed by digits,
+	// otherwise "20ELSE" would be malformed.
+	//
+	if p := l.peekChar(); p == rune('e') || p == rune('E') {
+		next := l.peekCharAt(1)
+		if (next == rune('+') || next == rune('-')) && isDigit(l.peekCharAt(2)) {
+			l.readChar()
+			str += string(l.ch)
+			l.readChar()
+			str += string(l.ch)
+			l.readChar()
+			str += l.readDigits()
+		} else if isDigit(next) {
+			l.readChar()
+			str += string(l.ch)
+			l.readChar()
+			str += l.readDigits()
+		}
+	}
+
+	//
+	// A second decimal point is an error; consume the rest of the
+	// number so that we report all of it.
+	//
+	if l.peekChar() == rune('.') {
+		for isDigit(l.peekChar()) || l.peekChar() == rune('.') {
+			l.readChar()
+			str += string(l.ch)
+		}
+		return token.Token{Type: token.ILLEGAL, Literal: str}
+	}
+
+	if _, err := strconv.ParseFloat(str, 64); err != nil {
+		return token.Token{Type: token.ILLEGAL, Literal: str}
+	}
+	return token.Token{Type: token.INT, Literal: str}
+}
+
+// readDigits reads a run of decimal digits, starting with the current
+// character.
+func (l *Tokenizer) readDigits() string {
+	str := string(l.ch)
+	for isDigit(l.peekChar()) {
 		l.readChar()
+		str += string(l.ch)
 	}
-	str += string(l.ch)
 	return str
 }
 
+// readBased reads the digits of a number in the given base, the current
+// character being the letter which specified the base.
+func (l *Tokenizer) readBased(base int, prefix string) token.Token {
+	prefix += string(l.ch)
+
+	digits := ""
+	for isLetter(l.peekChar()) || isDigit(l.peekChar()) {
+		l.readChar()
+		digits += string(l.ch)
+	}
+
+	n, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return token.Token{Type: token.ILLEGAL, Literal: prefix + digits}
+	}
+	return token.Token{Type: token.INT, Literal: strconv.FormatUint(n, 10)}
+}
+
+// basePrefix returns the base which the given character specifies,
+// after a "0" or "&", or zero if it doesn't specify one.
+func basePrefix(ch rune) int {
+	switch ch {
+	case rune('x'), rune('X'), rune('h'), rune('H'):
+		return 16
+	case rune('o'), rune('O'):
+		return 8
+	case rune('b'), rune('B'):
+		return 2
+	}
+	return 0
+}
+
 // read a string, handling "\t", "\n", etc.
-func (l *Tokenizer) readString() string {
+//
+// The string ends at the closing quote, which is the current character
+// on return.  If we reach the end of the line, or of the input, first
+// then the string is unterminated, and we return false, leaving the
+// newline to be read next.
+func (l *Tokenizer) readString() (string, bool) {
 	out := ""
 
 	for {
-		l.readChar()
-		if l.ch == '"' {
-			break
+		if l.readPosition >= len(l.characters) || l.peekChar() == '\n' {
+			return out, false
 		}
+		l.readChar()
+
+		// A NUL character terminates the string too.
 		if l.ch == rune(0) {
-			break
+			return out, true
+		}
+		if l.ch == '"' {
+			// A doubled quote is a literal quote.
+			if l.peekChar() != '"' {
+				return out, true
+			}
+			l.readChar()
 		}
 
 		//
 		// Handle \n, \r, \t, \", etc.
 		//
-		if l.ch == '\\' {
+		// The character the escape stands for is kept apart from
+		// l.ch, so that "\n" doesn't count as the end of a line.
+		//
+		c := l.ch
+		if l.ch == '\\' && l.readPosition < len(l.characters) && l.peekChar() != '\n' {
 			l.readChar()
+			c = l.ch
 
 			if l.ch == rune('n') {
-				l.ch = '\n'
+				c = '\n'
 			}
 			if l.ch == rune('r') {
-				l.ch = '\r'
+				c = '\r'
 			}
 			if l.ch == rune('t') {
-				l.ch = '\t'
-			}
-			if l.ch == rune('"') {
-				l.ch = '"'
+				c = '\t'
 			}
-			if l.ch == rune('\\') {
-				l.ch = '\\'
+			if l.ch == rune('u') {
+				c = l.readUnicode()
 			}
 		}
-		out = out + string(l.ch)
+		out = out + string(c)
 	}
+}
 
-	return out
+// readUnicode handles "\uXXXX", the current character being the "u",
+// returning the character which was specified.
+//
+// If the escape isn't followed by four hexadecimal digits it is left
+// alone, and we return the "u", as we do for other unknown escapes.
+func (l *Tokenizer) readUnicode() rune {
+	digits := ""
+	for i := 0; i < 4; i++ {
+		digits += string(l.peekCharAt(i))
+	}
+
+	n, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return l.ch
+	}
+	for i := 0; i < 4; i++ {
+		l.readChar()
+	}
+	return rune(n)
 }
 
 // peek character looks at the next character which is available for consumption
@@ -261,6 +477,15 @@ func (l *Tokenizer) peekChar() rune {
 	return l.characters[l.readPosition]
 }
 
+// peekCharAt looks at the character the given distance beyond the next
+// character which is available for consumption.
+func (l *Tokenizer) peekCharAt(n int) rune {
+	if l.readPosition+n >= len(l.characters) {
+		return rune(0)
+	}
+	return l.characters[l.readPosition+n]
+}
+
 // determinate ch is identifier or not
 func isIdentifier(ch rune) bool {
 	return !isWhitespace(ch) && !isBrace(ch) && !isOperator(ch) && !isComparison(ch) && !isCompound(ch) && !isBrace(ch) && !isParen(ch) && !isBracket(ch) && !isEmpty(ch) && (ch != rune('\n'))
@@ -307,7 +532,18 @@ func isEmpty(ch rune) bool {
 	return rune(0) == ch
 }
 
+// is letter
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch)
+}
+
 // is Digit
 func isDigit(ch rune) bool {
 	return rune('0') <= ch && ch <= rune('9')
 }
+
+// is a digit in the given base
+func isBaseDigit(ch rune, base int) bool {
+	_, err := strconv.ParseUint(string(ch), base, 8)
+	return err == nil
+}
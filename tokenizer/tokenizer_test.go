@@ -1,6 +1,8 @@
 package tokenizer
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/skx/gobasic/token"
@@ -100,6 +102,39 @@ func TestLineNo(t *testing.T) {
 	}
 }
 
+// TestLabel tests that "*name" at the start of a line is a label.
+func TestLabel(t *testing.T) {
+	input := `*loop
+10 *end
+PRINT 3 * 4`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.LABEL, "loop"},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "10"},
+		{token.LABEL, "end"},
+		{token.NEWLINE, "\\n"},
+		{token.IDENT, "PRINT"},
+		{token.INT, "3"},
+		{token.ASTERISK, "*"},
+		{token.INT, "4"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
 // TestStringParse tests we can cope with control-characters inside strings.
 func TestStringParse(t *testing.T) {
 	input := `10 LET a="\n\r\t\\\""
@@ -116,8 +151,7 @@ func TestStringParse(t *testing.T) {
 		{token.STRING, "\n\r\t\\\""},
 		{token.NEWLINE, "\\n"},
 		{token.LINENO, "20"},
-		{token.REM, "REM"},
-		{token.IDENT, "OK"},
+		{token.REM, "REM OK"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -195,19 +229,23 @@ func TestComparisons(t *testing.T) {
 
 // TestNumber tests that positive and negative numbers are OK.
 func TestNumber(t *testing.T) {
-	input := `10 REM -4.3
-20 REM 5 - 3`
+	input := `10 LET a = -4.3
+20 LET b = 5 - 3`
 
 	tests := []struct {
 		expectedType    token.Type
 		expectedLiteral string
 	}{
 		{token.LINENO, "10"},
-		{token.REM, "REM"},
+		{token.LET, "LET"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
 		{token.INT, "-4.3"},
 		{token.NEWLINE, "\\n"},
 		{token.LINENO, "20"},
-		{token.REM, "REM"},
+		{token.LET, "LET"},
+		{token.IDENT, "b"},
+		{token.ASSIGN, "="},
 		{token.INT, "5"},
 		{token.MINUS, "-"},
 		{token.INT, "3"},
@@ -373,3 +411,308 @@ func TestIssue120(t *testing.T) {
 		}
 	}
 }
+
+// TestOffset ensures we can find the end of each token.
+func TestOffset(t *testing.T) {
+	input := `10 PRINT "Hi" : REM x`
+
+	tests := []int{2, 8, 13, 15, 21}
+
+	l := New(input)
+	for i, expected := range tests {
+		l.NextToken()
+		if l.Offset() != expected {
+			t.Fatalf("tests[%d] - offset wrong, expected=%d, got=%d", i, expected, l.Offset())
+		}
+	}
+}
+
+// TestNumberLiterals tests scientific notation, and numbers in other
+// bases.
+func TestNumberLiterals(t *testing.T) {
+	input := `10 LET a = 1e-3 + 2.5E+2 + 3e4 + 1. + &HFF + &hff + 0x1F + &O17 + 0o17 + &B1010 + 0b11 - -0x10
+20 IF a = 0OR b = 0XOR 1 THEN 30ELSE 40`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.LINENO, "10"},
+		{token.LET, "LET"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "1e-3"},
+		{token.PLUS, "+"},
+		{token.INT, "2.5E+2"},
+		{token.PLUS, "+"},
+		{token.INT, "3e4"},
+		{token.PLUS, "+"},
+		{token.INT, "1."},
+		{token.PLUS, "+"},
+		{token.INT, "255"},
+		{token.PLUS, "+"},
+		{token.INT, "255"},
+		{token.PLUS, "+"},
+		{token.INT, "31"},
+		{token.PLUS, "+"},
+		{token.INT, "15"},
+		{token.PLUS, "+"},
+		{token.INT, "15"},
+		{token.PLUS, "+"},
+		{token.INT, "10"},
+		{token.PLUS, "+"},
+		{token.INT, "3"},
+		{token.MINUS, "-"},
+		{token.INT, "-16"},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "20"},
+		{token.IF, "IF"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "0"},
+		{token.OR, "OR"},
+		{token.IDENT, "b"},
+		{token.ASSIGN, "="},
+		{token.INT, "0"},
+		{token.XOR, "XOR"},
+		{token.INT, "1"},
+		{token.THEN, "THEN"},
+		{token.INT, "30"},
+		{token.ELSE, "ELSE"},
+		{token.INT, "40"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, got=%v", i, tt.expectedType, tok)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// TestMalformedNumbers ensures that broken numbers are reported.
+func TestMalformedNumbers(t *testing.T) {
+
+	tests := []string{"1.2.3", "&HFG", "&B102", "&O8", "&H", "1e999", "-1.2.3"}
+
+	for _, input := range tests {
+		l := New("a = " + input)
+		l.NextToken()
+		l.NextToken()
+
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("%s - expected ILLEGAL, got %v", input, tok)
+		}
+		if tok.Literal != input {
+			t.Errorf("%s - literal wrong, got %q", input, tok.Literal)
+		}
+		if l.NextToken().Type != token.EOF {
+			t.Errorf("%s - expected the whole number to be consumed", input)
+		}
+	}
+}
+
+// TestStringEscapes tests doubled quotes, and unicode escapes.
+func TestStringEscapes(t *testing.T) {
+
+	tests := map[string]string{
+		`"Say ""Hi"""`:    `Say "Hi"`,
+		`""""`:            `"`,
+		`"\u00e9t\u00e9"`: "\u00e9t\u00e9",
+		`"\u263A!"`:       "\u263a!",
+		`"\u12"`:          "u12",
+		`"\uzzzz"`:        "uzzzz",
+	}
+
+	for input, expected := range tests {
+		l := New(input)
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Errorf("%s - expected STRING, got %v", input, tok)
+		}
+		if tok.Literal != expected {
+			t.Errorf("%s - literal wrong, expected %q got %q", input, expected, tok.Literal)
+		}
+		if l.NextToken().Type != token.EOF {
+			t.Errorf("%s - expected the whole string to be consumed", input)
+		}
+	}
+}
+
+// TestPositions ensures each token records where it was found.
+func TestPositions(t *testing.T) {
+	input := "10 PRINT \"Hi\"\n  20 LET a=3"
+
+	tests := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{3, 1, 4},
+		{9, 1, 10},
+		{13, 1, 14},
+		{16, 2, 3},
+		{19, 2, 6},
+		{23, 2, 10},
+		{24, 2, 11},
+		{25, 2, 12},
+		{26, 2, 13},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Offset != tt.offset || tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - position of %v wrong, expected %d/%d:%d, got %d/%d:%d", i, tok, tt.offset, tt.line, tt.column, tok.Offset, tok.Line, tok.Column)
+		}
+	}
+
+	// An escaped newline, within a string, doesn't end the line.
+	l = New("10 PRINT \"a\\n\" : LET a=3")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Line != 1 {
+			t.Fatalf("position of %v wrong, expected line 1, got %d", tok, tok.Line)
+		}
+	}
+}
+
+// TestIllegal ensures that stray characters, and unterminated strings,
+// result in ILLEGAL tokens.  A string ends with its line, so those which
+// follow are unaffected.
+func TestIllegal(t *testing.T) {
+	input := `10 PRINT ? # {
+20 PRINT "Steve
+30 PRINT "Kemp", "\`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.LINENO, "10"},
+		{token.IDENT, "PRINT"},
+		{token.ILLEGAL, "?"},
+		{token.ILLEGAL, "#"},
+		{token.ILLEGAL, "{"},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "20"},
+		{token.IDENT, "PRINT"},
+		{token.ILLEGAL, "\"Steve"},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "30"},
+		{token.IDENT, "PRINT"},
+		{token.STRING, "Kemp"},
+		{token.COMMA, ","},
+		{token.ILLEGAL, "\"\\"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, got=%v", i, tt.expectedType, tok)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Type == token.ILLEGAL && tok.Literal == "\"Steve" && (tok.Line != 2 || tok.Column != 10) {
+			t.Fatalf("tests[%d] - position wrong, expected 2:10, got %d:%d", i, tok.Line, tok.Column)
+		}
+	}
+}
+
+// TestComments ensures that the text of comments is never tokenized.
+func TestComments(t *testing.T) {
+	input := `10 REM "Unterminated, & ? 1.2.3
+20 PRINT "Hi" ' Isn't this "fun"?
+30 rem:REM
+40 REMARK`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.LINENO, "10"},
+		{token.REM, `REM "Unterminated, & ? 1.2.3`},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "20"},
+		{token.IDENT, "PRINT"},
+		{token.STRING, "Hi"},
+		{token.REM, `' Isn't this "fun"?`},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "30"},
+		{token.REM, "rem:REM"},
+		{token.NEWLINE, "\\n"},
+		{token.LINENO, "40"},
+		{token.IDENT, "REMARK"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, got=%v", i, tt.expectedType, tok)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// tokens returns all the tokens of the given input.
+func tokens(input string) []token.Token {
+	var out []token.Token
+
+	l := New(input)
+	for {
+		tok := l.NextToken()
+		out = append(out, tok)
+		if tok.Type == token.EOF {
+			return out
+		}
+	}
+}
+
+// TestRoundTrip ensures that rendering tokens, and tokenizing the result,
+// gives the same tokens for each of our examples.
+func TestRoundTrip(t *testing.T) {
+
+	inputs := []string{
+		"10 LET a = 3-3\n20 LET b = a-3 : LET c = 5 - -3\n30 PRINT \"a\\\\b\\\"c\\u00e9\" ; 1e-3, &HFF\n",
+		"10 IF a<>b THEN 20 ELSE 30\n20 x = ( 1 ) * y[ 2 ]\n*loop\nstart: GOTO loop ' 1.2.3 \"\n",
+		"10 PRINT \"unterminated\nand ? 1.2.3",
+	}
+
+	files, err := filepath.Glob("../examples/*.bas")
+	if err != nil {
+		t.Fatalf("failed to find examples: %s", err.Error())
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", file, err.Error())
+		}
+		inputs = append(inputs, string(data))
+	}
+
+	for i, input := range inputs {
+		before := tokens(input)
+		after := tokens(token.Render(before))
+
+		if len(before) != len(after) {
+			t.Errorf("inputs[%d] - had %d tokens, and %d after rendering", i, len(before), len(after))
+			continue
+		}
+		for j := range before {
+			if before[j].Type != after[j].Type || before[j].Literal != after[j].Literal {
+				t.Errorf("inputs[%d] - token %d was %v, and %v after rendering", i, j, before[j], after[j])
+				break
+			}
+		}
+	}
+}
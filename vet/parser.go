@@ -0,0 +1,710 @@
+// parser.go contains the code which walks over the statements of each
+// line, recording the events they generate and reporting problems with
+// them.
+
+package vet
+
+import (
+	"github.com/skx/gobasic/token"
+)
+
+// parser holds the state for examining a single line.
+type parser struct {
+
+	// c is the checker we're working for.
+	c *Checker
+
+	// line is the line we're examining.
+	line *line
+
+	// offset is our position within the tokens of the line.
+	offset int
+
+	// conditional is true once we've passed an IF, because the
+	// remainder of the line might not be executed.
+	conditional bool
+
+	// target is true if the next token follows THEN or ELSE, and
+	// so may be a bare line-number or label.
+	target bool
+
+	// locals holds the arguments of a function-definition, whose
+	// body we're examining.
+	locals map[string]bool
+
+	// noReads is true if reads of variables shouldn't be recorded.
+	noReads bool
+
+	// last holds the name of the built-in function which was called
+	// most recently, if it was the last thing in an expression.
+	last token.Token
+}
+
+// peek returns the current token, or a token of type EOF if we've
+// reached the end of the line.
+func (p *parser) peek() token.Token {
+	if p.offset < len(p.line.tokens) {
+		return p.line.tokens[p.offset]
+	}
+	end := token.Token{Type: token.EOF}
+	if len(p.line.tokens) > 0 {
+		last := p.line.tokens[len(p.line.tokens)-1]
+		end.Line = last.Line
+		end.Column = last.Column + len([]rune(last.Literal))
+	}
+	return end
+}
+
+// atEnd returns true if we've reached the end of a statement.
+func (p *parser) atEnd() bool {
+	tok := p.peek()
+	return tok.Type == token.EOF || tok.Type == token.COLON || tok.Type == token.ELSE
+}
+
+// emit records an event.
+func (p *parser) emit(k kind, name string, target int, tok token.Token) {
+	p.line.events = append(p.line.events, event{kind: k, name: name, target: target, tok: tok})
+}
+
+// assign records the assignment of a variable.
+func (p *parser) assign(tok token.Token) {
+	p.c.assigned[tok.Literal] = true
+	p.emit(evAssign, tok.Literal, 0, tok)
+}
+
+// stop records that control doesn't continue past this point, unless
+// we're within the body of an IF.
+func (p *parser) stop(tok token.Token) {
+	if !p.conditional {
+		p.emit(evStop, "", 0, tok)
+	}
+}
+
+// parse examines each statement within the line.
+func (p *parser) parse() {
+
+	for p.offset < len(p.line.tokens) {
+		tok := p.peek()
+
+		switch tok.Type {
+		case token.COLON:
+			p.offset++
+			continue
+		case token.ELSE:
+			p.offset++
+			p.conditional = true
+			p.target = true
+			continue
+		}
+
+		p.last = token.Token{}
+		p.statement()
+
+		// Following THEN we have another statement.
+		if p.target {
+			continue
+		}
+
+		//
+		// If there is anything left over then the statement was
+		// malformed; the most likely cause is a built-in which was
+		// given too many arguments.
+		//
+		if !p.atEnd() {
+			extra := p.peek()
+			if extra.Type == token.COMMA && p.last.Type == token.BUILTIN {
+				p.c.report(extra, "too many arguments to %s, which expects %d", p.last.Literal, p.c.builtins[p.last.Literal])
+			}
+			for !p.atEnd() {
+				p.offset++
+			}
+		}
+	}
+}
+
+// statement examines a single statement.
+func (p *parser) statement() {
+	tok := p.peek()
+
+	//
+	// A line-number, or label, following THEN or ELSE is an
+	// implicit GOTO.
+	//
+	if p.target {
+		p.target = false
+		if tok.Type == token.INT || (tok.Type == token.IDENT && p.isLabel(tok) && p.endsAt(p.offset+1)) {
+			p.line.executable = true
+			p.jump(tok, evJump)
+			p.offset++
+			p.stop(tok)
+			return
+		}
+	}
+
+	switch tok.Type {
+	case token.LABEL:
+		p.offset++
+		return
+	case token.DATA:
+		p.c.data = append(p.c.data, tok)
+		p.offset = len(p.line.tokens)
+		return
+	case token.DEF:
+		p.def()
+		return
+	}
+
+	p.line.executable = true
+	p.offset++
+
+	switch tok.Type {
+	case token.ASSERT:
+		p.expr()
+		if p.peek().Type == token.COMMA {
+			p.offset++
+			p.expr()
+		}
+	case token.DIM:
+		p.dim()
+	case token.END:
+		p.stop(tok)
+	case token.ERROR:
+		p.expr()
+	case token.FOR:
+		p.forLoop()
+	case token.GOSUB:
+		p.c.gosub = true
+		p.jumpTarget(tok, evGosub)
+	case token.GOTO:
+		p.jumpTarget(tok, evJump)
+		p.stop(tok)
+	case token.IF:
+		p.expr()
+		if p.peek().Type == token.THEN {
+			p.offset++
+		}
+		p.conditional = true
+		p.target = true
+	case token.INPUT:
+		p.input()
+	case token.LET:
+		p.assignment()
+	case token.NEXT:
+		p.next()
+	case token.ON:
+		p.on()
+	case token.READ:
+		p.c.read = true
+		p.lvalues(false)
+	case token.RESUME:
+		p.resume(tok)
+	case token.RETURN:
+		p.emit(evReturn, "", 0, tok)
+		p.stop(tok)
+	case token.SWAP:
+		p.lvalues(true)
+	case token.BUILTIN:
+		p.offset--
+		p.builtin()
+	case token.IDENT:
+		p.offset--
+		p.assignment()
+	}
+}
+
+// isLabel returns true if the token is the name of a label.
+func (p *parser) isLabel(tok token.Token) bool {
+	_, ok := p.c.labels[tok.Literal]
+	return ok
+}
+
+// endsAt returns true if the statement ends at the given offset.
+func (p *parser) endsAt(offset int) bool {
+	if offset >= len(p.line.tokens) {
+		return true
+	}
+	t := p.line.tokens[offset].Type
+	return t == token.COLON || t == token.ELSE
+}
+
+// jump records a jump to the given line-number, or label, reporting an
+// error if there is no such line.
+func (p *parser) jump(tok token.Token, k kind) {
+	var target int
+	var ok bool
+
+	if tok.Type == token.INT {
+		target, ok = p.c.numbers[tok.Literal]
+		if !ok {
+			p.c.report(tok, "line %s does not exist", tok.Literal)
+			return
+		}
+	} else {
+		target, ok = p.c.labels[tok.Literal]
+		if !ok {
+			p.c.report(tok, "label %s does not exist", tok.Literal)
+			return
+		}
+	}
+	p.emit(k, "", target, tok)
+}
+
+// jumpTarget handles the target of GOTO or GOSUB, which is either a
+// line-number, a label, or an expression we can't follow.
+func (p *parser) jumpTarget(keyword token.Token, k kind) {
+	tok := p.peek()
+
+	if (tok.Type == token.INT || tok.Type == token.IDENT) && p.endsAt(p.offset+1) {
+		p.offset++
+
+		if tok.Type == token.INT || p.isLabel(tok) {
+			p.jump(tok, k)
+			return
+		}
+
+		// A name which isn't a label is either a variable, or a
+		// mistyped label, which we can only tell once we've seen
+		// the whole program.
+		p.c.names = append(p.c.names, tok)
+		return
+	}
+
+	if p.atEnd() {
+		p.c.report(keyword, "%s without a target", keyword.Literal)
+		return
+	}
+
+	// A computed target.
+	p.c.computed = true
+	p.expr()
+}
+
+// on handles "ON ERROR GOTO", and "ON expr GOTO|GOSUB".
+func (p *parser) on() {
+
+	if p.peek().Type == token.ERROR {
+		p.offset++
+		if p.peek().Type != token.GOTO {
+			p.c.report(p.peek(), "expected GOTO after ON ERROR")
+			return
+		}
+		p.offset++
+		tok := p.peek()
+		if tok.Type == token.INT && tok.Literal == "0" {
+			p.offset++
+			return
+		}
+		if tok.Type == token.INT || tok.Type == token.IDENT {
+			p.jump(tok, evOnError)
+			p.offset++
+			return
+		}
+		p.c.report(tok, "ON ERROR GOTO should be followed by a line-number or label")
+		return
+	}
+
+	p.expr()
+
+	kw := p.peek()
+	k := evJump
+	switch kw.Type {
+	case token.GOTO:
+	case token.GOSUB:
+		p.c.gosub = true
+		k = evGosub
+	default:
+		p.c.report(kw, "expected GOTO or GOSUB after ON")
+		return
+	}
+	p.offset++
+
+	for !p.atEnd() {
+		tok := p.peek()
+		switch tok.Type {
+		case token.COMMA:
+			p.offset++
+		case token.INT, token.IDENT:
+			p.jump(tok, k)
+			p.offset++
+		default:
+			p.c.report(tok, "ON .. %s should be followed by a list of line-numbers or labels", kw.Literal)
+			return
+		}
+	}
+}
+
+// resume handles "RESUME", "RESUME NEXT", and "RESUME target".
+func (p *parser) resume(keyword token.Token) {
+	tok := p.peek()
+
+	switch {
+	case tok.Type == token.NEXT:
+		p.offset++
+	case tok.Type == token.INT && tok.Literal == "0":
+		p.offset++
+	case tok.Type == token.INT || tok.Type == token.IDENT:
+		p.jump(tok, evJump)
+		p.offset++
+	}
+	p.stop(keyword)
+}
+
+// next handles the end of a FOR loop.
+func (p *parser) next() {
+	tok := p.peek()
+	if tok.Type != token.IDENT {
+		p.c.report(tok, "expected a variable after NEXT")
+		return
+	}
+	p.offset++
+
+	if !p.c.forVars[tok.Literal] {
+		p.c.report(tok, "NEXT %s without FOR %s", tok.Literal, tok.Literal)
+	}
+}
+
+// forLoop handles the start of a FOR loop.
+func (p *parser) forLoop() {
+	tok := p.peek()
+	if tok.Type != token.IDENT {
+		p.c.report(tok, "expected a variable after FOR")
+		return
+	}
+	p.offset++
+	p.c.forVars[tok.Literal] = true
+
+	if p.peek().Type != token.ASSIGN {
+		p.c.report(p.peek(), "expected = after FOR %s", tok.Literal)
+		return
+	}
+	p.offset++
+	p.expr()
+	p.assign(tok)
+
+	if p.peek().Type != token.TO {
+		p.c.report(p.peek(), "expected TO in FOR %s", tok.Literal)
+		return
+	}
+	p.offset++
+	p.expr()
+
+	if p.peek().Type == token.STEP {
+		p.offset++
+		p.expr()
+	}
+}
+
+// input handles reading a value from the user.
+func (p *parser) input() {
+	if p.peek().Type == token.STRING {
+		p.offset++
+	}
+	if p.peek().Type == token.COMMA || p.peek().Type == token.SEMICOLON {
+		p.offset++
+	}
+	p.lvalues(false)
+}
+
+// def handles the definition of a user-defined function.
+//
+// We don't record reads within the body, because they're evaluated when
+// the function is called.
+func (p *parser) def() {
+	p.offset = len(p.line.tokens)
+
+	toks := p.line.tokens
+	i := 0
+	for i < len(toks) && toks[i].Type != token.DEF {
+		i++
+	}
+	i += 3
+
+	locals := make(map[string]bool)
+	for i < len(toks) && toks[i].Type != token.ASSIGN {
+		if toks[i].Type == token.IDENT {
+			locals[toks[i].Literal] = true
+		}
+		i++
+	}
+	if i >= len(toks) {
+		return
+	}
+
+	body := &parser{c: p.c, line: &line{tokens: toks[i+1:]}, locals: locals, noReads: true}
+	body.expr()
+}
+
+// dim handles the creation of arrays.
+func (p *parser) dim() {
+	for !p.atEnd() {
+		tok := p.peek()
+		switch tok.Type {
+		case token.COMMA:
+			p.offset++
+		case token.IDENT:
+			p.offset++
+			if p.peek().Type == token.LBRACKET {
+				p.operand()
+			}
+			p.assign(tok)
+		default:
+			return
+		}
+	}
+}
+
+// assignment handles "LET x = ..", or the same without the LET.
+func (p *parser) assignment() {
+	tok := p.peek()
+	if tok.Type != token.IDENT {
+		return
+	}
+	p.offset++
+	p.index()
+
+	if p.peek().Type != token.ASSIGN {
+		return
+	}
+	p.offset++
+	p.expr()
+	p.assign(tok)
+}
+
+// lvalues handles a comma-separated list of variables, which are
+// assigned, and optionally read too, as used by READ and SWAP.
+func (p *parser) lvalues(read bool) {
+	for !p.atEnd() {
+		tok := p.peek()
+		switch tok.Type {
+		case token.COMMA:
+			p.offset++
+		case token.IDENT:
+			p.offset++
+			p.index()
+			if read {
+				p.read(tok)
+			}
+			p.assign(tok)
+		default:
+			return
+		}
+	}
+}
+
+// index handles an optional array-index, "[1,2]".
+func (p *parser) index() {
+	if p.peek().Type != token.LINDEX {
+		return
+	}
+	p.offset++
+	for !p.atEnd() {
+		switch p.peek().Type {
+		case token.RINDEX:
+			p.offset++
+			return
+		case token.COMMA:
+			p.offset++
+		default:
+			if !p.expr() {
+				return
+			}
+		}
+	}
+}
+
+// read records the reading of a variable.
+func (p *parser) read(tok token.Token) {
+	if p.noReads || p.locals[tok.Literal] {
+		return
+	}
+	p.emit(evRead, tok.Literal, 0, tok)
+}
+
+// expr handles an expression, returning false if there wasn't one.
+func (p *parser) expr() bool {
+	if !p.operand() {
+		return false
+	}
+
+	for {
+		switch p.peek().Type {
+		case token.PLUS, token.MINUS, token.ASTERISK, token.SLASH, token.MOD, token.POW,
+			token.ASSIGN, token.NOTEQUALS, token.LT, token.LTEQUALS, token.GT, token.GTEQUALS,
+			token.AND, token.OR, token.XOR:
+			p.offset++
+			if !p.operand() {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+}
+
+// startsOperand returns true if the token may begin an operand.
+func startsOperand(t token.Type) bool {
+	switch t {
+	case token.LBRACKET, token.INT, token.STRING, token.FN, token.BUILTIN, token.IDENT, token.MINUS:
+		return true
+	}
+	return false
+}
+
+// operand handles a single value within an expression, returning false
+// if there wasn't one.
+func (p *parser) operand() bool {
+	tok := p.peek()
+	p.last = token.Token{}
+
+	switch tok.Type {
+	case token.LBRACKET:
+		p.offset++
+		p.expr()
+		if p.peek().Type == token.RBRACKET {
+			p.offset++
+		}
+	case token.INT, token.STRING:
+		p.offset++
+	case token.MINUS:
+		p.offset++
+		return p.operand()
+	case token.FN:
+		p.offset++
+		name := p.peek()
+		if name.Type != token.IDENT {
+			p.c.report(name, "expected a function-name after FN")
+			return true
+		}
+		p.offset++
+		if !p.c.fns[name.Literal] {
+			p.c.report(name, "FN %s is not defined", name.Literal)
+		}
+		if p.peek().Type != token.LBRACKET {
+			return true
+		}
+		p.offset++
+		for !p.atEnd() {
+			switch p.peek().Type {
+			case token.RBRACKET:
+				p.offset++
+				return true
+			case token.COMMA:
+				p.offset++
+			default:
+				if !p.expr() {
+					return true
+				}
+			}
+		}
+	case token.BUILTIN:
+		p.builtin()
+	case token.IDENT:
+		p.offset++
+		p.index()
+		p.read(tok)
+	default:
+		return false
+	}
+	return true
+}
+
+// builtin handles a call to a built-in function, ensuring it receives
+// the correct number of arguments.
+func (p *parser) builtin() {
+	tok := p.peek()
+	p.offset++
+
+	n := p.c.builtins[tok.Literal]
+	args := 0
+
+	// Arguments may be given in brackets, as "LEFT$(a$, 2)".
+	if n > 0 && p.bracketed() {
+		p.arguments(tok, n)
+		p.last = tok
+		return
+	}
+
+	for n < 0 || args < n {
+		next := p.peek()
+		if next.Type == token.COMMA || next.Type == token.SEMICOLON {
+			p.offset++
+			continue
+		}
+		if p.atEnd() || !startsOperand(next.Type) {
+			if n >= 0 {
+				p.c.report(tok, "%s expects %d argument%s, but was given %d", tok.Literal, n, plural(n), args)
+			}
+			break
+		}
+		p.expr()
+		args++
+	}
+
+	p.last = tok
+}
+
+// bracketed returns true if the next token opens brackets which hold a
+// list of arguments, rather than a single expression.
+func (p *parser) bracketed() bool {
+	if p.peek().Type != token.LBRACKET {
+		return false
+	}
+	depth := 0
+	for i := p.offset; i < len(p.line.tokens); i++ {
+		switch p.line.tokens[i].Type {
+		case token.LBRACKET:
+			depth++
+		case token.RBRACKET:
+			depth--
+			if depth == 0 {
+				return false
+			}
+		case token.COMMA:
+			if depth == 1 {
+				return true
+			}
+		case token.COLON, token.ELSE:
+			return false
+		}
+	}
+	return false
+}
+
+// arguments handles the list of arguments given to a built-in within
+// brackets, ensuring there are the correct number of them.
+func (p *parser) arguments(tok token.Token, n int) {
+	p.offset++
+	args := 0
+
+	for !p.atEnd() {
+		next := p.peek()
+		if next.Type == token.RBRACKET {
+			p.offset++
+			break
+		}
+		if next.Type == token.COMMA {
+			if args == n {
+				p.c.report(next, "too many arguments to %s, which expects %d", tok.Literal, n)
+			}
+			p.offset++
+			continue
+		}
+		if !p.expr() {
+			p.offset++
+			continue
+		}
+		args++
+	}
+
+	if args < n {
+		p.c.report(tok, "%s expects %d argument%s, but was given %d", tok.Literal, n, plural(n), args)
+	}
+}
+
+// plural returns the suffix to use for the given count.
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
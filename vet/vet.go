@@ -0,0 +1,481 @@
+// Package vet contains a static checker for BASIC programs.
+//
+// Because our interpreter executes tokens directly, with no parsing
+// phase, many mistakes are only discovered at run-time - if they are
+// discovered at all.  This package examines a program without running
+// it, and reports problems such as:
+//
+//   - GOTO, GOSUB, and friends, which refer to missing lines.
+//   - NEXT statements without a matching FOR.
+//   - RETURN statements which can be reached without a GOSUB.
+//   - Variables which are read before they have been assigned.
+//   - Calls to undefined user-defined functions.
+//   - Built-in functions called with the wrong number of arguments.
+//   - Lines which can never be reached.
+//   - DATA statements which are never READ.
+//
+// If the program contains a computed jump, such as "GOTO x * 10", we
+// can't know where it goes, so the checks which depend upon the flow of
+// control are relaxed to avoid reporting false positives.
+package vet
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/skx/gobasic/eval"
+	"github.com/skx/gobasic/token"
+	"github.com/skx/gobasic/tokenizer"
+)
+
+// Diagnostic describes a single problem found in a program.
+type Diagnostic struct {
+
+	// Line is the line of the source, counting from one.
+	Line int
+
+	// Column is the column of the source, counting from one.
+	Column int
+
+	// Message describes the problem.
+	Message string
+}
+
+// String returns the diagnostic in the form "line:column: message".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// kind is the type of an event.
+type kind int
+
+// The events which a statement may generate.
+const (
+	evAssign  kind = iota // A variable is assigned.
+	evRead                // A variable is read.
+	evJump                // Control may jump to a line.
+	evGosub               // A subroutine is called.
+	evOnError             // An error-handler is installed.
+	evReturn              // A subroutine returns.
+	evStop                // Control never proceeds past this point.
+)
+
+// event is something which happens as a line is executed, and which is
+// relevant to the checks which consider the flow of control.
+type event struct {
+	kind kind
+
+	// name is the name of the variable, for assignments and reads.
+	name string
+
+	// target is the index of the line which is jumped to.
+	target int
+
+	// tok is the token which caused the event.
+	tok token.Token
+}
+
+// line holds the details of a single line of the program.
+type line struct {
+
+	// number is the line-number, if any.
+	number string
+
+	// tokens holds the tokens of the line, excluding any
+	// line-number and the trailing newline.
+	tokens []token.Token
+
+	// events holds the events which executing the line generates.
+	events []event
+
+	// executable is true if the line contains a statement, rather
+	// than only comments, data, labels, or function-definitions.
+	executable bool
+}
+
+// Checker holds our state.
+type Checker struct {
+
+	// builtins holds the names of the known built-in functions,
+	// and the number of arguments each requires.
+	builtins map[string]int
+
+	// lines holds the lines of the program.
+	lines []*line
+
+	// numbers maps line-numbers to the index of the line.
+	numbers map[string]int
+
+	// labels maps label-names to the index of the line.
+	labels map[string]int
+
+	// fns holds the names of the user-defined functions.
+	fns map[string]bool
+
+	// forVars holds the names of the variables used in FOR loops
+	// which we've seen so far.
+	forVars map[string]bool
+
+	// assigned holds the names of all variables which are assigned,
+	// anywhere in the program.
+	assigned map[string]bool
+
+	// data holds the first token of each DATA statement.
+	data []token.Token
+
+	// read is true if the program contains a READ statement.
+	read bool
+
+	// gosub is true if the program contains a GOSUB.
+	gosub bool
+
+	// names holds the targets of GOTO and GOSUB statements which are
+	// names, but not the names of labels.
+	names []token.Token
+
+	// computed is true if the program contains a jump whose
+	// target we can't determine.
+	computed bool
+
+	// diagnostics holds the problems we've found.
+	diagnostics []Diagnostic
+}
+
+// New returns a checker which knows about the built-in functions that
+// the given map contains, as returned by eval.Interpreter.Builtins.
+func New(builtins map[string]int) *Checker {
+	return &Checker{builtins: builtins}
+}
+
+// Source checks the given program, using the built-in functions that
+// the standard interpreter provides.
+func Source(src string) ([]Diagnostic, error) {
+	e, err := eval.New(tokenizer.New(""))
+	if err != nil {
+		return nil, err
+	}
+	return New(e.Builtins()).Check(src), nil
+}
+
+// Check examines the given program, returning the problems found within
+// it, sorted by their position.
+func (c *Checker) Check(src string) []Diagnostic {
+
+	c.lines = nil
+	c.numbers = make(map[string]int)
+	c.labels = make(map[string]int)
+	c.fns = make(map[string]bool)
+	c.forVars = make(map[string]bool)
+	c.assigned = make(map[string]bool)
+	c.data = nil
+	c.read = false
+	c.gosub = false
+	c.names = nil
+	c.computed = false
+	c.diagnostics = nil
+
+	c.split(src)
+
+	for _, l := range c.lines {
+		p := &parser{c: c, line: l}
+		p.parse()
+	}
+
+	// A jump to a name is a jump to a computed target, if that
+	// name is a variable, otherwise it's to a missing label.
+	for _, tok := range c.names {
+		if c.assigned[tok.Literal] {
+			c.computed = true
+		} else {
+			c.report(tok, "label %s does not exist", tok.Literal)
+		}
+	}
+
+	if c.computed {
+		c.checkAssignments()
+	} else {
+		c.checkFlow()
+	}
+
+	if len(c.data) > 0 && !c.read {
+		c.report(c.data[0], "DATA is never READ")
+	}
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a := c.diagnostics[i]
+		b := c.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+// report records a problem, found at the given token.
+func (c *Checker) report(tok token.Token, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// split tokenizes the program, and divides it into lines.
+func (c *Checker) split(src string) {
+
+	t := tokenizer.New(src)
+	cur := &line{}
+	for {
+		tok := t.NextToken()
+
+		if tok.Type == token.EOF || tok.Type == token.NEWLINE {
+			c.lines = append(c.lines, cur)
+			cur = &line{}
+			if tok.Type == token.EOF {
+				break
+			}
+			continue
+		}
+
+		// Comments have no effect.
+		if tok.Type == token.REM {
+			continue
+		}
+
+		// Anything the tokenizer couldn't understand is a problem.
+		if tok.Type == token.ILLEGAL {
+			c.report(tok, "%s", tok.Problem())
+		}
+
+		// Built-in functions are recognized in upper, or lower, case.
+		if tok.Type == token.IDENT {
+			if _, ok := c.builtins[tok.Literal]; ok {
+				tok.Type = token.BUILTIN
+			}
+		}
+
+		if tok.Type == token.LINENO {
+			cur.number = tok.Literal
+			if _, ok := c.numbers[tok.Literal]; ok {
+				c.report(tok, "line %s is duplicated", tok.Literal)
+			} else {
+				c.numbers[tok.Literal] = len(c.lines)
+			}
+			continue
+		}
+
+		// A name followed by a colon, at the start of a line, is
+		// a label.
+		if len(cur.tokens) == 1 && cur.tokens[0].Type == token.IDENT && tok.Type == token.COLON {
+			cur.tokens[0].Type = token.LABEL
+		}
+		cur.tokens = append(cur.tokens, tok)
+	}
+
+	// Now we can record the labels, and user-defined functions.
+	for i, l := range c.lines {
+		for j, tok := range l.tokens {
+			if tok.Type == token.LABEL {
+				c.labels[tok.Literal] = i
+			}
+			if tok.Type == token.DEF && j+2 < len(l.tokens) && l.tokens[j+1].Type == token.FN {
+				c.fns[l.tokens[j+2].Literal] = true
+			}
+		}
+	}
+}
+
+// checkAssignments reports variables which are read but never assigned.
+//
+// This is used when the program contains computed jumps, so we can't
+// follow the flow of control.
+func (c *Checker) checkAssignments() {
+	reported := make(map[string]bool)
+
+	for _, l := range c.lines {
+		for _, ev := range l.events {
+			if ev.kind == evRead && !c.assigned[ev.name] && !reported[ev.name] {
+				c.report(ev.tok, "%s is used but never assigned", ev.name)
+				reported[ev.name] = true
+			}
+		}
+	}
+
+	if !c.gosub {
+		for _, l := range c.lines {
+			for _, ev := range l.events {
+				if ev.kind == evReturn {
+					c.report(ev.tok, "RETURN without GOSUB")
+				}
+			}
+		}
+	}
+}
+
+// checkFlow follows the flow of control through the program, to find
+// unreachable lines, stray RETURN statements, and variables which are
+// read before they are assigned.
+func (c *Checker) checkFlow() {
+
+	if len(c.lines) == 0 {
+		return
+	}
+
+	//
+	// The variables which may have been assigned when we reach
+	// each line, or nil if the line is never reached.
+	//
+	// The main program starts at the first line, and we treat
+	// subroutines, and error-handlers, separately, so that we can
+	// tell whether a RETURN may be reached by falling into it.
+	//
+	main := make([]map[string]bool, len(c.lines))
+	sub := make([]map[string]bool, len(c.lines))
+
+	main[0] = make(map[string]bool)
+	c.flow(main, sub, []int{0}, false)
+
+	var roots []int
+	for i := range c.lines {
+		if sub[i] != nil {
+			roots = append(roots, i)
+		}
+	}
+	c.flow(sub, sub, roots, true)
+
+	//
+	// Now we know which lines are reached, and what has been
+	// assigned, we can report problems.
+	//
+	reported := make(map[string]bool)
+	unreachable := false
+
+	for i, l := range c.lines {
+
+		in := main[i]
+		if in == nil {
+			in = sub[i]
+		} else if sub[i] != nil {
+			in = union(in, sub[i])
+		}
+
+		if in == nil {
+			if l.executable && !unreachable && len(l.tokens) > 0 {
+				c.report(l.tokens[0], "unreachable code")
+			}
+			if l.executable {
+				unreachable = true
+			}
+			continue
+		}
+		if l.executable {
+			unreachable = false
+		}
+
+		state := copySet(in)
+	events:
+		for _, ev := range l.events {
+			switch ev.kind {
+			case evAssign:
+				state[ev.name] = true
+			case evRead:
+				if !state[ev.name] && !reported[ev.name] {
+					c.report(ev.tok, "%s is used before it is assigned", ev.name)
+					reported[ev.name] = true
+				}
+			case evGosub:
+				state = union(state, c.assigned)
+			case evReturn:
+				if main[i] != nil {
+					c.report(ev.tok, "RETURN may be reached without GOSUB")
+				}
+			case evStop:
+				break events
+			}
+		}
+	}
+}
+
+// flow propagates the sets of assigned variables through the program,
+// starting from the given lines, until nothing changes.
+//
+// Calls to subroutines, and error-handlers, propagate into the sub map,
+// and are only followed if we're examining subroutines.
+func (c *Checker) flow(states []map[string]bool, sub []map[string]bool, roots []int, follow bool) {
+
+	work := append([]int{}, roots...)
+
+	// merge adds the given variables to the state of a line,
+	// returning true if that changed anything.
+	merge := func(states []map[string]bool, i int, vars map[string]bool) bool {
+		if states[i] == nil {
+			states[i] = copySet(vars)
+			return true
+		}
+		changed := false
+		for name := range vars {
+			if !states[i][name] {
+				states[i][name] = true
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	for len(work) > 0 {
+		i := work[0]
+		work = work[1:]
+
+		state := copySet(states[i])
+		stopped := false
+
+	events:
+		for _, ev := range c.lines[i].events {
+			switch ev.kind {
+			case evAssign:
+				state[ev.name] = true
+			case evJump:
+				if merge(states, ev.target, state) {
+					work = append(work, ev.target)
+				}
+			case evGosub:
+				if merge(sub, ev.target, state) && follow {
+					work = append(work, ev.target)
+				}
+				state = union(state, c.assigned)
+			case evOnError:
+				if merge(sub, ev.target, c.assigned) && follow {
+					work = append(work, ev.target)
+				}
+			case evStop:
+				stopped = true
+				break events
+			}
+		}
+
+		if !stopped && i+1 < len(c.lines) {
+			if merge(states, i+1, state) {
+				work = append(work, i+1)
+			}
+		}
+	}
+}
+
+// union returns a new set containing the members of both sets.
+func union(a map[string]bool, b map[string]bool) map[string]bool {
+	out := copySet(a)
+	for name := range b {
+		out[name] = true
+	}
+	return out
+}
+
+// copySet returns a copy of the given set.
+func copySet(a map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(a))
+	for name := range a {
+		out[name] = true
+	}
+	return out
+}
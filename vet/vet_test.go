@@ -0,0 +1,138 @@
+// vet_test.go - Test-cases for our static checker.
+
+package vet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// check returns the diagnostics for the given program, as strings.
+func check(t *testing.T, src string) []string {
+	diags, err := Source(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var out []string
+	for _, d := range diags {
+		out = append(out, d.String())
+	}
+	return out
+}
+
+// TestClean ensures that valid programs have no diagnostics.
+func TestClean(t *testing.T) {
+
+	tests := []string{
+		"10 PRINT \"Hello\\n\"\n",
+		"10 LET a = 1\n20 IF a > 3 THEN 50\n30 a = a + 1\n40 GOTO 20\n50 END\n",
+		"10 FOR i = 1 TO 10\n20 PRINT i\n30 NEXT i\n",
+		"10 GOSUB 100\n20 END\n100 PRINT \"sub\"\n110 RETURN\n",
+		"10 ON ERROR GOTO 100\n20 ERROR 5\n30 END\n100 PRINT ERR\n110 RESUME NEXT\n",
+		"10 READ a\n20 PRINT a\n30 DATA 3\n",
+		"10 DEF FN sq(x) = x * x\n20 PRINT FN sq(3)\n",
+		"10 INPUT \"Name? \", n$\n20 PRINT n$\n",
+		"10 DIM a(3)\n20 a[1] = 3\n30 PRINT a[1]\n",
+		"10 LET a = 1 : LET b = 2\n20 SWAP a, b\n",
+		"10 LET a = 1 ' one\n20 PRINT a : REM \"a\n30 END ' done\n",
+		"10 ON 2 GOSUB 100, 200\n20 END\n100 RETURN\n200 RETURN\n",
+		"LET i = 0\nloop:\nLET i = i + 1\nIF i < 3 THEN loop\nGOSUB sub\nEND\n*sub\nRETURN\n",
+		"10 LET t = 1\n20 GOTO t * 100\n100 END\n",
+		"10 PRINT LEFT$ \"Hello\", 2, MID$ \"Hello\", 1, 2\n",
+		"10 LET a = 1\n20 ASSERT a = 1 AND a < 2, \"a is \" + STR$ a\n",
+	}
+
+	for _, test := range tests {
+		out := check(t, test)
+		if len(out) != 0 {
+			t.Errorf("unexpected problems in %q: %v", test, out)
+		}
+	}
+}
+
+// TestProblems ensures that problems are reported.
+func TestProblems(t *testing.T) {
+
+	type Test struct {
+		Input    string
+		Expected []string
+	}
+
+	tests := []Test{
+		{Input: "10 GOTO 999\n",
+			Expected: []string{"1:9: line 999 does not exist"}},
+		{Input: "10 GOSUB 999\n20 END\n",
+			Expected: []string{"1:10: line 999 does not exist"}},
+		{Input: "10 IF 1 THEN 20 ELSE 30\n20 END\n",
+			Expected: []string{"1:22: line 30 does not exist"}},
+		{Input: "10 ON 1 GOTO 10, 40\n",
+			Expected: []string{"1:18: line 40 does not exist"}},
+		{Input: "10 GOTO lop\nloop:\nEND\n",
+			Expected: []string{"1:9: label lop does not exist", "3:1: unreachable code"}},
+		{Input: "10 FOR i = 1 TO 3\n20 NEXT j\n",
+			Expected: []string{"2:9: NEXT j without FOR j"}},
+		{Input: "10 PRINT 1\n20 RETURN\n",
+			Expected: []string{"2:4: RETURN may be reached without GOSUB"}},
+		{Input: "10 GOSUB 30\n30 PRINT 1\n40 RETURN\n",
+			Expected: []string{"3:4: RETURN may be reached without GOSUB"}},
+		{Input: "10 LET a = b + 1\n",
+			Expected: []string{"1:12: b is used before it is assigned"}},
+		{Input: "10 GOTO 30\n20 LET a = 1\n30 PRINT a\n",
+			Expected: []string{"2:4: unreachable code", "3:10: a is used before it is assigned"}},
+		{Input: "10 PRINT 1.2.3\n20 REM #1?\n30 PRINT ?\n",
+			Expected: []string{"1:10: malformed number 1.2.3", "3:10: unexpected character \"?\""}},
+		{Input: "10 PRINT FN cube(3)\n",
+			Expected: []string{"1:13: FN cube is not defined"}},
+		{Input: "10 LET a = MID$ \"steve\", 1\n",
+			Expected: []string{"1:12: MID$ expects 3 arguments, but was given 2"}},
+		{Input: "10 LET a = LEN \"steve\", \"x\"\n",
+			Expected: []string{"1:23: too many arguments to LEN, which expects 1"}},
+		{Input: "10 LET a = LEN(\"steve\", \"x\")\n",
+			Expected: []string{"1:23: too many arguments to LEN, which expects 1"}},
+		{Input: "10 LET a = MID$(\"steve\", 1)\n",
+			Expected: []string{"1:12: MID$ expects 3 arguments, but was given 2"}},
+		{Input: "10 LET a = LEFT$(\"steve\", (1 + 1)) + MID$ (\"x\"), 1, 1\n",
+			Expected: nil},
+		{Input: "10 END\n20 PRINT 1\n30 PRINT 2\n40 REM\n50 PRINT 3\n",
+			Expected: []string{"2:4: unreachable code"}},
+		{Input: "10 PRINT 1\n20 DATA 1, 2, 3\n",
+			Expected: []string{"2:4: DATA is never READ"}},
+		{Input: "10 PRINT 1\n10 PRINT 2\n",
+			Expected: []string{"2:1: line 10 is duplicated"}},
+
+		// With a computed GOTO we only report variables which are
+		// never assigned.
+		{Input: "10 LET t = 1\n20 GOTO t * 100\n30 PRINT x\n100 END\n",
+			Expected: []string{"3:10: x is used but never assigned"}},
+	}
+
+	for _, test := range tests {
+		out := check(t, test.Input)
+		if strings.Join(out, "\n") != strings.Join(test.Expected, "\n") {
+			t.Errorf("checking %q gave %v, expected %v", test.Input, out, test.Expected)
+		}
+	}
+}
+
+// TestExamples ensures that our examples are clean.
+func TestExamples(t *testing.T) {
+
+	files, err := filepath.Glob("../examples/*.bas")
+	if err != nil {
+		t.Fatalf("failed to find examples: %s", err.Error())
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", file, err.Error())
+		}
+
+		for _, d := range check(t, string(data)) {
+			t.Errorf("%s:%s", file, d)
+		}
+	}
+}